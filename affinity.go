@@ -0,0 +1,60 @@
+package virtualbox
+
+import (
+	"errors"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrCPUAffinityNotSupported is returned by SetCPUAffinity on hosts it is not implemented for.
+var ErrCPUAffinityNotSupported = errors.New("cpu affinity not supported on this host")
+
+// maxAffinityCPU bounds the host CPU numbers accepted by SetCPUAffinity (CPU_SETSIZE on linux).
+const maxAffinityCPU = 1024
+
+// setProcessAffinity is a variable so that tests can replace it.
+var setProcessAffinity = setProcessCPUAffinity
+
+// SetCPUAffinity restricts the running machine to the given host CPUs (numbered from 0).
+//
+// VBoxManage has no setting for it, so the affinity of the host process running the machine
+// (e.g. VBoxHeadless), found by the machine UUID in its command line, is set directly. It must
+// therefore be set again each time the machine is started.
+// Only supported on linux hosts, ErrCPUAffinityNotSupported is returned on other hosts.
+func (m *Machine) SetCPUAffinity(cpus []int) error {
+	mask, err := cpuMask(cpus)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "fail to set cpu affinity: vm=%s", m.Name)
+	}
+	if m.UUID == "" {
+		return pkgerrors.Errorf("fail to set cpu affinity: vm=%s, machine UUID is unknown", m.Name)
+	}
+	pids, err := vmProcessIDs(m.UUID)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "fail to find vm process: vm=%s, uuid=%s", m.Name, m.UUID)
+	}
+	if len(pids) == 0 {
+		return pkgerrors.Wrapf(ErrMachineNotRunning, "fail to set cpu affinity: vm=%s, no process found for uuid=%s", m.Name, m.UUID)
+	}
+	for _, pid := range pids {
+		if err := setProcessAffinity(pid, mask); err != nil {
+			return pkgerrors.Wrapf(err, "fail to set cpu affinity: vm=%s, pid=%d, cpus=%v", m.Name, pid, cpus)
+		}
+	}
+	return nil
+}
+
+// cpuMask returns the bit mask of the given CPUs, as 64 bits words.
+func cpuMask(cpus []int) ([]uint64, error) {
+	if len(cpus) == 0 {
+		return nil, errors.New("no cpu given")
+	}
+	mask := make([]uint64, maxAffinityCPU/64)
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= maxAffinityCPU {
+			return nil, pkgerrors.Errorf("cpu out of range [0, %d): %d", maxAffinityCPU, cpu)
+		}
+		mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	}
+	return mask, nil
+}
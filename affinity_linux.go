@@ -0,0 +1,32 @@
+package virtualbox
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"github.com/pkg/errors"
+)
+
+// setProcessCPUAffinity sets the affinity of all the threads of the given process,
+// as sched_setaffinity only applies to a single thread.
+func setProcessCPUAffinity(pid int, mask []uint64) error {
+	tasks, err := os.ReadDir(filepath.Join("/proc", strconv.Itoa(pid), "task"))
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		tid, err := strconv.Atoi(task.Name())
+		if err != nil {
+			continue
+		}
+		_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY,
+			uintptr(tid), uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0]))) // #nosec
+		if errno != 0 && errno != syscall.ESRCH { // ESRCH: the thread has exited meanwhile
+			return errors.Wrapf(errno, "sched_setaffinity failed: tid=%d", tid)
+		}
+	}
+	return nil
+}
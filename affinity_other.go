@@ -0,0 +1,7 @@
+//go:build !linux
+
+package virtualbox
+
+func setProcessCPUAffinity(pid int, mask []uint64) error {
+	return ErrCPUAffinityNotSupported
+}
@@ -0,0 +1,41 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCPUMask(t *testing.T) {
+	mask, err := cpuMask([]int{0, 2, 65})
+	require.NoError(t, err)
+	require.Equal(t, uint64(0b101), mask[0])
+	require.Equal(t, uint64(0b10), mask[1])
+
+	_, err = cpuMask(nil)
+	require.Error(t, err)
+	_, err = cpuMask([]int{-1})
+	require.Error(t, err)
+}
+
+func TestSetCPUAffinity(t *testing.T) {
+	origIDs, origSet := vmProcessIDs, setProcessAffinity
+	defer func() { vmProcessIDs, setProcessAffinity = origIDs, origSet }()
+	vmProcessIDs = func(uuid string) ([]int, error) {
+		require.Equal(t, "37f5d336-bf07-48dd-947c-37e6a56420a7", uuid)
+		return []int{4242}, nil
+	}
+	affinities := map[int][]uint64{}
+	setProcessAffinity = func(pid int, mask []uint64) error {
+		affinities[pid] = mask
+		return nil
+	}
+
+	m := &Machine{Name: "go-virtualbox", UUID: "37f5d336-bf07-48dd-947c-37e6a56420a7", State: Running}
+	require.NoError(t, m.SetCPUAffinity([]int{2, 3}))
+	require.Len(t, affinities, 1)
+	require.Equal(t, uint64(0b1100), affinities[4242][0])
+
+	vmProcessIDs = func(uuid string) ([]int, error) { return []int{}, nil }
+	require.ErrorIs(t, m.SetCPUAffinity([]int{2, 3}), ErrMachineNotRunning)
+}
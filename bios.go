@@ -0,0 +1,76 @@
+package virtualbox
+
+import (
+	"errors"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrInvalidBIOS is returned when the BIOS settings of a machine are not valid.
+var ErrInvalidBIOS = errors.New("invalid bios settings")
+
+// BIOS APIC modes of BIOS.APIC.
+const (
+	BIOSAPICX2APIC   = "x2apic"
+	BIOSAPIC         = "apic"
+	BIOSAPICDisabled = "disabled"
+)
+
+// BIOS boot menu modes of BIOS.BootMenu.
+const (
+	BIOSBootMenuMessageAndMenu = "messageandmenu"
+	BIOSBootMenuMenuOnly       = "menuonly"
+	BIOSBootMenuDisabled       = "disabled"
+)
+
+// BIOS holds the BIOS settings of a machine.
+type BIOS struct {
+	APIC     string // x2apic|apic|disabled, empty to keep the current one
+	BootMenu string // messageandmenu|menuonly|disabled, empty for disabled
+	PXEDebug *bool  // PXE debug logging, nil to keep the current one
+}
+
+// validate checks the APIC and boot menu modes.
+func (b BIOS) validate() error {
+	switch b.APIC {
+	case "", BIOSAPICX2APIC, BIOSAPIC, BIOSAPICDisabled:
+	default:
+		return pkgerrors.Wrapf(ErrInvalidBIOS, "bios apic must be x2apic|apic|disabled: %+v", b)
+	}
+	switch b.BootMenu {
+	case "", BIOSBootMenuMessageAndMenu, BIOSBootMenuMenuOnly, BIOSBootMenuDisabled:
+	default:
+		return pkgerrors.Wrapf(ErrInvalidBIOS, "bios boot menu must be messageandmenu|menuonly|disabled: %+v", b)
+	}
+	return nil
+}
+
+// cmdArgs returns the modifyvm args applying the BIOS settings. The boot menu is disabled when not set.
+func (b BIOS) cmdArgs() []CmdArg {
+	bootMenu := b.BootMenu
+	if bootMenu == "" {
+		bootMenu = BIOSBootMenuDisabled
+	}
+	args := []CmdArg{NewCmdArg("--biosbootmenu", bootMenu)}
+	if b.APIC != "" {
+		args = append(args, NewCmdArg("--biosapic", b.APIC))
+	}
+	if b.PXEDebug != nil {
+		args = append(args, NewCmdArg("--biospxedebug", bool2string(*b.PXEDebug)))
+	}
+	return args
+}
+
+// biosFromPropMap reads the BIOS settings from the machine readable VM info,
+// e.g. biosapic="apic" and bootmenu="messageandmenu".
+func biosFromPropMap(propMap map[string]string) BIOS {
+	b := BIOS{
+		APIC:     propMap["biosapic"],
+		BootMenu: propMap["bootmenu"],
+	}
+	if pxeDebug, ok := propMap["biospxedebug"]; ok {
+		enabled := pxeDebug == "on"
+		b.PXEDebug = &enabled
+	}
+	return b
+}
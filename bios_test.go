@@ -0,0 +1,56 @@
+package virtualbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMachineBIOS(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-full-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		require.Equal(t, BIOSAPIC, m.BIOS.APIC)
+		require.Equal(t, BIOSBootMenuMessageAndMenu, m.BIOS.BootMenu)
+		require.NotNil(t, m.BIOS.PXEDebug)
+		require.False(t, *m.BIOS.PXEDebug)
+
+		modifyArgs = expectModifyVM("go-virtualbox", vmInfoOut)
+		require.NoError(t, m.Modify())
+		bootMenu, _ := argValue(*modifyArgs, "--biosbootmenu")
+		require.Equal(t, "messageandmenu", bootMenu, "modify should keep the boot menu read from the VM info")
+
+		modifyArgs = expectModifyVM("go-virtualbox", strings.NewReplacer(
+			`biosapic="apic"`, `biosapic="x2apic"`,
+			`biospxedebug="off"`, `biospxedebug="on"`,
+			`bootmenu="messageandmenu"`, `bootmenu="disabled"`,
+		).Replace(vmInfoOut))
+	} else {
+		restoreOnCleanup(t, m, m.BIOS.cmdArgs()...)
+	}
+
+	pxeDebug := true
+	m.BIOS = BIOS{APIC: BIOSAPICX2APIC, PXEDebug: &pxeDebug}
+	require.NoError(t, m.Modify())
+	require.Equal(t, BIOSAPICX2APIC, m.BIOS.APIC)
+	require.Equal(t, BIOSBootMenuDisabled, m.BIOS.BootMenu)
+	require.NotNil(t, m.BIOS.PXEDebug)
+	require.True(t, *m.BIOS.PXEDebug)
+	if ManageMock != nil {
+		bootMenu, _ := argValue(*modifyArgs, "--biosbootmenu")
+		require.Equal(t, "disabled", bootMenu)
+		apic, _ := argValue(*modifyArgs, "--biosapic")
+		require.Equal(t, "x2apic", apic)
+		pxe, _ := argValue(*modifyArgs, "--biospxedebug")
+		require.Equal(t, "on", pxe)
+	}
+
+	// invalid settings are rejected before running modifyvm
+	m.BIOS = BIOS{BootMenu: "always"}
+	require.ErrorIs(t, m.Modify(), ErrInvalidBIOS)
+}
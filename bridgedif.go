@@ -0,0 +1,130 @@
+package virtualbox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrBridgedInterfaceNotFound holds the error message when no host interface matches a query.
+	ErrBridgedInterfaceNotFound = errors.New("bridged interface not found")
+	// ErrBridgedInterfaceAmbiguous holds the error message when several host interfaces match a query.
+	ErrBridgedInterfaceAmbiguous = errors.New("bridged interface query is ambiguous")
+)
+
+// BridgedInterface defines a host network interface which NICs can be bridged to (NICNetBridged).
+type BridgedInterface struct {
+	Name     string // to be used as NIC.HostInterface, i.e. --bridgeadapter<1-N>
+	GUID     string
+	DHCP     bool
+	IPv4     CIDR
+	HwAddr   net.HardwareAddr
+	Medium   string
+	Wireless bool
+	Status   string
+}
+
+// BridgedInterfaces returns the host network interfaces which NICs can be bridged to.
+func BridgedInterfaces() ([]BridgedInterface, error) {
+	out, stderr, err := Manage().runOutErr("list", "bridgedifs")
+	if err != nil {
+		return nil, fmt.Errorf("fail to list bridged interfaces: stderr=%s, err=%w", stderr, err)
+	}
+	return parseBridgedInterfaces(out), nil
+}
+
+func parseBridgedInterfaces(out string) []BridgedInterface {
+	ifs := []BridgedInterface{}
+	n := BridgedInterface{}
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			if n.Name != "" {
+				ifs = append(ifs, n)
+			}
+			n = BridgedInterface{}
+			continue
+		}
+		res := reColonLine.FindStringSubmatch(line)
+		if res == nil {
+			continue
+		}
+		switch key, val := res[1], res[2]; key {
+		case "Name":
+			n.Name = val
+		case "GUID":
+			n.GUID = val
+		case "DHCP":
+			n.DHCP = val != "Disabled"
+		case "IPAddress":
+			n.IPv4.IP = net.ParseIP(val)
+		case "NetworkMask":
+			n.IPv4.Mask = ParseIPv4Mask(val)
+		case "HardwareAddress":
+			// not parsable for some virtual interfaces, e.g. 00:00:00:00:00:00:00:e0 on Windows
+			n.HwAddr, _ = net.ParseMAC(val)
+		case "MediumType":
+			n.Medium = val
+		case "Wireless":
+			n.Wireless = val == "Yes"
+		case "Status":
+			n.Status = val
+		}
+	}
+	if n.Name != "" {
+		// last entry not followed by an empty line
+		ifs = append(ifs, n)
+	}
+	return ifs
+}
+
+// ResolveBridgedInterface returns the exact name of the host interface matching the given query,
+// to be used as NIC.HostInterface of a bridged NIC. This is handy on Windows, where interface names
+// are long, e.g. "Intel(R) Ethernet Connection (7) I219-V" can be resolved from "I219".
+//
+// The query is matched, in this order, as the exact name, as a case-insensitive substring of the name,
+// then as a regular expression. ErrBridgedInterfaceNotFound is returned if no interface matches and
+// ErrBridgedInterfaceAmbiguous if several ones do.
+func ResolveBridgedInterface(query string) (string, error) {
+	ifs, err := BridgedInterfaces()
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(ifs))
+	for _, n := range ifs {
+		if n.Name == query {
+			return n.Name, nil
+		}
+		names = append(names, n.Name)
+	}
+	matches := matchingNames(names, func(name string) bool {
+		return strings.Contains(strings.ToLower(name), strings.ToLower(query))
+	})
+	if re, err := regexp.Compile(query); len(matches) == 0 && err == nil {
+		matches = matchingNames(names, re.MatchString)
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("%w: query=%q, available=%q", ErrBridgedInterfaceNotFound, query, names)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%w: query=%q, matching=%q", ErrBridgedInterfaceAmbiguous, query, matches)
+	}
+}
+
+// matchingNames returns the names for which match returns true.
+func matchingNames(names []string, match func(name string) bool) []string {
+	var matches []string
+	for _, name := range names {
+		if match(name) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
@@ -0,0 +1,61 @@
+package virtualbox
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBridgedInterfaces(t *testing.T) {
+	ifs := parseBridgedInterfaces(ReadTestData("vboxmanage-list-bridgedifs-1.out"))
+
+	require.Len(t, ifs, 3)
+	require.Equal(t, "Intel(R) Ethernet Connection (7) I219-V", ifs[0].Name)
+	require.True(t, ifs[0].DHCP)
+	require.Equal(t, "192.168.1.23", ifs[0].IPv4.IP.String())
+	require.Equal(t, "8c:ec:4b:12:34:56", ifs[0].HwAddr.String())
+	require.True(t, ifs[1].Wireless)
+	require.Equal(t, "Down", ifs[1].Status)
+	require.Equal(t, "Hyper-V Virtual Ethernet Adapter", ifs[2].Name)
+	require.Equal(t, net.IPv4Mask(255, 255, 240, 0), ifs[2].IPv4.Mask)
+}
+
+func TestResolveBridgedInterface(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("list", "bridgedifs").
+			Return(ReadTestData("vboxmanage-list-bridgedifs-1.out"), "", nil).AnyTimes()
+
+		for query, expected := range map[string]string{
+			"Hyper-V Virtual Ethernet Adapter": "Hyper-V Virtual Ethernet Adapter",
+			"i219":                             "Intel(R) Ethernet Connection (7) I219-V",
+			"Wi-Fi":                            "Intel(R) Wi-Fi 6 AX201 160MHz",
+			`^Intel.*\(7\)`:                    "Intel(R) Ethernet Connection (7) I219-V",
+		} {
+			name, err := ResolveBridgedInterface(query)
+			require.NoErrorf(t, err, "query=%s", query)
+			require.Equalf(t, expected, name, "query=%s", query)
+		}
+
+		_, err := ResolveBridgedInterface("intel")
+		require.ErrorIs(t, err, ErrBridgedInterfaceAmbiguous)
+	}
+
+	// the exact names always resolve to themselves
+	ifs, err := BridgedInterfaces()
+	require.NoError(t, err)
+	for _, n := range ifs {
+		name, err := ResolveBridgedInterface(n.Name)
+		require.NoErrorf(t, err, "query=%s", n.Name)
+		require.Equal(t, n.Name, name)
+	}
+
+	_, err = ResolveBridgedInterface("Realtek go-virtualbox")
+	require.ErrorIs(t, err, ErrBridgedInterfaceNotFound)
+	if len(ifs) > 0 {
+		require.Contains(t, err.Error(), ifs[len(ifs)-1].Name, "the available interfaces should be listed")
+	}
+}
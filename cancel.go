@@ -0,0 +1,72 @@
+package virtualbox
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// InterruptGracePeriod is how long a cancelled VBoxManage command is given to roll back
+// its operation after being interrupted, before it gets killed.
+var InterruptGracePeriod = 30 * time.Second
+
+// execute runs cmd until it completes or, if set, until the command context is done.
+//
+// On cancellation VBoxManage is interrupted first, as it then cancels the operation it runs
+// in VBoxSVC; killing it right away would leave the operation running in the background.
+func (vbcmd command) execute(cmd *exec.Cmd) error {
+	if vbcmd.ctx == nil {
+		return cmd.Run()
+	}
+	if err := vbcmd.ctx.Err(); err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-vbcmd.ctx.Done():
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		// e.g. on windows, where interrupting another process is not supported
+		_ = cmd.Process.Kill()
+	}
+	select {
+	case <-done:
+	case <-time.After(InterruptGracePeriod):
+		Debug("killing command not exiting after interrupt: %s", cmd.String())
+		_ = cmd.Process.Kill()
+		<-done
+	}
+	return vbcmd.ctx.Err()
+}
+
+// cancelled returns a non nil error wrapping the context error if the operation was cancelled,
+// after removing the partially written output file.
+// A file which existed before the operation started is kept.
+func cancelled(ctx context.Context, name, output string, outputExisted bool) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if output != "" && !outputExisted {
+		if err := os.Remove(output); err != nil && !os.IsNotExist(err) {
+			Debug("fail to remove partial output of cancelled %s: file=%s, err=%v", name, output, err)
+		}
+	}
+	return errors.Wrapf(ctx.Err(), "%s cancelled", name)
+}
+
+// fileExists tells whether the given file exists.
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
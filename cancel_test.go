@@ -0,0 +1,78 @@
+package virtualbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandRunInterruptedOnCancel(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	cmd := command{program: "sh"}
+
+	start := time.Now()
+	err := cmd.setOpts(withContext(ctx)).run("-c", "exec sleep 10")
+
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "unexpected error: %v", err)
+	require.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestCloneHDCtxRemovesPartialOutput(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	output := filepath.Join(t.TempDir(), "clone.vdi")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().setOpts(gomock.Any()).Return(ManageMock).Times(1),
+			ManageMock.EXPECT().run("clonehd", "disk.vdi", output).DoAndReturn(func(args ...string) error {
+				require.NoError(t, os.WriteFile(output, []byte("partial"), 0600))
+				cancel()
+				return context.Canceled
+			}).Times(1),
+		)
+	} else {
+		// a real clone cannot be reliably interrupted midway: it is cancelled before it starts
+		cancel()
+	}
+
+	err := CloneHDCtx(ctx, "disk.vdi", output)
+	require.True(t, errors.Is(err, context.Canceled), "unexpected error: %v", err)
+	require.False(t, fileExists(output), "partial output should have been removed")
+}
+
+func TestExportApplianceCtxKeepsExistingOutput(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	output := filepath.Join(t.TempDir(), "export.ova")
+	require.NoError(t, os.WriteFile(output, []byte("previous"), 0600))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	vms := []string{"vm1", "vm2"}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().setOpts(gomock.Any()).Return(ManageMock).Times(1),
+			ManageMock.EXPECT().run("export", "vm1", "vm2", "--output", output).Return(context.Canceled).Times(1),
+		)
+	} else {
+		vms = []string{VM}
+	}
+
+	err := ExportApplianceCtx(ctx, vms, output)
+	require.True(t, errors.Is(err, context.Canceled), "unexpected error: %v", err)
+	require.True(t, fileExists(output), "pre-existing output should be kept")
+}
@@ -0,0 +1,82 @@
+package virtualbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+)
+
+// CIDR is an IP network which keeps the IP of its CIDR notation unmasked, e.g. 192.168.56.1/24
+// for the host address of a host-only network, rather than the network address.
+//
+// IPv4 networks are normalized to their 4 bytes form when parsed and compared, so that
+// networks read from different VBoxManage outputs compare equal.
+type CIDR struct {
+	net.IPNet
+}
+
+// ParseCIDR parses s in CIDR notation (e.g. 192.168.56.1/24), keeping its IP unmasked.
+// An empty string gives the zero CIDR.
+func ParseCIDR(s string) (CIDR, error) {
+	if s == "" {
+		return CIDR{}, nil
+	}
+	ip, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return CIDR{}, err
+	}
+	return CIDR{net.IPNet{IP: ip, Mask: ipnet.Mask}}.normalized(), nil
+}
+
+// normalized returns the CIDR with an IPv4 IP and mask in their 4 bytes form.
+func (c CIDR) normalized() CIDR {
+	if ip4 := c.IP.To4(); ip4 != nil {
+		c.IP = ip4
+		if len(c.Mask) == net.IPv6len {
+			c.Mask = c.Mask[12:]
+		}
+	}
+	return c
+}
+
+// IsZero tells whether the CIDR is not set.
+func (c CIDR) IsZero() bool {
+	return c.IP == nil && c.Mask == nil
+}
+
+// String formats the CIDR in CIDR notation, the IP alone if there is no mask, empty if not set.
+func (c CIDR) String() string {
+	if c.IP == nil {
+		return ""
+	}
+	if c.Mask == nil {
+		return c.IP.String()
+	}
+	ones, _ := c.Mask.Size()
+	return fmt.Sprintf("%s/%d", c.IP, ones)
+}
+
+// Equal tells whether both CIDR have the same IP and mask, whatever the form of their IPv4 addresses.
+func (c CIDR) Equal(o CIDR) bool {
+	c, o = c.normalized(), o.normalized()
+	return c.IP.Equal(o.IP) && c.Mask.String() == o.Mask.String()
+}
+
+// MarshalJSON marshals the CIDR as a string in CIDR notation.
+func (c CIDR) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+// UnmarshalJSON unmarshals a CIDR from a string in CIDR notation.
+func (c *CIDR) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseCIDR(s)
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
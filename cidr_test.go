@@ -0,0 +1,33 @@
+package virtualbox
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCIDR(t *testing.T) {
+	c, err := ParseCIDR("192.168.56.1/24")
+	require.NoError(t, err)
+	require.Equal(t, "192.168.56.1/24", c.String())
+	require.Len(t, c.IP, net.IPv4len)
+
+	// 16 bytes IPv4 as parsed by net.ParseIP
+	parsed := CIDR{net.IPNet{IP: net.ParseIP("192.168.56.1"), Mask: ParseIPv4Mask("255.255.255.0")}}
+	require.True(t, c.Equal(parsed))
+	require.False(t, c.Equal(mustParseCIDR(t, "192.168.56.2/24")))
+
+	b, err := json.Marshal(c)
+	require.NoError(t, err)
+	require.Equal(t, `"192.168.56.1/24"`, string(b))
+	var unmarshaled CIDR
+	require.NoError(t, json.Unmarshal([]byte(`"fd17:625c:f037:2::/64"`), &unmarshaled))
+	require.Equal(t, "fd17:625c:f037:2::/64", unmarshaled.String())
+
+	zero, err := ParseCIDR("")
+	require.NoError(t, err)
+	require.True(t, zero.IsZero())
+	require.Equal(t, "", zero.String())
+}
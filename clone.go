@@ -0,0 +1,111 @@
+package virtualbox
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// CloneMachineDisks clones the source machine, which must have been read with GetMachine, into a new
+// registered machine with the given name, choosing per disk whether it is cloned: the disks for which
+// cloneDisk returns true are cloned, the other ones are replaced by new empty disks of the same capacity.
+// DVD and floppy drives are attached to the same media as in the source. The new machine gets new NIC
+// MAC addresses. If the clone cannot be completed, the new machine is unregistered and deleted.
+//
+// This allows e.g. golden image workflows, cloning the OS disk and giving each instance a fresh data disk,
+// without copying all the disks as <VBoxManage clonevm> would. The disks are written in the folder of
+// the new machine as <name>-disk<i>.vdi.
+func CloneMachineDisks(source *Machine, name string, cloneDisk func(ctlName string, disk StorageMedium) bool) (*Machine, error) {
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to generate machine UUID")
+	}
+	clone, err := CreateMachine(uuid, name, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to clone machine: source=%s, name=%s", source.Name, name)
+	}
+	if err := clone.cloneFrom(source, cloneDisk); err != nil {
+		if errDel := Manage().run("unregistervm", name, "--delete"); errDel != nil {
+			return nil, errors.Wrapf(err, "fail to roll back clone: err=%v", errDel)
+		}
+		return nil, err
+	}
+	return clone, clone.Refresh()
+}
+
+// cloneFrom applies the settings and storage of the source machine to the new machine m.
+func (m *Machine) cloneFrom(source *Machine, cloneDisk func(ctlName string, disk StorageMedium) bool) error {
+	if err := m.copySettings(source); err != nil {
+		return errors.Wrapf(err, "fail to copy machine settings: source=%s, name=%s", source.Name, m.Name)
+	}
+	i := 0
+	for _, ctl := range source.StorageControllers {
+		if err := m.AddStorageCtl(ctl.Name, ctl); err != nil {
+			return errors.Wrapf(err, "fail to add storage controller: vm=%s, ctl=%s", m.Name, ctl.Name)
+		}
+		removable := DriveDVD
+		if ctl.SysBus == SysBusFloppy {
+			removable = DriveFDD
+		}
+		for _, disk := range ctl.Devices {
+			medium := StorageMedium{Port: disk.Port, Device: disk.Device, DriveType: removable, Medium: disk.Medium, UUID: disk.UUID}
+			var md *Medium
+			var err error
+			if isAttachedMedium(disk.Medium) {
+				if md, err = diskMediumInfo(disk.UUIDOrMedium()); err != nil {
+					return errors.Wrapf(err, "fail to clone machine: source=%s, name=%s, ctl=%s, port=%d, device=%d",
+						source.Name, m.Name, ctl.Name, disk.Port, disk.Device)
+				}
+			}
+			if md != nil {
+				i++
+				target := filepath.Join(m.BaseFolder, fmt.Sprintf("%s-disk%d.vdi", m.Name, i))
+				if medium, err = cloneOrCreateDisk(disk, md, target, cloneDisk(ctl.Name, disk)); err != nil {
+					return errors.Wrapf(err, "fail to clone machine: source=%s, name=%s, ctl=%s, port=%d, device=%d",
+						source.Name, m.Name, ctl.Name, disk.Port, disk.Device)
+				}
+			}
+			if err := m.AttachStorage(ctl.Name, medium); err != nil {
+				return errors.Wrapf(err, "fail to attach storage: vm=%s, ctl=%s, medium=%s", m.Name, ctl.Name, medium.UUIDOrMedium())
+			}
+		}
+	}
+	return nil
+}
+
+// copySettings applies the settings of the source machine to the new machine, except for the ones
+// which must differ between machines.
+func (m *Machine) copySettings(source *Machine) error {
+	settings := *source
+	settings.Name = m.Name
+	settings.UUID = m.UUID
+	settings.CfgFile = m.CfgFile
+	settings.BaseFolder = m.BaseFolder
+	settings.State = m.State
+	settings.SnapshotFolder = ""
+	settings.HardwareUUID = ""
+	settings.StorageControllers = nil
+	settings.NICs = make([]NIC, len(source.NICs))
+	for i, nic := range source.NICs {
+		nic.MacAddr = NICMacAddrAuto
+		settings.NICs[i] = nic
+	}
+	if err := settings.Modify(); err != nil {
+		return err
+	}
+	*m = settings
+	return nil
+}
+
+// cloneOrCreateDisk clones the given disk, whose medium information is md, into target, or creates target
+// as a new empty disk with the same capacity, and returns the medium to attach.
+func cloneOrCreateDisk(disk StorageMedium, md *Medium, target string, clone bool) (StorageMedium, error) {
+	medium := StorageMedium{Port: disk.Port, Device: disk.Device, DriveType: DriveHDD, Medium: target}
+	if clone {
+		uuid, err := CloneHDNewUUID(disk.UUIDOrMedium(), target)
+		medium.UUID = uuid
+		return medium, err
+	}
+	return medium, CreateDisk(target, md.CapacityMB)
+}
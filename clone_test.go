@@ -0,0 +1,169 @@
+package virtualbox
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneMachineDisks(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	name := "worker1"
+	source := &Machine{Name: "golden", CPUs: 2, Memory: 2048,
+		NICs: []NIC{{Network: NICNetNAT, Hardware: VirtIO, MacAddr: "080027C1B7A5"}},
+		StorageControllers: StorageControllers{
+			{Name: "SATA", SysBus: SysBusSATA, Ports: 2, Devices: []StorageMedium{
+				{Port: 0, Medium: "/vms/golden/os.vdi", UUID: "8c80c269-8569-4c90-b745-bac723810dab"},
+				{Port: 1, Medium: "/vms/golden/data.vdi"},
+			}},
+			{Name: "IDE", SysBus: SysBusIDE, Devices: []StorageMedium{{Port: 1, Medium: "emptydrive"}}},
+			{Name: "Floppy", SysBus: SysBusFloppy, Devices: []StorageMedium{{Medium: "/vms/golden/boot.img"}}},
+		},
+	}
+	var modifyArgs []string
+	if ManageMock != nil {
+		propMap, err := vminfoAsPropMap(strings.NewReader(strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"),
+			`x2apic="on"`, `x2apic="off"`, 1)))
+		require.NoError(t, err)
+		golden, err := newMachineFromPropMap(propMap)
+		require.NoError(t, err)
+		golden.Name, golden.CPUs, golden.Memory = source.Name, source.CPUs, source.Memory
+		golden.NICs, golden.StorageControllers = source.NICs, source.StorageControllers
+		source = golden
+		vmInfo := strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"), `name="go-virtualbox"`, `name="worker1"`, 1)
+		folder := "/Users/fix/VirtualBox VMs/go-virtualbox"
+		gomock.InOrder(
+			ManageMock.EXPECT().runOut("list", "vms").Return("", nil).Times(1),
+			ManageMock.EXPECT().run("createvm", "--uuid", gomock.Any(), "--name", "worker1", "--register").Return(nil).Times(1),
+			expectShowVMInfo("worker1", vmInfo),
+			ManageMock.EXPECT().runOutErr(gomock.Any()).DoAndReturn(func(args ...string) (string, string, error) {
+				modifyArgs = args
+				return "", "", nil
+			}).Times(1),
+			expectShowVMInfo("worker1", vmInfo),
+			ManageMock.EXPECT().run("storagectl", "worker1", "--name", "SATA", "--add", "sata", "--portcount", "2",
+				"--hostiocache", "off", "--bootable", "off").Return(nil).Times(1),
+			// OS disk cloned
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "8c80c269-8569-4c90-b745-bac723810dab").
+				Return(ReadTestData("vboxmanage-showmediuminfo-base-1.out"), "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "8c80c269-8569-4c90-b745-bac723810dab").
+				Return(ReadTestData("vboxmanage-showmediuminfo-base-1.out"), "", nil).Times(1),
+			ManageMock.EXPECT().run("clonehd", "8c80c269-8569-4c90-b745-bac723810dab", filepath.Join(folder, "worker1-disk1.vdi")).
+				Return(nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", filepath.Join(folder, "worker1-disk1.vdi")).
+				Return(ReadTestData("vboxmanage-showmediuminfo-diff-1.out"), "", nil).Times(1),
+			ManageMock.EXPECT().run("storageattach", "worker1", "--storagectl", "SATA", "--port", "0", "--device", "0",
+				"--type", "hdd", "--medium", "3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf").Return(nil).Times(1),
+			// data disk created empty
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/vms/golden/data.vdi").
+				Return(ReadTestData("vboxmanage-showmediuminfo-base-1.out"), "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("createmedium", "disk", "--filename", filepath.Join(folder, "worker1-disk2.vdi"),
+				"--size", "20480", "--format", "VDI").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().run("storageattach", "worker1", "--storagectl", "SATA", "--port", "1", "--device", "0",
+				"--type", "hdd", "--medium", filepath.Join(folder, "worker1-disk2.vdi")).Return(nil).Times(1),
+			ManageMock.EXPECT().run("storagectl", "worker1", "--name", "IDE", "--add", "ide",
+				"--hostiocache", "off", "--bootable", "off").Return(nil).Times(1),
+			ManageMock.EXPECT().run("storageattach", "worker1", "--storagectl", "IDE", "--port", "1", "--device", "0",
+				"--type", "dvddrive", "--medium", "emptydrive").Return(nil).Times(1),
+			// floppy image attached as is
+			ManageMock.EXPECT().run("storagectl", "worker1", "--name", "Floppy", "--add", "floppy",
+				"--hostiocache", "off", "--bootable", "off").Return(nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/vms/golden/boot.img").
+				Return("", "VBoxManage: error: Could not find file for the medium", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "dvd", "/vms/golden/boot.img").
+				Return("", "VBoxManage: error: Could not find file for the medium", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "floppy", "/vms/golden/boot.img").
+				Return("UUID: 4e2b1c0d-9a8f-4e7d-b6c5-a4b3c2d1e0f9", "", nil).Times(1),
+			ManageMock.EXPECT().run("storageattach", "worker1", "--storagectl", "Floppy", "--port", "0", "--device", "0",
+				"--type", "fdd", "--medium", "/vms/golden/boot.img").Return(nil).Times(1),
+			expectShowVMInfo("worker1", vmInfo),
+		)
+	} else {
+		source = testMachine(t, "", Poweroff, Aborted)
+		name = source.Name + "-clone"
+		t.Cleanup(func() {
+			if err := Manage().run("unregistervm", name, "--delete"); err != nil {
+				t.Errorf("fail to delete clone %s: %v", name, err)
+			}
+		})
+	}
+
+	clone, err := CloneMachineDisks(source, name, func(ctlName string, disk StorageMedium) bool {
+		return disk.Port == 0
+	})
+	require.NoError(t, err)
+	require.Equal(t, name, clone.Name)
+	if ManageMock != nil {
+		require.Equal(t, []string{"modifyvm", "worker1"}, modifyArgs[:2])
+		cpus, _ := argValue(modifyArgs, "--cpus")
+		require.Equal(t, "2", cpus)
+		mac, _ := argValue(modifyArgs, "--macaddress1")
+		require.Equal(t, NICMacAddrAuto, mac)
+		for key, want := range map[string]string{
+			"--ostype": "Ubuntu_64", "--apic": "on", "--x2apic": "off", "--accelerate2dvideo": "off", "--ioapic": "on",
+		} {
+			got, _ := argValue(modifyArgs, key)
+			require.Equalf(t, want, got, "the clone should keep %s of the source", key)
+		}
+	} else {
+		require.Equal(t, source.OSType, clone.OSType)
+		require.Equal(t, source.Flag, clone.Flag)
+		require.Equal(t, source.CPUs, clone.CPUs)
+		require.Equal(t, source.Memory, clone.Memory)
+		require.Len(t, clone.StorageControllers, len(source.StorageControllers))
+		for i, nic := range clone.NICs {
+			if nic.Network != NICNetAbsent {
+				require.NotEqualf(t, source.NICs[i].MacAddr, nic.MacAddr, "nic%d should get a new MAC address", i+1)
+			}
+		}
+	}
+}
+
+func TestCloneMachineDisksRollback(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	// the source disk does not exist
+	name := "worker1"
+	source := &Machine{Name: "golden", CPUs: 2, Memory: 2048,
+		StorageControllers: StorageControllers{
+			{Name: "SATA", SysBus: SysBusSATA, Ports: 1, Devices: []StorageMedium{{Port: 0, Medium: "/vms/golden/os.vdi"}}},
+		},
+	}
+	if ManageMock != nil {
+		vmInfo := strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"), `name="go-virtualbox"`, `name="worker1"`, 1)
+		gomock.InOrder(
+			ManageMock.EXPECT().runOut("list", "vms").Return("", nil).Times(1),
+			ManageMock.EXPECT().run("createvm", "--uuid", gomock.Any(), "--name", "worker1", "--register").Return(nil).Times(1),
+			expectShowVMInfo("worker1", vmInfo),
+			ManageMock.EXPECT().runOutErr(gomock.Any()).Return("", "", nil).Times(1),
+			expectShowVMInfo("worker1", vmInfo),
+			ManageMock.EXPECT().run("storagectl", "worker1", "--name", "SATA", "--add", "sata", "--portcount", "1",
+				"--hostiocache", "off", "--bootable", "off").Return(nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/vms/golden/os.vdi").
+				Return("", "VBoxManage: error: Could not open the medium", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "dvd", "/vms/golden/os.vdi").
+				Return("", "", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "floppy", "/vms/golden/os.vdi").
+				Return("", "", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().run("unregistervm", "worker1", "--delete").Return(nil).Times(1),
+		)
+		ManageMock.EXPECT().runOutErr("showvminfo", "worker1", "--machinereadable").
+			Return("", "VBoxManage: error: Could not find a registered machine named 'worker1'", errors.New("exit status 1")).Times(1)
+	} else {
+		name = VM + "-clone-rollback"
+		source.StorageControllers[0].Devices[0].Medium = filepath.Join(t.TempDir(), "missing.vdi")
+	}
+
+	clone, err := CloneMachineDisks(source, "worker1", func(string, StorageMedium) bool { return true })
+	require.Error(t, err)
+	require.Nil(t, clone)
+	_, err = GetMachine(name)
+	require.ErrorIs(t, err, ErrMachineNotExist, "the partial clone should have been deleted")
+}
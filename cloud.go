@@ -0,0 +1,59 @@
+package virtualbox
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// CloudProviderOCI is the short name of the Oracle Cloud Infrastructure provider, the only one VirtualBox supports.
+const CloudProviderOCI = "OCI"
+
+var (
+	reCloudNameLine = regexp.MustCompile(`^Name:\s*(.*)$`)
+	reCloudIDLine   = regexp.MustCompile(`^ID:\s*(.*)$`)
+)
+
+// CloudInstance is an instance of a cloud provider.
+type CloudInstance struct {
+	Name string
+	ID   string
+}
+
+// CloudProfiles returns the names of the configured cloud profiles.
+func CloudProfiles() ([]string, error) {
+	stdout, stderr, err := Manage().runOutErr("list", "cloudprofiles")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to list cloud profiles: stderr=%s", stderr)
+	}
+	profiles := []string{}
+	s := bufio.NewScanner(strings.NewReader(stdout))
+	for s.Scan() {
+		if res := reCloudNameLine.FindStringSubmatch(s.Text()); res != nil {
+			profiles = append(profiles, strings.TrimSpace(res[1]))
+		}
+	}
+	return profiles, s.Err()
+}
+
+// CloudInstances returns the instances of the given OCI cloud profile.
+func CloudInstances(profile string) ([]CloudInstance, error) {
+	stdout, stderr, err := Manage().runOutErr("cloud", "--provider="+CloudProviderOCI, "--profile="+profile,
+		"list", "instances")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to list cloud instances: profile=%s, stderr=%s", profile, stderr)
+	}
+	instances := []CloudInstance{}
+	s := bufio.NewScanner(strings.NewReader(stdout))
+	for s.Scan() {
+		line := s.Text()
+		if res := reCloudNameLine.FindStringSubmatch(line); res != nil {
+			instances = append(instances, CloudInstance{Name: strings.TrimSpace(res[1])})
+		} else if res := reCloudIDLine.FindStringSubmatch(line); res != nil && len(instances) > 0 {
+			instances[len(instances)-1].ID = strings.TrimSpace(res[1])
+		}
+	}
+	return instances, s.Err()
+}
@@ -0,0 +1,37 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloud(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("list", "cloudprofiles").
+			Return(ReadTestData("vboxmanage-list-cloudprofiles-1.out"), "", nil).Times(1)
+		ManageMock.EXPECT().runOutErr("cloud", "--provider=OCI", "--profile=ci", "list", "instances").
+			Return(ReadTestData("vboxmanage-cloud-list-instances-1.out"), "", nil).Times(1)
+	}
+
+	profiles, err := CloudProfiles()
+	require.NoError(t, err)
+	if ManageMock == nil {
+		// listing instances requires the credentials of a cloud account
+		t.Logf("cloud profiles: %v", profiles)
+		return
+	}
+	require.Equal(t, []string{"DEFAULT", "ci"}, profiles)
+
+	instances, err := CloudInstances("ci")
+	require.NoError(t, err)
+	require.Equal(t,
+		[]CloudInstance{
+			{Name: "build-runner-1", ID: "ocid1.instance.oc1.iad.anuwcljr1"},
+			{Name: "build-runner-2", ID: "ocid1.instance.oc1.iad.anuwcljr2"},
+		},
+		instances)
+}
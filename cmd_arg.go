@@ -1,11 +1,14 @@
 package virtualbox
 
+import "fmt"
+
 //CmdArg models a command arg, which can be a flag or not.
 type CmdArg struct {
 	K             string                     //Args key. e.g. --accelerated
 	V             *string                    // V value. nil if arg does not allow value specification. Note that empty string "" is a valid value, and different from nil.
 	ToCmdArgParts func(K, V string) []string //
 	Del           bool                       // if true deleted, the arg will not be part of the final command
+	Repeatable    bool                       // if true, all occurrences of the arg are kept in order instead of being overridden
 }
 
 type CmdArgs struct {
@@ -25,6 +28,27 @@ func NewCmdArgDeleted(k string) CmdArg {
 	return CmdArg{K: k, V: nil, Del: true}
 }
 
+// NewCmdArgRepeatable returns an arg which can occur multiple times (e.g. --natpf1 <rule>).
+// Occurrences are kept in order; a deleted arg with the same key removes all of them.
+func NewCmdArgRepeatable(k, v string) CmdArg {
+	return CmdArg{K: k, V: &v, Repeatable: true}
+}
+
+// NewCmdArgsBootOrder returns the --boot<1-4> args setting the boot order to the given devices
+// in {none|floppy|dvd|disk|net}. Unused slots are set to none, so that the full boot order
+// is replaced at once when used as Modify overrides.
+func NewCmdArgsBootOrder(devices ...string) []CmdArg {
+	args := make([]CmdArg, 0, 4)
+	for i := 0; i < 4; i++ {
+		dev := "none"
+		if i < len(devices) {
+			dev = devices[i]
+		}
+		args = append(args, NewCmdArg(fmt.Sprintf("--boot%d", i+1), dev))
+	}
+	return args
+}
+
 func (cmdArgs *CmdArgs) AppendNoValue(key string) {
 	cmdArgs.args = append(cmdArgs.args, NewCmdArgNoValue(key))
 }
@@ -33,6 +57,11 @@ func (cmdArgs *CmdArgs) Append(key, value string) {
 	cmdArgs.args = append(cmdArgs.args, NewCmdArg(key, value))
 }
 
+// AppendRepeatable appends an arg which does not override previous occurrences of the same key.
+func (cmdArgs *CmdArgs) AppendRepeatable(key, value string) {
+	cmdArgs.args = append(cmdArgs.args, NewCmdArgRepeatable(key, value))
+}
+
 func (cmdArgs *CmdArgs) AppendCmdArgs(arg ...CmdArg) {
 	if len(arg) == 0 {
 		return
@@ -40,6 +69,11 @@ func (cmdArgs *CmdArgs) AppendCmdArgs(arg ...CmdArg) {
 	cmdArgs.args = append(cmdArgs.args, arg...)
 }
 
+// Delete removes the arg with the given key from the final command, even if it has already been appended.
+func (cmdArgs *CmdArgs) Delete(key string) {
+	cmdArgs.AppendOverride(NewCmdArgDeleted(key))
+}
+
 func (cmdArgs *CmdArgs) AppendOverride(arg ...CmdArg) {
 	if len(arg) == 0 {
 		return
@@ -48,36 +82,43 @@ func (cmdArgs *CmdArgs) AppendOverride(arg ...CmdArg) {
 }
 
 //Args returns an slice containing the args which can be use in a command execution context.
-//Args with multiple occurrence are not supported we will be overriding values.
+//Args with multiple occurrence are only supported for repeatable args, otherwise we will be overriding values.
 func (cmdArgs CmdArgs) Args() []string {
-	m := make(map[string]CmdArg, len(cmdArgs.args)+len(cmdArgs.overrides))
+	m := make(map[string][]CmdArg, len(cmdArgs.args)+len(cmdArgs.overrides))
 	orderK := make([]string, 0, len(cmdArgs.args)+len(cmdArgs.overrides))
 	for _, curArgs := range [][]CmdArg{cmdArgs.args, cmdArgs.overrides} {
 		for _, arg := range curArgs {
-			_, contains := m[arg.K]
+			occurrences, contains := m[arg.K]
 			if !contains {
 				orderK = append(orderK, arg.K)
 			}
-			// yes we are always overriding --> multiple occurrences are not supported
-			m[arg.K] = arg
+			if arg.Repeatable && len(occurrences) > 0 && occurrences[len(occurrences)-1].Repeatable {
+				m[arg.K] = append(occurrences, arg)
+				continue
+			}
+			// non repeatable: we are always overriding --> multiple occurrences are not supported
+			m[arg.K] = []CmdArg{arg}
 		}
 	}
 	argStrs := make([]string, 0, len(orderK))
 	for _, k := range orderK {
-		arg := m[k]
-		if arg.Del {
-			continue
-		}
-		if arg.V == nil {
-			argStrs = append(argStrs, arg.K)
-		} else {
-			if arg.ToCmdArgParts == nil {
-				argStrs = append(argStrs, arg.K)
-				argStrs = append(argStrs, *arg.V)
-			} else {
-				argStrs = append(argStrs, arg.ToCmdArgParts(arg.K, *arg.V)...)
-			}
+		for _, arg := range m[k] {
+			argStrs = append(argStrs, arg.parts()...)
 		}
 	}
 	return argStrs
 }
+
+// parts returns the command parts of this arg, nothing if it is deleted.
+func (arg CmdArg) parts() []string {
+	if arg.Del {
+		return nil
+	}
+	if arg.V == nil {
+		return []string{arg.K}
+	}
+	if arg.ToCmdArgParts == nil {
+		return []string{arg.K, *arg.V}
+	}
+	return arg.ToCmdArgParts(arg.K, *arg.V)
+}
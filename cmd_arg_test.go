@@ -0,0 +1,68 @@
+package virtualbox
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdArgsOverrideDeletesArg(t *testing.T) {
+	cmdArgs := CmdArgs{}
+	cmdArgs.Append("--firmware", "bios")
+	cmdArgs.Append("--biosbootmenu", "disabled")
+	cmdArgs.Append("--cpus", "2")
+	cmdArgs.AppendOverride(NewCmdArgDeleted("--biosbootmenu"))
+
+	require.Equal(t, []string{"--firmware", "bios", "--cpus", "2"}, cmdArgs.Args())
+}
+
+func TestCmdArgsDelete(t *testing.T) {
+	cmdArgs := CmdArgs{}
+	cmdArgs.Append("--cpus", "2")
+	cmdArgs.Delete("--cpus")
+	cmdArgs.Delete("--memory")
+
+	require.Empty(t, cmdArgs.Args())
+}
+
+func TestNewCmdArgsBootOrderReplacesAllSlots(t *testing.T) {
+	cmdArgs := CmdArgs{}
+	for i, dev := range []string{"disk", "dvd", "net", "floppy"} {
+		cmdArgs.Append(fmt.Sprintf("--boot%d", i+1), dev)
+	}
+	cmdArgs.AppendOverride(NewCmdArgsBootOrder("net", "disk")...)
+
+	require.Equal(t,
+		[]string{"--boot1", "net", "--boot2", "disk", "--boot3", "none", "--boot4", "none"},
+		cmdArgs.Args())
+}
+
+func TestCmdArgsKeepsAllOccurrencesOfRepeatableArgs(t *testing.T) {
+	cmdArgs := CmdArgs{}
+	cmdArgs.Append("--nic1", "nat")
+	cmdArgs.AppendRepeatable("--natpf1", "ssh,tcp,,2222,,22")
+	cmdArgs.AppendRepeatable("--natpf1", "http,tcp,,8080,,80")
+	cmdArgs.Append("--cpus", "1")
+	cmdArgs.AppendOverride(NewCmdArgRepeatable("--natpf1", "https,tcp,,8443,,443"), NewCmdArg("--cpus", "2"))
+
+	require.Equal(t,
+		[]string{
+			"--nic1", "nat",
+			"--natpf1", "ssh,tcp,,2222,,22", "--natpf1", "http,tcp,,8080,,80", "--natpf1", "https,tcp,,8443,,443",
+			"--cpus", "2",
+		},
+		cmdArgs.Args())
+}
+
+func TestCmdArgsDeleteRemovesAllOccurrencesOfRepeatableArgs(t *testing.T) {
+	cmdArgs := CmdArgs{}
+	cmdArgs.AppendCmdArgs(
+		NewCmdArgRepeatable("--natpf1", "ssh,tcp,,2222,,22"),
+		NewCmdArgRepeatable("--natpf1", "http,tcp,,8080,,80"),
+	)
+	cmdArgs.Delete("--natpf1")
+	cmdArgs.AppendOverride(NewCmdArgRepeatable("--natpf1", "https,tcp,,8443,,443"))
+
+	require.Equal(t, []string{"--natpf1", "https,tcp,,8443,,443"}, cmdArgs.Args())
+}
@@ -0,0 +1,154 @@
+package virtualbox
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// consolePollInterval is the delay between 2 reads of an UART file which has no new content.
+var consolePollInterval = 200 * time.Millisecond
+
+// StreamConsole copies the output of the first UART configured with a readable mode to w
+// until stop is closed.
+//
+// Supported modes are:
+//   - file: the file is tailed, waiting for it to be created if necessary
+//   - tcpserver: a connection to the port the VM is listening on is opened
+//   - tcpclient: the address the VM is connecting to is listened on
+//   - server: the host pipe (unix socket) is connected to
+func (m *Machine) StreamConsole(w io.Writer, stop <-chan struct{}) error {
+	uart, err := m.UARTs.consoleUART()
+	if err != nil {
+		return errors.Wrapf(err, "fail to stream console of vm=%s", m.Name)
+	}
+	switch uart.Mode {
+	case UARTModeFile:
+		return tailFile(uart.ModeData, w, stop)
+	case UARTModeTCPServer:
+		conn, err := net.Dial("tcp", net.JoinHostPort("127.0.0.1", uart.ModeData))
+		if err != nil {
+			return errors.Wrapf(err, "fail to connect to console: vm=%s, uart=%s", m.Name, uart.Key)
+		}
+		return copyConn(conn, w, stop)
+	case UARTModeTCPClient:
+		ln, err := net.Listen("tcp", uart.ModeData)
+		if err != nil {
+			return errors.Wrapf(err, "fail to listen for console: vm=%s, uart=%s", m.Name, uart.Key)
+		}
+		return acceptAndCopy(ln, w, stop)
+	case UARTModeServer:
+		conn, err := net.Dial("unix", uart.ModeData)
+		if err != nil {
+			return errors.Wrapf(err, "fail to connect to console pipe: vm=%s, uart=%s", m.Name, uart.Key)
+		}
+		return copyConn(conn, w, stop)
+	default:
+		return fmt.Errorf("uart mode not supported for console streaming: vm=%s, uart=%s, mode=%s",
+			m.Name, uart.Key, uart.Mode)
+	}
+}
+
+// consoleUART returns the first UART which is not off and has a mode which can be streamed.
+func (uarts UARTs) consoleUART() (*UART, error) {
+	for _, uart := range uarts {
+		if uart.IsOff() {
+			continue
+		}
+		switch uart.Mode {
+		case UARTModeFile, UARTModeTCPServer, UARTModeTCPClient, UARTModeServer:
+			return &uart, nil
+		}
+	}
+	return nil, fmt.Errorf("no uart configured with a streamable mode (file|tcpserver|tcpclient|server): uarts=%v", uarts)
+}
+
+func isStopped(stop <-chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func tailFile(path string, w io.Writer, stop <-chan struct{}) error {
+	var f *os.File
+	for f == nil {
+		var err error
+		f, err = os.Open(path) // #nosec
+		if err != nil {
+			if !os.IsNotExist(err) {
+				return errors.Wrapf(err, "fail to open uart file: %s", path)
+			}
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(consolePollInterval):
+			}
+		}
+	}
+	defer f.Close()
+
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, errW := w.Write(buf[:n]); errW != nil {
+				return errors.Wrapf(errW, "fail to write console output")
+			}
+		}
+		if err != nil && err != io.EOF {
+			return errors.Wrapf(err, "fail to read uart file: %s", path)
+		}
+		if n == 0 || err == io.EOF {
+			select {
+			case <-stop:
+				return nil
+			case <-time.After(consolePollInterval):
+			}
+		} else if isStopped(stop) {
+			return nil
+		}
+	}
+}
+
+func copyConn(conn net.Conn, w io.Writer, stop <-chan struct{}) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		conn.Close()
+	}()
+	if _, err := io.Copy(w, conn); err != nil && !isStopped(stop) {
+		return errors.Wrapf(err, "fail to copy console output from %s", conn.RemoteAddr())
+	}
+	return nil
+}
+
+func acceptAndCopy(ln net.Listener, w io.Writer, stop <-chan struct{}) error {
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+		case <-done:
+		}
+		ln.Close()
+	}()
+	conn, err := ln.Accept()
+	if err != nil {
+		if isStopped(stop) {
+			return nil
+		}
+		return errors.Wrapf(err, "fail to accept console connection on %s", ln.Addr())
+	}
+	return copyConn(conn, w, stop)
+}
@@ -0,0 +1,84 @@
+package virtualbox
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func streamConsoleUntil(t *testing.T, m *Machine, produce func(), expected string) {
+	out := &syncBuffer{}
+	stop := make(chan struct{})
+	errC := make(chan error, 1)
+	go func() { errC <- m.StreamConsole(out, stop) }()
+
+	produce()
+	require.Eventuallyf(t, func() bool { return out.String() == expected }, 5*time.Second, 10*time.Millisecond,
+		"console output should have been streamed: expected=%q actual=%q", expected, out)
+	close(stop)
+	require.NoError(t, <-errC)
+}
+
+func TestStreamConsoleTailsUARTFile(t *testing.T) {
+	uartFile := filepath.Join(t.TempDir(), "my uart.log")
+	m := &Machine{Name: "go-virtualbox", UARTs: UARTs{
+		UART1.UARTOffFromKey(),
+		{Key: UART2, ComConfig: COM2(), Mode: UARTModeFile, ModeData: uartFile},
+	}}
+
+	streamConsoleUntil(t, m, func() {
+		require.NoError(t, os.WriteFile(uartFile, []byte("Booting Linux\n"), 0o600))
+		f, err := os.OpenFile(uartFile, os.O_APPEND|os.O_WRONLY, 0o600)
+		require.NoError(t, err)
+		defer f.Close()
+		_, err = f.WriteString("login: ")
+		require.NoError(t, err)
+	}, "Booting Linux\nlogin: ")
+}
+
+func TestStreamConsoleConnectsToUARTTCPServer(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+	m := &Machine{Name: "go-virtualbox", UARTs: UARTs{
+		{Key: UART1, ComConfig: COM1(), Mode: UARTModeTCPServer, ModeData: strconv.Itoa(port)},
+	}}
+
+	streamConsoleUntil(t, m, func() {
+		conn, err := ln.Accept()
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("Booting Linux\n"))
+		require.NoError(t, err)
+	}, "Booting Linux\n")
+}
+
+func TestStreamConsoleFailsWithoutStreamableUART(t *testing.T) {
+	m := &Machine{Name: "go-virtualbox", UARTs: *NewUARTsAllOff()}
+
+	require.Error(t, m.StreamConsole(&syncBuffer{}, make(chan struct{})))
+}
@@ -0,0 +1,31 @@
+package virtualbox
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidCPUExecutionCap holds the error message when a CPU execution cap is not within 1-100.
+var ErrInvalidCPUExecutionCap = errors.New("invalid cpu execution cap, expected 1-100")
+
+// SetCPUCapRuntime limits the host CPU time each virtual CPU of the running (or paused) machine can use,
+// in percent, without restarting it. The cap is lost when the machine is powered off.
+func (m *Machine) SetCPUCapRuntime(pct uint) error {
+	if pct < 1 || pct > 100 {
+		return errors.Wrapf(ErrInvalidCPUExecutionCap, "vm=%s, cap=%d", m.Name, pct)
+	}
+	if err := m.Refresh(); err != nil {
+		return err
+	}
+	if m.State != Running && m.State != Paused {
+		return errors.Wrapf(ErrMachineNotRunning, "fail to set cpu execution cap: vm=%s, state=%s", m.Name, m.State)
+	}
+	value := strconv.FormatUint(uint64(pct), 10)
+	stdout, stderr, err := Manage().runOutErr("controlvm", m.Name, "cpuexecutioncap", value)
+	if err != nil {
+		return errors.Wrapf(err, "fail to set cpu execution cap: vm=%s, cap=%s, stdout=%s, stderr=%s",
+			m.Name, value, stdout, stderr)
+	}
+	return nil
+}
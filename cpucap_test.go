@@ -0,0 +1,44 @@
+package virtualbox
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCPUCapRuntime(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: VM}
+	running := false
+	if ManageMock != nil {
+		m.Name = "go-virtualbox"
+		gomock.InOrder(
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Poweroff, "0")),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "2")),
+			ManageMock.EXPECT().runOutErr("controlvm", "go-virtualbox", "cpuexecutioncap", "50").Return("", "", nil).Times(1),
+		)
+	} else {
+		vmInfo, _, err := Manage().runOutErr("showvminfo", VM, "--machinereadable")
+		require.NoError(t, err)
+		propMap, err := vminfoAsPropMap(strings.NewReader(vmInfo))
+		require.NoError(t, err)
+		running = propMap["VMState"] == string(Running)
+		if cpuCap, err := strconv.ParseUint(propMap["cpuexecutioncap"], 10, 32); running && err == nil {
+			defer func() { require.NoError(t, m.SetCPUCapRuntime(uint(cpuCap))) }()
+		}
+	}
+
+	require.ErrorIs(t, m.SetCPUCapRuntime(0), ErrInvalidCPUExecutionCap)
+	require.ErrorIs(t, m.SetCPUCapRuntime(101), ErrInvalidCPUExecutionCap)
+	if !running {
+		require.ErrorIs(t, m.SetCPUCapRuntime(50), ErrMachineNotRunning)
+	}
+	if ManageMock != nil || running {
+		require.NoError(t, m.SetCPUCapRuntime(50))
+	}
+}
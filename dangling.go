@@ -0,0 +1,90 @@
+package virtualbox
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/pkg/errors"
+)
+
+// matches e.g. Nonexistent host networking interface, name 'vboxnet1' (VERR_INTERNAL_ERROR)
+var reDanglingHostonlyAdapter = regexp.MustCompile(`Nonexistent host networking interface, name '([^']+)'`)
+
+// ErrDanglingHostonlyAdapter is returned when a machine cannot start because one of its NICs references
+// a host-only adapter which does not exist anymore, e.g. after vboxnet<N> interfaces have been removed.
+// See Machine.FixDanglingAdapters.
+type ErrDanglingHostonlyAdapter struct {
+	VM      string
+	Adapter string // name of the missing host-only adapter, e.g. vboxnet1
+	Err     error  // error of the failed operation
+}
+
+func (e *ErrDanglingHostonlyAdapter) Error() string {
+	return fmt.Sprintf("vm=%s references the missing host-only adapter %s: %v", e.VM, e.Adapter, e.Err)
+}
+
+// Unwrap returns the error of the failed operation.
+func (e *ErrDanglingHostonlyAdapter) Unwrap() error {
+	return e.Err
+}
+
+// danglingHostonlyAdapterError returns an ErrDanglingHostonlyAdapter if the given error is due to a missing
+// host-only adapter, otherwise nil.
+func danglingHostonlyAdapterError(err error, vm string) error {
+	if err == nil {
+		return nil
+	}
+	res := reDanglingHostonlyAdapter.FindStringSubmatch(err.Error())
+	if res == nil {
+		return nil
+	}
+	return &ErrDanglingHostonlyAdapter{VM: vm, Adapter: res[1], Err: err}
+}
+
+// FixDanglingAdapters repairs the host-only NICs of the powered off machine referencing a host-only adapter
+// which does not exist anymore: a new adapter is created for each missing one and the NICs are attached to it.
+// VirtualBox names the new adapters (e.g. vboxnet0 for the first free one), so that their names may differ
+// from the missing ones, and configures them with its default addresses.
+// The NICs of an adapter which cannot be created are disconnected instead.
+//
+// The machine must have been read with GetMachine, it is refreshed afterwards.
+func (m *Machine) FixDanglingAdapters() error {
+	nets, err := HostonlyNets()
+	if err != nil {
+		return errors.Wrapf(err, "fail to fix dangling host-only adapters: vm=%s", m.Name)
+	}
+	existing := make(map[string]bool, len(nets))
+	for _, n := range nets {
+		existing[n.Name] = true
+	}
+	replacements := map[string]string{}
+	fixed := false
+	for i, nic := range m.NICs {
+		if nic.Network != NICNetHostonly || nic.HostInterface == "" || existing[nic.HostInterface] {
+			continue
+		}
+		replacement, ok := replacements[nic.HostInterface]
+		if !ok {
+			created, err := CreateHostonlyNet()
+			if err != nil {
+				Debug("fail to recreate host-only adapter: vm=%s, adapter=%s, err=%v", m.Name, nic.HostInterface, err)
+			} else {
+				replacement = created.Name
+			}
+			replacements[nic.HostInterface] = replacement
+		}
+		if replacement != "" {
+			nic.HostInterface = replacement
+		} else {
+			nic = NIC{Network: NICNetDisconnected, Hardware: nic.Hardware, MacAddr: nic.MacAddr}
+		}
+		if err := m.SetNIC(i+1, nic); err != nil {
+			return errors.Wrapf(err, "fail to fix dangling host-only adapter: vm=%s, nic=%d", m.Name, i+1)
+		}
+		fixed = true
+	}
+	if !fixed {
+		return nil
+	}
+	return m.Refresh()
+}
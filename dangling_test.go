@@ -0,0 +1,91 @@
+package virtualbox
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// setDanglingNIC makes a NIC of the real test machine reference the given host-only adapter, which does not exist,
+// and returns the machine and the NIC rank. The machine settings are restored once the test is done.
+func setDanglingNIC(t *testing.T, adapter string) (*Machine, int) {
+	t.Helper()
+	m := testMachine(t, "", Poweroff, Aborted)
+	n := len(m.NICs) + 1
+	if n > 4 {
+		n = 4
+	}
+	restoreOnCleanup(t, m, nicRestoreArgs(t, m, n)...)
+	require.NoError(t, m.SetNIC(n, NIC{Network: NICNetHostonly, Hardware: IntelPro1000MTDesktop, HostInterface: adapter}))
+	require.NoError(t, m.Refresh())
+	return m, n
+}
+
+func TestStartDanglingHostonlyAdapter(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", State: Poweroff}
+	adapter := "vboxnet3"
+	if ManageMock != nil {
+		ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(errors.New(
+			"VBoxManage: error: Nonexistent host networking interface, name 'vboxnet3' (VERR_INTERNAL_ERROR)\n" +
+				"VBoxManage: error: Details: code E_FAIL (0x80004005), component ConsoleWrap, interface IConsole")).Times(1)
+	} else {
+		adapter = "vboxnet-go-virtualbox-missing"
+		m, _ = setDanglingNIC(t, adapter)
+	}
+
+	err := m.Start()
+	if err == nil {
+		_ = m.Poweroff()
+	}
+	var dangling *ErrDanglingHostonlyAdapter
+	require.ErrorAs(t, err, &dangling)
+	require.Equal(t, adapter, dangling.Adapter)
+	require.Equal(t, m.Name, dangling.VM)
+}
+
+func TestFixDanglingAdapters(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", NICs: []NIC{
+		{Network: NICNetNAT, Hardware: VirtIO},
+		{Network: NICNetHostonly, Hardware: VirtIO, HostInterface: "vboxnet0", MacAddr: "080027C1B7A5"},
+		{Network: NICNetHostonly, Hardware: VirtIO, HostInterface: "vboxnet3", MacAddr: "080027EE1DF7"},
+	}}
+	n := 3
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOut("list", "hostonlyifs").Return(ReadTestData("vboxmanage-list-hostonlyifs-1.out"), nil).Times(1),
+			ManageMock.EXPECT().runOut("hostonlyif", "create").
+				Return("0%...10%...20%...30%...40%...50%...60%...70%...80%...90%...100%\n"+
+					"Interface 'vboxnet1' was successfully created\n", nil).Times(1),
+			ManageMock.EXPECT().run("modifyvm", "go-virtualbox", "--nic3", "hostonly", "--nictype3", "virtio",
+				"--cableconnected3", "on", "--macaddress3", "080027EE1DF7", "--hostonlyadapter3", "vboxnet1").Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", strings.NewReplacer(
+				`nic2="none"`, "nic2=\"hostonly\"\nnictype2=\"virtio\"\nmacaddress2=\"080027C1B7A5\"\nhostonlyadapter2=\"vboxnet0\"",
+				`nic3="none"`, "nic3=\"hostonly\"\nnictype3=\"virtio\"\nmacaddress3=\"080027EE1DF7\"\nhostonlyadapter3=\"vboxnet1\"",
+			).Replace(ReadTestData("vboxmanage-showvminfo-1.out"))),
+		)
+	} else {
+		m, n = setDanglingNIC(t, "vboxnet-go-virtualbox-missing")
+	}
+
+	require.NoError(t, m.FixDanglingAdapters())
+	require.Greater(t, len(m.NICs), n-1)
+	nic := m.NICs[n-1]
+	if nic.Network == NICNetHostonly {
+		if ManageMock == nil {
+			t.Cleanup(func() { _ = Manage().run("hostonlyif", "remove", nic.HostInterface) })
+		}
+		require.NotEqual(t, "vboxnet-go-virtualbox-missing", nic.HostInterface)
+		require.NotEqual(t, "vboxnet3", nic.HostInterface)
+	} else {
+		require.Equal(t, NICNetDisconnected, nic.Network, "the NIC should be disconnected if no adapter can be created")
+	}
+}
@@ -0,0 +1,21 @@
+package virtualbox
+
+import (
+	"github.com/pkg/errors"
+)
+
+// DebugInfo returns the raw output of <VBoxManage debugvm <vm> info <item>> for items such as
+// cpuid, timers or pit. The machine must be running (or paused).
+func (m *Machine) DebugInfo(item string) (string, error) {
+	if err := m.Refresh(); err != nil {
+		return "", err
+	}
+	if m.State != Running && m.State != Paused {
+		return "", errors.Wrapf(ErrMachineNotRunning, "fail to get debug info: vm=%s, state=%s", m.Name, m.State)
+	}
+	stdout, stderr, err := Manage().runOutErr("debugvm", m.Name, "info", item)
+	if err != nil {
+		return "", errors.Wrapf(err, "fail to get debug info: vm=%s, item=%s, stderr=%s", m.Name, item, stderr)
+	}
+	return stdout, nil
+}
@@ -0,0 +1,37 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugInfo(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	running := true
+	if ManageMock != nil {
+		expectShowVMInfo("go-virtualbox", vmInfoWithState(Saved, "0"))
+		_, err := m.DebugInfo("timers")
+		require.ErrorIs(t, err, ErrMachineNotRunning)
+
+		gomock.InOrder(
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "2")),
+			ManageMock.EXPECT().runOutErr("debugvm", "go-virtualbox", "info", "timers").Return("Timers (pVM=...)\n", "", nil).Times(1),
+		)
+	} else {
+		m = testMachine(t, "")
+		running = m.State == Running
+	}
+
+	out, err := m.DebugInfo("timers")
+	if !running {
+		require.ErrorIs(t, err, ErrMachineNotRunning)
+		return
+	}
+	require.NoError(t, err)
+	require.Contains(t, out, "Timers")
+}
@@ -2,6 +2,7 @@ package virtualbox
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
 	"net"
 	"strings"
@@ -12,17 +13,26 @@ import (
 
 // DHCP server info.
 type DHCP struct {
-	NetworkName string
-	IPv4        net.IPNet
-	LowerIP     net.IP
-	UpperIP     net.IP
-	Enabled     bool
+	NetworkName string `json:"networkName"`
+	IPv4        CIDR   `json:"ipv4"`
+	LowerIP     net.IP `json:"lowerIP"`
+	UpperIP     net.IP `json:"upperIP"`
+	Enabled     bool   `json:"enabled"`
 }
 
 func (dhcp DHCP) String() string {
 	return fmt.Sprintf(
 		"DHCP[%s, net=%s, start=%s stop=%s, enable=%t]",
-		dhcp.NetworkName, dhcp.IPv4.String(), dhcp.LowerIP.String(), dhcp.LowerIP.String(), dhcp.Enabled)
+		dhcp.NetworkName, dhcp.IPv4.String(), dhcp.LowerIP.String(), dhcp.UpperIP.String(), dhcp.Enabled)
+}
+
+// DHCPsJSON returns all DHCP server settings as a JSON object keyed by DHCP.NetworkName.
+func DHCPsJSON() ([]byte, error) {
+	dhcps, err := DHCPs()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(dhcps)
 }
 
 func addDHCP(kind, name string, d DHCP) error {
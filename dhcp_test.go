@@ -1,8 +1,8 @@
 package virtualbox
 
 import (
+	"encoding/json"
 	"net"
-	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -38,7 +38,7 @@ func TestDHCPs(t *testing.T) {
 	expectedServers := []DHCP{
 		{
 			NetworkName: "HostInterfaceNetworking-VirtualBox Host-Only Ethernet Adapter",
-			IPv4:        mustCidrKeepUnmaskIp(t, "192.168.56.100/24"),
+			IPv4:        mustParseCIDR(t, "192.168.56.100/24"),
 			LowerIP:     mustParseIp(t, "192.168.56.101"),
 			UpperIP:     mustParseIp(t, "192.168.56.254"),
 			Enabled:     false,
@@ -46,7 +46,7 @@ func TestDHCPs(t *testing.T) {
 
 		{
 			NetworkName: "HostInterfaceNetworking-vboxnet5",
-			IPv4:        mustCidrKeepUnmaskIp(t, "192.168.61.1/24"),
+			IPv4:        mustParseCIDR(t, "192.168.61.1/24"),
 			LowerIP:     mustParseIp(t, "192.168.61.50"),
 			UpperIP:     mustParseIp(t, "192.168.61.200"),
 			Enabled:     true,
@@ -63,15 +63,38 @@ func mustParseIp(t *testing.T, ipStr string) net.IP {
 	return ip.To4()
 }
 
-func mustCidrKeepUnmaskIp(t *testing.T, cidrStr string) net.IPNet {
+func mustParseCIDR(t *testing.T, cidrStr string) CIDR {
+	cidr, err := ParseCIDR(cidrStr)
+	require.NoErrorf(t, err, "fail to parse cidr:%s", err)
+	return cidr
+}
 
-	ip, cidr, err := net.ParseCIDR(cidrStr)
-	cidr.IP = ip
-	if !strings.Contains(cidrStr, ":") {
-		// ipv4
-		cidr.IP = cidr.IP.To4()
+func TestDHCPsJSON(t *testing.T) {
+	Setup(t)
+	defer Teardown()
 
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOut("list", "dhcpservers").Return(ReadTestData("vboxmanage-list-dhcpservers-1.out"), nil).Times(1)
+	}
+	out, err := DHCPsJSON()
+	require.NoError(t, err)
+	if ManageMock != nil {
+		require.JSONEq(t, `{
+		"HostInterfaceNetworking-VirtualBox Host-Only Ethernet Adapter": {
+			"networkName": "HostInterfaceNetworking-VirtualBox Host-Only Ethernet Adapter",
+			"ipv4": "192.168.56.100/24", "lowerIP": "192.168.56.101", "upperIP": "192.168.56.254", "enabled": false
+		},
+		"HostInterfaceNetworking-vboxnet5": {
+			"networkName": "HostInterfaceNetworking-vboxnet5",
+			"ipv4": "192.168.61.1/24", "lowerIP": "192.168.61.50", "upperIP": "192.168.61.200", "enabled": true
+		}
+	}`, string(out))
+		return
+	}
+	// the DHCP servers depend on the host: each one is keyed by its network name
+	var dhcps map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(out, &dhcps))
+	for name, dhcp := range dhcps {
+		require.Equal(t, name, dhcp["networkName"])
 	}
-	require.NoErrorf(t, err, "fail to parse cidr:%s", err)
-	return *cidr
 }
@@ -1,12 +1,39 @@
 package virtualbox
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// DiskFormat is the format of a disk image as supported by VirtualBox.
+type DiskFormat string
+
+// Disk image formats VirtualBox can write.
+const (
+	DiskFormatVDI  DiskFormat = "VDI"
+	DiskFormatVMDK DiskFormat = "VMDK"
+	DiskFormatVHD  DiskFormat = "VHD"
 )
 
+// ErrUnsupportedDiskFormat is returned when converting from or to a disk format VirtualBox does not support.
+var ErrUnsupportedDiskFormat = errors.New("unsupported disk format")
+
+// diskSourceFormats maps the supported disk image file extensions to whether they hold a raw image.
+var diskSourceFormats = map[string]bool{
+	".vdi":  false,
+	".vmdk": false,
+	".vhd":  false,
+	".raw":  true,
+	".img":  true,
+}
+
 // MakeDiskImage makes a disk image at dest with the given size in MB. If r is
 // not nil, it will be read as a raw disk image to convert from.
 func MakeDiskImage(dest string, size uint, r io.Reader) error {
@@ -68,3 +95,32 @@ func ZeroFill(w io.Writer, n int64) error {
 	}
 	return nil
 }
+
+// ConvertDisk converts the disk image input into a new image output with the given format.
+//
+// VDI, VMDK and VHD images are cloned into the target format, raw images (.raw, .img) are converted
+// with convertfromraw. Other images, e.g. qcow2 cloud images, must be converted to raw first
+// (e.g. with qemu-img convert -O raw); ErrUnsupportedDiskFormat is returned for them.
+func ConvertDisk(input, output string, format DiskFormat) error {
+	switch format {
+	case DiskFormatVDI, DiskFormatVMDK, DiskFormatVHD:
+	default:
+		return pkgerrors.Wrapf(ErrUnsupportedDiskFormat, "unsupported target format: %q, expected one of VDI, VMDK, VHD", format)
+	}
+	ext := strings.ToLower(filepath.Ext(input))
+	raw, ok := diskSourceFormats[ext]
+	if !ok {
+		return pkgerrors.Wrapf(ErrUnsupportedDiskFormat,
+			"unsupported source image: %q, expected one of .vdi, .vmdk, .vhd or a raw image (.raw, .img)", input)
+	}
+	subCmd := "clonehd"
+	if raw {
+		subCmd = "convertfromraw"
+	}
+	stdout, stderr, err := Manage().runOutErr(subCmd, input, output, "--format", string(format))
+	if err != nil {
+		return pkgerrors.Wrapf(err, "fail to convert disk: input=%q, output=%q, format=%s, err=%q, out=%q",
+			input, output, format, stderr, stdout)
+	}
+	return nil
+}
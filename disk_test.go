@@ -0,0 +1,38 @@
+package virtualbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertDisk(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	raw, vdi, vmdk := "/images/jammy.img", "/images/jammy.vdi", "/images/jammy.vmdk"
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("convertfromraw", raw, vdi, "--format", "VDI").Return("", "", nil).Times(1)
+		ManageMock.EXPECT().runOutErr("clonehd", vdi, vmdk, "--format", "VMDK").Return("", "", nil).Times(1)
+	} else {
+		dir := t.TempDir()
+		raw, vdi, vmdk = filepath.Join(dir, "jammy.img"), filepath.Join(dir, "jammy.vdi"), filepath.Join(dir, "jammy.vmdk")
+		require.NoError(t, os.WriteFile(raw, make([]byte, 1<<20), 0600))
+		t.Cleanup(func() {
+			_ = UnregisterDisk(vmdk)
+			_ = UnregisterDisk(vdi)
+		})
+	}
+
+	require.NoError(t, ConvertDisk(raw, vdi, DiskFormatVDI))
+	require.NoError(t, ConvertDisk(vdi, vmdk, DiskFormatVMDK))
+	if ManageMock == nil {
+		require.FileExists(t, vdi)
+		require.FileExists(t, vmdk)
+	}
+
+	require.ErrorIs(t, ConvertDisk("/images/jammy.qcow2", "/images/jammy.vdi", DiskFormatVDI), ErrUnsupportedDiskFormat)
+	require.ErrorIs(t, ConvertDisk("/images/jammy.img", "/images/jammy.qcow2", DiskFormat("QCOW2")), ErrUnsupportedDiskFormat)
+}
@@ -0,0 +1,96 @@
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+var (
+	// matches e.g. VBoxManage: error: The guest additions are not installed or not running
+	reGuestAdditionsNotAvailable = regexp.MustCompile(`(?i)guest additions.*(not|no longer) (installed|running|active|available)`)
+	// matches the display mode reported by the guest additions, e.g. 1280x800x32
+	reGuestDisplayMode = regexp.MustCompile(`^(\d+)x(\d+)x(\d+)`)
+)
+
+var (
+	// ErrGuestAdditionsNotAvailable holds the error message when an operation requires guest additions
+	// which are not installed or not (yet) running in the guest.
+	ErrGuestAdditionsNotAvailable = errors.New("guest additions not available")
+	// ErrDisplayInfoNotReady holds the error message when the guest additions have not reported
+	// the guest display information (yet).
+	ErrDisplayInfoNotReady = errors.New("display info not reported by the guest")
+)
+
+// SetVideoModeHint asks the guest to switch the given display to the given resolution and color depth.
+// This requires the guest additions to be installed and running.
+func (m *Machine) SetVideoModeHint(width, height, bpp uint, display uint) error {
+	stdout, stderr, err := Manage().runOutErr("controlvm", m.Name, "setvideomodehint",
+		fmt.Sprintf("%d", width), fmt.Sprintf("%d", height), fmt.Sprintf("%d", bpp),
+		fmt.Sprintf("%d", display))
+	if err != nil {
+		if reGuestAdditionsNotAvailable.MatchString(stderr) {
+			return pkgerrors.Wrapf(ErrGuestAdditionsNotAvailable,
+				"fail to set video mode hint: vm=%s, stderr=%s", m.Name, stderr)
+		}
+		return pkgerrors.Wrapf(err,
+			"fail to set video mode hint:\n\tvm=%s \n\tmode=%dx%dx%d \n\tdisplay=%d \n\tstdout=%s \n\tstderr=%s",
+			m.Name, width, height, bpp, display, stdout, stderr)
+	}
+	return nil
+}
+
+// guestDisplayPropPrefix prefixes the guest properties holding the mode of each guest display.
+const guestDisplayPropPrefix = "/VirtualBox/GuestInfo/Display/"
+
+// DisplayMode is the mode of a guest display.
+type DisplayMode struct {
+	Display      uint
+	Width        uint
+	Height       uint
+	BitsPerPixel uint
+}
+
+// DisplayInfo holds the guest displays as reported by the guest additions.
+type DisplayInfo struct {
+	Monitors uint
+	Modes    []DisplayMode // ordered by display
+}
+
+// DisplayInfo returns the guest displays as reported by the guest additions,
+// read from the guest properties /VirtualBox/GuestInfo/Display/<display>.
+// ErrDisplayInfoNotReady is returned if the guest additions have not reported them yet.
+func (m *Machine) DisplayInfo() (*DisplayInfo, error) {
+	props, err := EnumerateGuestProperties(m.Name, guestDisplayPropPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	info := DisplayInfo{}
+	for _, prop := range props {
+		display, err := strconv.ParseUint(strings.TrimPrefix(prop.Name, guestDisplayPropPrefix), 10, 32)
+		if err != nil {
+			continue
+		}
+		res := reGuestDisplayMode.FindStringSubmatch(prop.Value)
+		if res == nil {
+			return nil, fmt.Errorf("unexpected display mode: vm=%s, property=%s, value=%q", m.Name, prop.Name, prop.Value)
+		}
+		mode := DisplayMode{Display: uint(display)}
+		for i, field := range []*uint{&mode.Width, &mode.Height, &mode.BitsPerPixel} {
+			n, _ := strconv.ParseUint(res[i+1], 10, 32)
+			*field = uint(n)
+		}
+		info.Modes = append(info.Modes, mode)
+	}
+	if len(info.Modes) == 0 {
+		return nil, pkgerrors.Wrapf(ErrDisplayInfoNotReady, "fail to get display info: vm=%s", m.Name)
+	}
+	sort.Slice(info.Modes, func(i, j int) bool { return info.Modes[i].Display < info.Modes[j].Display })
+	info.Monitors = uint(len(info.Modes))
+	return &info, nil
+}
@@ -0,0 +1,77 @@
+package virtualbox
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testMachineWithGuestAdditions returns the machine a test runs against, as testMachine, skipping the test
+// unless the real machine is running with its guest additions.
+func testMachineWithGuestAdditions(t *testing.T, vmInfoOut string) *Machine {
+	t.Helper()
+	m := testMachine(t, vmInfoOut, Running)
+	if ManageMock == nil && m.GuestAdditionsRunLevel == 0 {
+		t.Skipf("requires %s to run the guest additions", m.Name)
+	}
+	return m
+}
+
+func TestSetVideoModeHint(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("controlvm", "go-virtualbox", "setvideomodehint", "1280", "800", "32", "0").
+			Return("", "", nil).Times(1)
+	} else {
+		m = testMachineWithGuestAdditions(t, "")
+	}
+	require.NoError(t, m.SetVideoModeHint(1280, 800, 32, 0))
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("controlvm", "go-virtualbox", "setvideomodehint", "1280", "800", "32", "0").
+			Return("", "VBoxManage: error: The guest additions are not installed or not running", errors.New("exit status 1")).Times(1)
+		err := m.SetVideoModeHint(1280, 800, 32, 0)
+		require.Truef(t, errors.Is(err, ErrGuestAdditionsNotAvailable),
+			"should have been ErrGuestAdditionsNotAvailable but got: %v", err)
+	}
+}
+
+func TestDisplayInfo(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("guestproperty", "enumerate", "go-virtualbox", "--patterns", "/VirtualBox/GuestInfo/Display/*").
+			Return("Name: /VirtualBox/GuestInfo/Display/1, value: 1024x768x32, timestamp: 1697449466178830000, flags: \n"+
+				"Name: /VirtualBox/GuestInfo/Display/0, value: 1280x800x32, timestamp: 1697449466178830000, flags: \n", "", nil).Times(1)
+	} else {
+		m = testMachineWithGuestAdditions(t, "")
+	}
+	info, err := m.DisplayInfo()
+	if ManageMock == nil && errors.Is(err, ErrDisplayInfoNotReady) {
+		t.Skipf("the guest additions of %s do not report the display yet", m.Name)
+	}
+	require.NoError(t, err)
+	require.NotZero(t, info.Monitors)
+	require.Len(t, info.Modes, int(info.Monitors))
+	if ManageMock == nil {
+		return
+	}
+	require.Equal(t,
+		&DisplayInfo{Monitors: 2, Modes: []DisplayMode{
+			{Display: 0, Width: 1280, Height: 800, BitsPerPixel: 32},
+			{Display: 1, Width: 1024, Height: 768, BitsPerPixel: 32},
+		}},
+		info)
+
+	// additions not started yet
+	ManageMock.EXPECT().runOutErr("guestproperty", "enumerate", "go-virtualbox", "--patterns", "/VirtualBox/GuestInfo/Display/*").
+		Return("", "", nil).Times(1)
+	_, err = m.DisplayInfo()
+	require.ErrorIs(t, err, ErrDisplayInfoNotReady)
+}
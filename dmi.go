@@ -0,0 +1,72 @@
+package virtualbox
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// DMI holds the SMBIOS/DMI strings presented to the guest, overriding the VirtualBox ones
+// (e.g. "innotek GmbH", "VirtualBox"). Empty fields keep the VirtualBox values.
+type DMI struct {
+	BIOSVendor      string
+	BIOSVersion     string
+	BIOSReleaseDate string // e.g. 12/01/2006
+	SystemVendor    string
+	SystemProduct   string
+	SystemVersion   string
+	SystemSerial    string
+	SystemSKU       string
+	SystemFamily    string
+	BoardVendor     string
+	BoardProduct    string
+	BoardVersion    string
+	BoardSerial     string
+	ChassisVendor   string
+	ChassisVersion  string
+	ChassisSerial   string
+}
+
+// settings returns the DMI config keys with their values, e.g. DmiSystemProduct.
+func (dmi DMI) settings() []struct{ key, value string } {
+	return []struct{ key, value string }{
+		{"DmiBIOSVendor", dmi.BIOSVendor},
+		{"DmiBIOSVersion", dmi.BIOSVersion},
+		{"DmiBIOSReleaseDate", dmi.BIOSReleaseDate},
+		{"DmiSystemVendor", dmi.SystemVendor},
+		{"DmiSystemProduct", dmi.SystemProduct},
+		{"DmiSystemVersion", dmi.SystemVersion},
+		{"DmiSystemSerial", dmi.SystemSerial},
+		{"DmiSystemSKU", dmi.SystemSKU},
+		{"DmiSystemFamily", dmi.SystemFamily},
+		{"DmiBoardVendor", dmi.BoardVendor},
+		{"DmiBoardProduct", dmi.BoardProduct},
+		{"DmiBoardVersion", dmi.BoardVersion},
+		{"DmiBoardSerial", dmi.BoardSerial},
+		{"DmiChassisVendor", dmi.ChassisVendor},
+		{"DmiChassisVersion", dmi.ChassisVersion},
+		{"DmiChassisSerial", dmi.ChassisSerial},
+	}
+}
+
+// ApplyDMI writes the non-empty fields of Machine.DMI as VirtualBox internal settings of the firmware
+// device (pcbios, or efi for EFI firmwares), effective from the next machine start.
+func (m *Machine) ApplyDMI() error {
+	device := "pcbios"
+	if m.isEFI() {
+		device = "efi"
+	}
+	for _, s := range m.DMI.settings() {
+		if s.value == "" {
+			continue
+		}
+		value := s.value
+		if _, err := strconv.ParseUint(value, 10, 64); err == nil {
+			// VirtualBox would take an all digits value as an integer
+			value = "string:" + value
+		}
+		if err := m.SetVBoxInternal(fmt.Sprintf("Devices/%s/0/Config/%s", device, s.key), value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
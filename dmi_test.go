@@ -0,0 +1,54 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDMI(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", Firmware: "efi"}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/Devices/efi/0/Config/DmiSystemVendor", "Dell Inc.").
+				Return(nil).Times(1),
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/Devices/efi/0/Config/DmiSystemProduct", "OptiPlex 7050").
+				Return(nil).Times(1),
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/Devices/efi/0/Config/DmiBoardSerial", "string:12345").
+				Return(nil).Times(1),
+		)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+	}
+	device := "pcbios"
+	if m.isEFI() {
+		device = "efi"
+	}
+	expected := map[string]string{
+		"VBoxInternal/Devices/" + device + "/0/Config/DmiSystemVendor":  "Dell Inc.",
+		"VBoxInternal/Devices/" + device + "/0/Config/DmiSystemProduct": "OptiPlex 7050",
+		"VBoxInternal/Devices/" + device + "/0/Config/DmiBoardSerial":   "string:12345",
+	}
+	if ManageMock == nil {
+		t.Cleanup(func() {
+			for key := range expected {
+				_ = m.DeleteExtraData(key)
+			}
+		})
+	}
+
+	m.DMI = DMI{SystemVendor: "Dell Inc.", SystemProduct: "OptiPlex 7050", BoardSerial: "12345"}
+	require.NoError(t, m.ApplyDMI())
+	if ManageMock == nil {
+		for key, value := range expected {
+			actual, err := m.GetExtraData(key)
+			require.NoError(t, err)
+			require.NotNilf(t, actual, "%s should be set", key)
+			require.Equal(t, value, *actual)
+		}
+	}
+}
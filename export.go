@@ -0,0 +1,30 @@
+package virtualbox
+
+import (
+	"context"
+)
+
+// ExportAppliance exports the given VMs to an ova or ovf appliance at output.
+func ExportAppliance(vms []string, output string, opts ...OperationOption) error {
+	return runOperation("export", func() error {
+		return Manage().run(exportArgs(vms, output)...)
+	}, opts...)
+}
+
+// ExportApplianceCtx exports the given VMs like ExportAppliance, cancelling the export when ctx is done.
+// The partially written output is then removed and the returned error wraps ctx.Err().
+func ExportApplianceCtx(ctx context.Context, vms []string, output string, opts ...OperationOption) error {
+	outputExisted := fileExists(output)
+	return runOperation("export", func() error {
+		err := Manage().setOpts(withContext(ctx)).run(exportArgs(vms, output)...)
+		if errCancel := cancelled(ctx, "export", output, outputExisted); errCancel != nil {
+			return errCancel
+		}
+		return err
+	}, opts...)
+}
+
+func exportArgs(vms []string, output string) []string {
+	args := append([]string{"export"}, vms...)
+	return append(args, "--output", output)
+}
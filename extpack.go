@@ -0,0 +1,106 @@
+package virtualbox
+
+import (
+	"bufio"
+	"errors"
+	"regexp"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// OracleExtensionPack is the name of the Oracle VM VirtualBox Extension Pack.
+const OracleExtensionPack = "Oracle VM VirtualBox Extension Pack"
+
+var (
+	reExtPackStart = regexp.MustCompile(`^Pack no\. \d+:\s*(.*)$`)
+	reExtPackLine  = regexp.MustCompile(`^([^:]+):\s*(.*)$`)
+)
+
+var (
+	// ErrExtensionPackRequired holds the error message when a feature requires the Oracle extension pack,
+	// e.g. VRDE, USB 2.0/3.0, disk encryption or PXE boot for Intel cards.
+	ErrExtensionPackRequired = errors.New("requires " + OracleExtensionPack)
+)
+
+// ExtPack is an installed extension pack.
+type ExtPack struct {
+	Name        string
+	Version     string
+	Revision    string
+	Edition     string
+	Description string
+	VRDEModule  string
+	Usable      bool
+	WhyUnusable string
+}
+
+// ExtensionPacks returns the installed extension packs.
+func ExtensionPacks() ([]ExtPack, error) {
+	stdout, stderr, err := Manage().runOutErr("list", "extpacks")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "fail to list extension packs: stderr=%s", stderr)
+	}
+	return parseExtPacks(stdout), nil
+}
+
+// HasExtensionPack returns true if the Oracle extension pack is installed and usable.
+func HasExtensionPack() (bool, error) {
+	packs, err := ExtensionPacks()
+	if err != nil {
+		return false, err
+	}
+	for _, pack := range packs {
+		if pack.Name == OracleExtensionPack && pack.Usable {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// requireExtensionPack returns ErrExtensionPackRequired if the Oracle extension pack is not usable.
+func requireExtensionPack(feature string) error {
+	has, err := HasExtensionPack()
+	if err != nil {
+		return err
+	}
+	if !has {
+		return pkgerrors.Wrapf(ErrExtensionPackRequired, "feature=%s", feature)
+	}
+	return nil
+}
+
+func parseExtPacks(out string) []ExtPack {
+	packs := []ExtPack{}
+	var pack *ExtPack
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if res := reExtPackStart.FindStringSubmatch(line); res != nil {
+			packs = append(packs, ExtPack{Name: strings.TrimSpace(res[1])})
+			pack = &packs[len(packs)-1]
+			continue
+		}
+		res := reExtPackLine.FindStringSubmatch(line)
+		if pack == nil || res == nil {
+			continue
+		}
+		switch key, val := res[1], strings.TrimSpace(res[2]); key {
+		case "Version":
+			pack.Version = val
+		case "Revision":
+			pack.Revision = val
+		case "Edition":
+			pack.Edition = val
+		case "Description":
+			pack.Description = val
+		case "VRDE Module":
+			pack.VRDEModule = val
+		case "Usable":
+			pack.Usable = val == "true"
+		case "Why unusable":
+			pack.WhyUnusable = val
+		}
+	}
+	return packs
+}
@@ -0,0 +1,46 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtensionPacks(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("list", "extpacks").
+			Return(ReadTestData("vboxmanage-list-extpacks-1.out"), "", nil).Times(2)
+	}
+
+	packs, err := ExtensionPacks()
+	require.NoError(t, err)
+	usable := false
+	for _, pack := range packs {
+		require.NotEmpty(t, pack.Name)
+		require.NotEmpty(t, pack.Version)
+		usable = usable || pack.Name == OracleExtensionPack && pack.Usable
+	}
+	if ManageMock != nil {
+		require.Equal(t,
+			[]ExtPack{
+				{
+					Name: OracleExtensionPack, Version: "6.1.38", Revision: "153438",
+					Description: "Oracle Cloud Infrastructure integration, USB 2.0 and USB 3.0 Host Controller, Host Webcam, VirtualBox RDP, PXE ROM, Disk Encryption, NVMe.",
+					VRDEModule:  "VBoxVRDP", Usable: true,
+				},
+				{
+					Name: "VNC", Version: "6.1.38", Revision: "153438", Description: "VNC plugin module",
+					VRDEModule: "VBoxVNC", Usable: false,
+					WhyUnusable: "The installed version 6.1.38 does not match the VirtualBox version 6.1.40",
+				},
+			},
+			packs)
+	}
+
+	has, err := HasExtensionPack()
+	require.NoError(t, err)
+	require.Equal(t, usable, has)
+}
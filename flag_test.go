@@ -0,0 +1,232 @@
+package virtualbox
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// modifyVMOnOffOptions are the on|off options of <VBoxManage modifyvm> (VirtualBox 6.1, still accepted by 7.x),
+// used instead of the usage of the installed VBoxManage when it is mocked.
+var modifyVMOnOffOptions = map[string]bool{
+	"--acpi": true, "--ioapic": true, "--apic": true, "--x2apic": true, "--pae": true, "--longmode": true,
+	"--hpet": true, "--rtcuseutc": true, "--cpuhotplug": true, "--hwvirtex": true, "--triplefaultreset": true,
+	"--nestedpaging": true, "--largepages": true, "--vtxvpid": true, "--vtxux": true, "--nested-hw-virt": true,
+	"--accelerate3d": true, "--accelerate2dvideo": true, "--spec-ctrl": true, "--ibpb-on-vm-exit": true,
+	"--ibpb-on-vm-entry": true, "--l1d-flush-on-sched": true, "--l1d-flush-on-vm-entry": true,
+	"--mds-clear-on-sched": true, "--mds-clear-on-vm-entry": true,
+}
+
+// e.g. [--acpi on|off] (6.1) or [--acpi=on | off] (7.x)
+var reModifyVMOnOffOption = regexp.MustCompile(`(--[a-z0-9-]+)[ =]on ?\| ?off\b`)
+
+// parseModifyVMOnOffOptions returns the on|off options found in the <VBoxManage modifyvm> usage.
+func parseModifyVMOnOffOptions(usage string) map[string]bool {
+	options := map[string]bool{}
+	for _, res := range reModifyVMOnOffOption.FindAllStringSubmatch(usage, -1) {
+		options[res[1]] = true
+	}
+	return options
+}
+
+// normalizeModifyVMOption removes the dashes within an option, as VirtualBox 7 renamed e.g. --rtcuseutc
+// to --rtc-use-utc while still accepting the former name.
+func normalizeModifyVMOption(option string) string {
+	return "--" + strings.ReplaceAll(strings.TrimPrefix(option, "--"), "-", "")
+}
+
+func TestParseModifyVMOnOffOptions(t *testing.T) {
+	options := parseModifyVMOnOffOptions(`Usage:
+VBoxManage modifyvm         <uuid|vmname>
+                            [--name <name>]
+                            [--acpi on|off]
+                            [--rtc-use-utc=on | off]
+                            [--graphicscontroller none|vboxvga|vmsvga|vboxsvga]
+`)
+	require.Equal(t, map[string]bool{"--acpi": true, "--rtc-use-utc": true}, options)
+}
+
+func TestEveryFlagMapsToAValidModifyVMOption(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	var options map[string]bool
+	if ManageMock != nil {
+		options = modifyVMOnOffOptions
+	} else {
+		// the usage is printed along with a syntax error
+		stdout, stderr, _ := Manage().runOutErr("modifyvm")
+		options = parseModifyVMOnOffOptions(stdout + stderr)
+		require.NotEmpty(t, options, "no on|off option found in the modifyvm usage: %s%s", stdout, stderr)
+	}
+	valid := map[string]bool{}
+	for option := range options {
+		valid[normalizeModifyVMOption(option)] = true
+	}
+
+	retired := Flag(1 << 6) // formerly SYNTHCPU
+	mapped := map[Flag]string{}
+	for _, fo := range flagOptions {
+		require.Truef(t, valid[normalizeModifyVMOption(fo.option)], "flag %d maps to unknown modifyvm option %s", fo.flag, fo.option)
+		_, dup := mapped[fo.flag]
+		require.Falsef(t, dup, "flag %d is mapped more than once", fo.flag)
+		mapped[fo.flag] = fo.option
+	}
+	for f := ACPI; f <= ACCELERATE2DVIDEO; f <<= 1 {
+		if f == retired {
+			require.NotContainsf(t, mapped, f, "retired flag %d should not be emitted", f)
+			continue
+		}
+		require.Containsf(t, mapped, f, "flag %d has no modifyvm option", f)
+	}
+}
+
+func TestFlagsRoundTripThroughGetMachineAndModify(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	if ManageMock == nil {
+		var err error
+		vmInfoOut, _, err = Manage().runOutErr("showvminfo", VM, "--machinereadable")
+		require.NoError(t, err)
+	}
+	propMap, err := vminfoAsPropMap(strings.NewReader(vmInfoOut))
+	require.NoError(t, err)
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		require.NotZero(t, m.Flag&ACPI, "acpi is on in the VM info")
+		require.Zero(t, m.Flag&HPET, "hpet is off in the VM info")
+		modifyArgs = expectModifyVM("go-virtualbox", vmInfoOut)
+	} else {
+		var args []CmdArg
+		for _, fo := range flagOptions {
+			if value, ok := propMap[strings.TrimPrefix(fo.option, "--")]; ok {
+				args = append(args, NewCmdArg(fo.option, value))
+			}
+		}
+		restoreOnCleanup(t, m, args...)
+	}
+
+	flags := m.Flag
+	require.NoError(t, m.Modify())
+	require.Equal(t, flags, m.Flag, "modify should keep the flags as in the VM info")
+	if ManageMock != nil {
+		for _, fo := range flagOptions {
+			key := strings.TrimPrefix(fo.option, "--")
+			infoValue, ok := propMap[key]
+			if !ok {
+				continue
+			}
+			value, sent := argValue(*modifyArgs, fo.option)
+			if fo.optIn && !sent {
+				require.Equalf(t, "off", infoValue, "modifyvm should send %s when on in the VM info", fo.option)
+				continue
+			}
+			require.Equalf(t, infoValue, value, "modifyvm should keep %s as in the VM info", fo.option)
+		}
+	}
+	require.Equal(t, m.Flag, flagsFromPropMap(propMap))
+}
+
+func TestAPICFlagsMapOneToOne(t *testing.T) {
+	options := map[Flag]string{}
+	for _, fo := range flagOptions {
+		options[fo.flag] = fo.option
+	}
+	require.Equal(t, "--acpi", options[ACPI])
+	require.Equal(t, "--ioapic", options[IOAPIC])
+	require.Equal(t, "--apic", options[APIC])
+	require.Equal(t, "--x2apic", options[X2APIC])
+
+	m := New()
+	m.Flag = ACPI | APIC
+	require.Equal(t, "on", m.Flag.Get(APIC))
+	require.Equal(t, "off", m.Flag.Get(IOAPIC))
+
+	cmdArgs := CmdArgs{}
+	m.Flag = ACPI
+	cmdArgs.AppendCmdArgs(m.flagCmdArgs()...)
+	_, sent := argValue(cmdArgs.Args(), "--apic")
+	require.False(t, sent, "apic should be kept when not set")
+
+	cmdArgs = CmdArgs{}
+	m.ExplicitFlags = APIC
+	cmdArgs.AppendCmdArgs(m.flagCmdArgs()...)
+	apic, _ := argValue(cmdArgs.Args(), "--apic")
+	require.Equal(t, "off", apic)
+}
+
+func TestFlagCmdArgsOnlyApplyOptInFlagsWhenSet(t *testing.T) {
+	args := func(m *Machine) []string {
+		cmdArgs := CmdArgs{}
+		cmdArgs.AppendCmdArgs(m.flagCmdArgs()...)
+		return cmdArgs.Args()
+	}
+	m := New()
+	m.Flag = ACPI
+	acpi, _ := argValue(args(m), "--acpi")
+	require.Equal(t, "on", acpi)
+	_, sent := argValue(args(m), "--x2apic")
+	require.False(t, sent, "x2apic should be kept when not set")
+
+	m.Flag |= X2APIC
+	x2apic, _ := argValue(args(m), "--x2apic")
+	require.Equal(t, "on", x2apic)
+
+	m.Flag &^= X2APIC
+	m.ExplicitFlags = X2APIC
+	x2apic, _ = argValue(args(m), "--x2apic")
+	require.Equal(t, "off", x2apic)
+}
+
+func TestSetRTCUseUTC(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", Flag: ACPI | RTCUSEUTC}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--rtcuseutc", "off").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--rtcuseutc", "on").Return("", "", nil).Times(1),
+		)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		utc := m.Flag&RTCUSEUTC != 0
+		t.Cleanup(func() { require.NoError(t, m.SetRTCUseUTC(utc)) })
+	}
+	others := m.Flag &^ RTCUSEUTC
+
+	require.NoError(t, m.SetRTCUseUTC(false))
+	require.Equal(t, others, m.Flag)
+	if ManageMock == nil {
+		require.NoError(t, m.Refresh())
+		require.Zero(t, m.Flag&RTCUSEUTC)
+	}
+
+	require.NoError(t, m.SetRTCUseUTC(true))
+	require.Equal(t, others|RTCUSEUTC, m.Flag)
+	if ManageMock == nil {
+		require.NoError(t, m.Refresh())
+		require.NotZero(t, m.Flag&RTCUSEUTC)
+	}
+}
+
+func TestAccelerate2DVideoFlag(t *testing.T) {
+	vmInfoOut := strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"),
+		`accelerate2dvideo="off"`, `accelerate2dvideo="on"`, 1)
+	propMap, err := vminfoAsPropMap(strings.NewReader(vmInfoOut))
+	require.NoError(t, err)
+
+	f := flagsFromPropMap(propMap)
+	require.Equal(t, "on", f.Get(ACCELERATE2DVIDEO))
+	require.Equal(t, "off", f.Get(ACCELERATE3D))
+
+	cmdArgs := CmdArgs{}
+	cmdArgs.AppendCmdArgs((&Machine{Flag: f &^ ACCELERATE2DVIDEO}).flagCmdArgs()...)
+	_, sent := argValue(cmdArgs.Args(), "--accelerate2dvideo")
+	require.False(t, sent, "accelerate2dvideo is not supported by VirtualBox 7 and should only be sent when set")
+}
@@ -0,0 +1,290 @@
+package virtualbox
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// GuestCredentials holds the guest account used by guest control operations.
+type GuestCredentials struct {
+	Username     string
+	Password     string // never put on the command line, written to a temporary password file instead
+	PasswordFile string // file containing the password, takes precedence over Password
+	Domain       string
+}
+
+// GuestExec describes a program to be run in the guest with <VBoxManage guestcontrol run>.
+type GuestExec struct {
+	VM          string
+	Credentials GuestCredentials
+	Exe         string        // absolute path of the program in the guest
+	Args        []string      // program arguments, including argv[0]
+	Env         []string      // NAME=VALUE pairs to set, NAME= to unset, in the guest process environment
+	Timeout     time.Duration // 0 for no timeout
+}
+
+// cmdArgs returns the guestcontrol credentials arguments, given the password file to use.
+func (cred GuestCredentials) cmdArgs(passwordFile string) []string {
+	args := make([]string, 0, 6)
+	if cred.Username != "" {
+		args = append(args, "--username", cred.Username)
+	}
+	if passwordFile != "" {
+		args = append(args, "--passwordfile", passwordFile)
+	}
+	if cred.Domain != "" {
+		args = append(args, "--domain", cred.Domain)
+	}
+	return args
+}
+
+// withPasswordFile calls f with the password file to use with these credentials.
+// If only a password is given, it is written to a temporary file which is removed once f returns.
+func (cred GuestCredentials) withPasswordFile(f func(passwordFile string) error) error {
+	return withPasswordFile(cred.Password, cred.PasswordFile, f)
+}
+
+// withPasswordFile calls f with passwordFile if given, otherwise with a temporary file containing password,
+// which is removed once f returns. f is called with an empty file name if there is no password at all.
+func withPasswordFile(password, passwordFile string, f func(passwordFile string) error) error {
+	if passwordFile != "" || password == "" {
+		return f(passwordFile)
+	}
+	pwFile, err := os.CreateTemp("", "go-virtualbox-pw-*")
+	if err != nil {
+		return pkgerrors.Wrap(err, "fail to create temporary password file")
+	}
+	defer os.Remove(pwFile.Name())
+	_, errW := pwFile.WriteString(password)
+	if errC := pwFile.Close(); errW == nil {
+		errW = errC
+	}
+	if errW != nil {
+		return pkgerrors.Wrapf(errW, "fail to write temporary password file: %s", pwFile.Name())
+	}
+	return f(pwFile.Name())
+}
+
+func (cfg GuestExec) runArgs(passwordFile string) []string {
+	args := []string{"guestcontrol", cfg.VM, "run"}
+	args = append(args, cfg.Credentials.cmdArgs(passwordFile)...)
+	if cfg.Exe != "" {
+		args = append(args, "--exe", cfg.Exe)
+	}
+	if cfg.Timeout > 0 {
+		args = append(args, "--timeout", fmt.Sprintf("%d", cfg.Timeout.Milliseconds()))
+	}
+	for _, env := range cfg.Env {
+		args = append(args, "--putenv", env)
+	}
+	args = append(args, "--wait-stdout", "--wait-stderr", "--")
+	args = append(args, cfg.Args...)
+	return args
+}
+
+// RunGuestControlStream runs a program in the guest, streaming its stdout and stderr
+// to the given writers while it runs.
+//
+// The returned exit code is the one of <VBoxManage guestcontrol run>, which reflects the guest program
+// exit code. err is not nil if the program could not be run or exited with a non-zero code.
+func RunGuestControlStream(cfg GuestExec, stdout, stderr io.Writer) (exitCode int, err error) {
+	if cfg.Exe == "" && len(cfg.Args) == 0 {
+		return -1, fmt.Errorf("no program to run in guest: vm=%s", cfg.VM)
+	}
+	err = cfg.Credentials.withPasswordFile(func(passwordFile string) error {
+		return Manage().setOpts(outWriter(stdout), errWriter(stderr)).run(cfg.runArgs(passwordFile)...)
+	})
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), pkgerrors.Wrapf(err,
+				"guest program exited with non-zero code: vm=%s, exe=%s, args=%v",
+				cfg.VM, cfg.Exe, cfg.Args)
+		}
+		return -1, pkgerrors.Wrapf(err, "fail to run guest program: vm=%s, exe=%s, args=%v",
+			cfg.VM, cfg.Exe, cfg.Args)
+	}
+	return 0, nil
+}
+
+// SetGuestCredentials hands the given credentials over to the guest additions of the running machine,
+// e.g. for the auto-logon module of Windows guests. The password is passed through a temporary password file.
+func (m *Machine) SetGuestCredentials(user, password, domain string, allowLocalLogon bool) error {
+	return withPasswordFile(password, "", func(passwordFile string) error {
+		args := []string{"controlvm", m.Name, "setcredentials", user}
+		if passwordFile != "" {
+			args = append(args, "--passwordfile", passwordFile)
+		} else {
+			args = append(args, "")
+		}
+		allow := "no"
+		if allowLocalLogon {
+			allow = "yes"
+		}
+		args = append(args, domain, "--allowlocallogon", allow)
+		stdout, stderr, err := Manage().runOutErr(args...)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "fail to set guest credentials: vm=%s, user=%s, stdout=%s, stderr=%s",
+				m.Name, user, stdout, stderr)
+		}
+		return nil
+	})
+}
+
+// GuestCopy describes files to be copied from the host to the guest with <VBoxManage guestcontrol copyto>.
+type GuestCopy struct {
+	VM                string
+	Credentials       GuestCredentials
+	Source            string      // host file or directory
+	Target            string      // guest file path, or guest directory to copy into if Recursive
+	Recursive         bool        // copy directories recursively
+	Follow            bool        // follow symbolic links
+	Mode              os.FileMode // if not 0, the permissions set on the copy, e.g. 0755 for scripts to be run; on all its files and directories if Recursive
+	Dos2Unix          bool        // convert CRLF line endings to LF while copying, single files only
+	Unix2Dos          bool        // convert LF line endings to CRLF while copying, single files only
+	PreserveTimestamp bool        // set the modification time of the copy to the one of Source
+}
+
+// ErrInvalidGuestCopy is returned when the guest copy options are inconsistent.
+var ErrInvalidGuestCopy = errors.New("invalid guest copy")
+
+// destination returns the guest path of the copy of Source.
+func (cfg GuestCopy) destination() string {
+	if cfg.Recursive {
+		return path.Join(cfg.Target, filepath.Base(cfg.Source))
+	}
+	return cfg.Target
+}
+
+// copyToArgs returns the <VBoxManage guestcontrol copyto> arguments, given the password file to use
+// and the host file or directory to copy.
+//
+// A single file is copied to Target with the positional form, a recursive copy goes into the Target directory.
+func (cfg GuestCopy) copyToArgs(passwordFile, source string) []string {
+	args := []string{"guestcontrol", cfg.VM, "copyto"}
+	args = append(args, cfg.Credentials.cmdArgs(passwordFile)...)
+	if cfg.Recursive {
+		args = append(args, "--recursive")
+	}
+	if cfg.Follow {
+		args = append(args, "--follow")
+	}
+	if cfg.Recursive {
+		return append(args, "--target-directory", cfg.Target, source)
+	}
+	return append(args, source, cfg.Target)
+}
+
+func (cfg GuestCopy) validate() error {
+	if cfg.Dos2Unix && cfg.Unix2Dos {
+		return pkgerrors.Wrap(ErrInvalidGuestCopy, "dos2unix and unix2dos are mutually exclusive")
+	}
+	if cfg.Recursive && (cfg.Dos2Unix || cfg.Unix2Dos) {
+		return pkgerrors.Wrap(ErrInvalidGuestCopy, "line endings can only be converted for a single file")
+	}
+	return nil
+}
+
+// convertLineEndings writes a copy of source with converted line endings to a temporary file,
+// and returns its name. The caller has to remove it.
+func (cfg GuestCopy) convertLineEndings(source string) (string, error) {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return "", pkgerrors.Wrapf(err, "fail to read file to convert: %s", source)
+	}
+	data = bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))
+	if cfg.Unix2Dos {
+		data = bytes.ReplaceAll(data, []byte("\n"), []byte("\r\n"))
+	}
+	tmp, err := os.CreateTemp("", "go-virtualbox-copy-*")
+	if err != nil {
+		return "", pkgerrors.Wrap(err, "fail to create temporary file for line endings conversion")
+	}
+	_, errW := tmp.Write(data)
+	if errC := tmp.Close(); errW == nil {
+		errW = errC
+	}
+	if errW != nil {
+		os.Remove(tmp.Name())
+		return "", pkgerrors.Wrapf(errW, "fail to write temporary file: %s", tmp.Name())
+	}
+	return tmp.Name(), nil
+}
+
+// CopyToGuest copies files from the host to the guest.
+//
+// As copyto neither preserves file permissions nor timestamps, a non-zero Mode and PreserveTimestamp are applied
+// afterwards by running chmod and touch in the guest, which requires a posix guest.
+// Line endings conversions are done on the host before copying.
+func CopyToGuest(cfg GuestCopy) error {
+	if err := cfg.validate(); err != nil {
+		return pkgerrors.Wrapf(err, "fail to copy to guest: vm=%s, source=%s, target=%s", cfg.VM, cfg.Source, cfg.Target)
+	}
+	var mtime time.Time
+	if cfg.PreserveTimestamp {
+		fi, err := os.Stat(cfg.Source)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "fail to get source timestamp: source=%s", cfg.Source)
+		}
+		mtime = fi.ModTime()
+	}
+	source := cfg.Source
+	if cfg.Dos2Unix || cfg.Unix2Dos {
+		converted, err := cfg.convertLineEndings(cfg.Source)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(converted)
+		source = converted
+	}
+	dst := cfg.destination()
+	return cfg.Credentials.withPasswordFile(func(passwordFile string) error {
+		stdout, stderr, err := Manage().runOutErr(cfg.copyToArgs(passwordFile, source)...)
+		if err != nil {
+			return pkgerrors.Wrapf(err, "fail to copy to guest: vm=%s, source=%s, target=%s, stdout=%s, stderr=%s",
+				cfg.VM, cfg.Source, cfg.Target, stdout, stderr)
+		}
+		if cfg.Mode != 0 {
+			args := []string{"chmod", fmt.Sprintf("%o", cfg.Mode.Perm()), dst}
+			if cfg.Recursive {
+				args = []string{"chmod", "-R", fmt.Sprintf("%o", cfg.Mode.Perm()), dst}
+			}
+			chmod := GuestExec{
+				VM:          cfg.VM,
+				Credentials: cfg.Credentials,
+				Exe:         "/bin/chmod",
+				Args:        args,
+			}
+			stdout, stderr, err = Manage().runOutErr(chmod.runArgs(passwordFile)...)
+			if err != nil {
+				return pkgerrors.Wrapf(err, "fail to set mode of guest file: vm=%s, path=%s, mode=%o, stdout=%s, stderr=%s",
+					cfg.VM, dst, cfg.Mode.Perm(), stdout, stderr)
+			}
+		}
+		if cfg.PreserveTimestamp {
+			// posix touch -t, the time being given in UTC
+			touch := GuestExec{
+				VM:          cfg.VM,
+				Credentials: cfg.Credentials,
+				Exe:         "/bin/touch",
+				Args:        []string{"touch", "-m", "-t", mtime.UTC().Format("200601021504.05"), dst},
+				Env:         []string{"TZ=UTC"},
+			}
+			stdout, stderr, err = Manage().runOutErr(touch.runArgs(passwordFile)...)
+			if err != nil {
+				return pkgerrors.Wrapf(err, "fail to set timestamp of guest file: vm=%s, path=%s, mtime=%s, stdout=%s, stderr=%s",
+					cfg.VM, dst, mtime, stdout, stderr)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,289 @@
+package virtualbox
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// testGuestCredentials returns the credentials of a TEST_VM guest user, as given by TEST_GUEST_USER and
+// TEST_GUEST_PASSWORD, skipping the test if they are not set.
+func testGuestCredentials(t *testing.T) GuestCredentials {
+	t.Helper()
+	cred := GuestCredentials{Username: os.Getenv("TEST_GUEST_USER"), Password: os.Getenv("TEST_GUEST_PASSWORD")}
+	if cred.Username == "" {
+		t.Skip("requires TEST_GUEST_USER and TEST_GUEST_PASSWORD for the guest of TEST_VM")
+	}
+	return cred
+}
+
+// guestShell runs the given posix shell script in the TEST_VM guest and returns its standard output.
+func guestShell(t *testing.T, cred GuestCredentials, script string) string {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	_, err := RunGuestControlStream(GuestExec{
+		VM:          VM,
+		Credentials: cred,
+		Exe:         "/bin/sh",
+		Args:        []string{"sh", "-c", script},
+		Timeout:     time.Minute,
+	}, &stdout, &stderr)
+	require.NoErrorf(t, err, "stderr=%s", stderr.String())
+	return stdout.String()
+}
+
+// guestTempDir creates a temporary directory in the TEST_VM guest, removed once the test is done.
+func guestTempDir(t *testing.T, cred GuestCredentials) string {
+	t.Helper()
+	dir := strings.TrimSpace(guestShell(t, cred, "mktemp -d"))
+	require.NotEmpty(t, dir)
+	t.Cleanup(func() { guestShell(t, cred, "rm -rf '"+dir+"'") })
+	return dir
+}
+
+func TestRunGuestControlStreamArgs(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	cfg := GuestExec{
+		VM:          "go-virtualbox",
+		Credentials: GuestCredentials{Username: "vagrant", Password: "s3cr3t"},
+		Exe:         "/usr/bin/apt-get",
+		Args:        []string{"apt-get", "upgrade", "-y"},
+		Env:         []string{"DEBIAN_FRONTEND=noninteractive"},
+		Timeout:     10 * time.Minute,
+	}
+	var runArgs []string
+	var password []byte
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().setOpts(gomock.Any(), gomock.Any()).Return(ManageMock).Times(1),
+			ManageMock.EXPECT().run(gomock.Any()).DoAndReturn(func(args ...string) error {
+				runArgs = args
+				pwFile, _ := argValue(args, "--passwordfile")
+				var err error
+				password, err = os.ReadFile(pwFile)
+				return err
+			}).Times(1),
+		)
+	} else {
+		testMachine(t, "", Running)
+		cfg = GuestExec{
+			VM:          VM,
+			Credentials: testGuestCredentials(t),
+			Exe:         "/bin/sh",
+			Args:        []string{"sh", "-c", "echo $GREETING; echo err >&2"},
+			Env:         []string{"GREETING=hello"},
+			Timeout:     time.Minute,
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	exitCode, err := RunGuestControlStream(cfg, &stdout, &stderr)
+	require.NoError(t, err)
+	require.Equal(t, 0, exitCode)
+	if ManageMock == nil {
+		require.Equal(t, "hello\n", stdout.String())
+		require.Equal(t, "err\n", stderr.String())
+		return
+	}
+	require.Equal(t, "s3cr3t", string(password))
+	pwFile, _ := argValue(runArgs, "--passwordfile")
+	require.Equal(t,
+		[]string{
+			"guestcontrol", "go-virtualbox", "run", "--username", "vagrant", "--passwordfile", pwFile,
+			"--exe", "/usr/bin/apt-get", "--timeout", "600000", "--putenv", "DEBIAN_FRONTEND=noninteractive",
+			"--wait-stdout", "--wait-stderr", "--", "apt-get", "upgrade", "-y",
+		},
+		runArgs)
+	_, err = os.Stat(pwFile)
+	require.Truef(t, os.IsNotExist(err), "temporary password file should have been removed: %s", pwFile)
+}
+
+func TestCommandRunStreamsToWriters(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := command{program: "sh"}
+
+	err := cmd.setOpts(outWriter(&stdout), errWriter(&stderr)).run("-c", "echo out; echo err >&2; exit 3")
+
+	require.Equal(t, "out\n", stdout.String())
+	require.Equal(t, "err\n", stderr.String())
+	var exitErr *exec.ExitError
+	require.Truef(t, errors.As(err, &exitErr) && exitErr.ExitCode() == 3,
+		"exit code should be available from the error: %v", err)
+}
+
+func TestSetGuestCredentials(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	user, password, domain := "Administrator", "s3cr3t", "LAB"
+	var credArgs []string
+	var pwContent []byte
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr(gomock.Any()).DoAndReturn(func(args ...string) (string, string, error) {
+			credArgs = args
+			pwFile, _ := argValue(args, "--passwordfile")
+			var err error
+			pwContent, err = os.ReadFile(pwFile)
+			return "", "", err
+		}).Times(1)
+	} else {
+		m = testMachineWithGuestAdditions(t, "")
+		cred := testGuestCredentials(t)
+		user, password, domain = cred.Username, cred.Password, cred.Domain
+	}
+
+	require.NoError(t, m.SetGuestCredentials(user, password, domain, true))
+	if ManageMock != nil {
+		require.Equal(t, "s3cr3t", string(pwContent))
+		pwFile, _ := argValue(credArgs, "--passwordfile")
+		require.Equal(t,
+			[]string{"controlvm", "go-virtualbox", "setcredentials", "Administrator", "--passwordfile", pwFile,
+				"LAB", "--allowlocallogon", "yes"},
+			credArgs)
+	}
+}
+
+func TestCopyToGuestWithMode(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	cfg := GuestCopy{
+		VM:          "go-virtualbox",
+		Credentials: GuestCredentials{Username: "vagrant", PasswordFile: "/etc/vbox/pw"},
+		Source:      "provision.sh",
+		Target:      "/tmp/provision.sh",
+		Mode:        0755,
+	}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("guestcontrol", "go-virtualbox", "copyto", "--username", "vagrant",
+				"--passwordfile", "/etc/vbox/pw", "provision.sh", "/tmp/provision.sh").
+				Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("guestcontrol", "go-virtualbox", "run", "--username", "vagrant",
+				"--passwordfile", "/etc/vbox/pw", "--exe", "/bin/chmod", "--wait-stdout", "--wait-stderr", "--",
+				"chmod", "755", "/tmp/provision.sh").
+				Return("", "", nil).Times(1),
+		)
+	} else {
+		testMachine(t, "", Running)
+		cfg.VM = VM
+		cfg.Credentials = testGuestCredentials(t)
+		cfg.Source = filepath.Join(t.TempDir(), "provision.sh")
+		require.NoError(t, os.WriteFile(cfg.Source, []byte("#!/bin/sh\necho ok\n"), 0644))
+		cfg.Target = guestTempDir(t, cfg.Credentials) + "/provision.sh"
+	}
+
+	require.NoError(t, CopyToGuest(cfg))
+	if ManageMock == nil {
+		require.Equal(t, "755\nok\n", guestShell(t, cfg.Credentials, "stat -c %a "+cfg.Target+" && "+cfg.Target))
+	}
+}
+
+func TestCopyToGuestRecursiveWithMode(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	cfg := GuestCopy{
+		VM:          "go-virtualbox",
+		Credentials: GuestCredentials{Username: "vagrant", PasswordFile: "/etc/vbox/pw"},
+		Source:      "testdata/scripts",
+		Target:      "/opt",
+		Recursive:   true,
+		Mode:        0700,
+	}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("guestcontrol", "go-virtualbox", "copyto", "--username", "vagrant",
+				"--passwordfile", "/etc/vbox/pw", "--recursive", "--target-directory", "/opt", "testdata/scripts").
+				Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("guestcontrol", "go-virtualbox", "run", "--username", "vagrant",
+				"--passwordfile", "/etc/vbox/pw", "--exe", "/bin/chmod", "--wait-stdout", "--wait-stderr", "--",
+				"chmod", "-R", "700", "/opt/scripts").
+				Return("", "", nil).Times(1),
+		)
+	} else {
+		testMachine(t, "", Running)
+		cfg.VM = VM
+		cfg.Credentials = testGuestCredentials(t)
+		cfg.Source = filepath.Join(t.TempDir(), "scripts")
+		require.NoError(t, os.Mkdir(cfg.Source, 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(cfg.Source, "setup.sh"), []byte("#!/bin/sh\n"), 0644))
+		cfg.Target = guestTempDir(t, cfg.Credentials)
+	}
+
+	require.NoError(t, CopyToGuest(cfg))
+	if ManageMock == nil {
+		require.Equal(t, "700\n700\n", guestShell(t, cfg.Credentials, "stat -c %a "+cfg.Target+"/scripts "+cfg.Target+"/scripts/setup.sh"))
+	}
+}
+
+func TestCopyToGuestDos2UnixAndTimestamp(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	source := filepath.Join(t.TempDir(), "provision.sh")
+	require.NoError(t, os.WriteFile(source, []byte("#!/bin/sh\r\necho ok\r\n"), 0644))
+	mtime := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	require.NoError(t, os.Chtimes(source, mtime, mtime))
+
+	cfg := GuestCopy{
+		VM:                "go-virtualbox",
+		Credentials:       GuestCredentials{Username: "vagrant", PasswordFile: "/etc/vbox/pw"},
+		Source:            source,
+		Target:            "/tmp/provision.sh",
+		Dos2Unix:          true,
+		PreserveTimestamp: true,
+	}
+	var copied string
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("guestcontrol", "go-virtualbox", "copyto", "--username", "vagrant",
+				"--passwordfile", "/etc/vbox/pw", gomock.Any(), "/tmp/provision.sh").
+				DoAndReturn(func(args ...string) (string, string, error) {
+					data, err := os.ReadFile(args[len(args)-2])
+					require.NoError(t, err)
+					copied = string(data)
+					return "", "", nil
+				}).Times(1),
+			ManageMock.EXPECT().runOutErr("guestcontrol", "go-virtualbox", "run", "--username", "vagrant",
+				"--passwordfile", "/etc/vbox/pw", "--exe", "/bin/touch", "--putenv", "TZ=UTC",
+				"--wait-stdout", "--wait-stderr", "--", "touch", "-m", "-t", "202103040506.07", "/tmp/provision.sh").
+				Return("", "", nil).Times(1),
+		)
+	} else {
+		testMachine(t, "", Running)
+		cfg.VM = VM
+		cfg.Credentials = testGuestCredentials(t)
+		cfg.Target = guestTempDir(t, cfg.Credentials) + "/provision.sh"
+	}
+
+	require.NoError(t, CopyToGuest(cfg))
+	if ManageMock == nil {
+		copied = guestShell(t, cfg.Credentials, "cat "+cfg.Target)
+		require.Equal(t, strconv.FormatInt(mtime.Unix(), 10)+"\n", guestShell(t, cfg.Credentials, "stat -c %Y "+cfg.Target))
+	}
+	require.Equal(t, "#!/bin/sh\necho ok\n", copied)
+}
+
+func TestCopyToGuestInvalid(t *testing.T) {
+	err := CopyToGuest(GuestCopy{VM: "go-virtualbox", Source: "scripts", Target: "/opt", Recursive: true, Unix2Dos: true})
+	require.True(t, errors.Is(err, ErrInvalidGuestCopy), "got %v", err)
+	err = CopyToGuest(GuestCopy{VM: "go-virtualbox", Source: "a.txt", Target: "/tmp/a.txt", Dos2Unix: true, Unix2Dos: true})
+	require.True(t, errors.Is(err, ErrInvalidGuestCopy), "got %v", err)
+}
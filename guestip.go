@@ -0,0 +1,78 @@
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// guestNetPropPrefix prefixes the guest properties holding the network interfaces of the guest,
+// e.g. /VirtualBox/GuestInfo/Net/0/MAC and /VirtualBox/GuestInfo/Net/0/V4/IP.
+const guestNetPropPrefix = "/VirtualBox/GuestInfo/Net/"
+
+// ErrGuestIPNotAvailable holds the error message when the guest additions have not reported
+// the IP address of a NIC (yet), e.g. because the guest is still booting or has not got a DHCP lease.
+var ErrGuestIPNotAvailable = errors.New("guest IP not available")
+
+// ResolveGuestIP returns the IPv4 address of the guest on the n-th NIC (1-based) of the machine,
+// which must have been read with GetMachine. The guest interface is found by the MAC address of the NIC
+// among the ones reported by the guest additions.
+//
+// ErrGuestIPNotAvailable is returned if the guest has not reported it yet; the error then mentions
+// the range of the DHCP server of the network for host-only and NAT network NICs.
+func (m *Machine) ResolveGuestIP(n int) (net.IP, error) {
+	if n < 1 || n > len(m.NICs) {
+		return nil, fmt.Errorf("no such nic: vm=%s, nic=%d, nics=%d", m.Name, n, len(m.NICs))
+	}
+	nic := m.NICs[n-1]
+	if nic.Network == NICNetAbsent || nic.Network == NICNetDisconnected || nic.MacAddr == "" {
+		return nil, fmt.Errorf("nic is not attached to a network: vm=%s, nic=%d, network=%s", m.Name, n, nic.Network)
+	}
+	props, err := EnumerateGuestProperties(m.Name, guestNetPropPrefix+"*")
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(props))
+	for _, prop := range props {
+		values[prop.Name] = prop.Value
+	}
+	for _, prop := range props {
+		iface := strings.TrimSuffix(prop.Name, "/MAC")
+		if iface == prop.Name || !sameMacAddr(prop.Value, nic.MacAddr) {
+			continue
+		}
+		if ip := net.ParseIP(values[iface+"/V4/IP"]).To4(); ip != nil {
+			return ip, nil
+		}
+	}
+	return nil, pkgerrors.Wrapf(ErrGuestIPNotAvailable, "vm=%s, nic=%d, mac=%s%s", m.Name, n, nic.MacAddr, nicDHCPRange(nic))
+}
+
+// sameMacAddr returns true if both MAC addresses are the same, whatever their separators and case.
+func sameMacAddr(a, b string) bool {
+	normalize := strings.NewReplacer(":", "", "-", "")
+	return strings.EqualFold(normalize.Replace(a), normalize.Replace(b))
+}
+
+// nicDHCPRange describes the range of the DHCP server of the network of the NIC, empty if there is none.
+func nicDHCPRange(nic NIC) string {
+	key := nic.HostonlyDHCPKey()
+	if nic.Network == NICNetNATNetwork {
+		key = nic.NetworkName
+	}
+	if key == "" {
+		return ""
+	}
+	dhcps, err := DHCPs()
+	if err != nil {
+		return ""
+	}
+	dhcp, ok := dhcps[key]
+	if !ok {
+		return fmt.Sprintf(", dhcp=none (network=%s)", key)
+	}
+	return fmt.Sprintf(", dhcp=%s-%s (network=%s, enabled=%t)", dhcp.LowerIP, dhcp.UpperIP, key, dhcp.Enabled)
+}
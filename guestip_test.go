@@ -0,0 +1,70 @@
+package virtualbox
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveGuestIP(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", NICs: []NIC{
+		{Network: NICNetNAT, Hardware: IntelPro1000MTDesktop, MacAddr: "080027C1B7A5"},
+		{Network: NICNetHostonly, Hardware: IntelPro1000MTDesktop, HostInterface: "vboxnet5", MacAddr: "080027EE1DF7"},
+	}}
+	n := 2
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("guestproperty", "enumerate", "go-virtualbox", "--patterns", "/VirtualBox/GuestInfo/Net/*").
+			Return("Name: /VirtualBox/GuestInfo/Net/0/V4/IP, value: 10.0.2.15, timestamp: 1697449466178830000, flags: \n"+
+				"Name: /VirtualBox/GuestInfo/Net/0/MAC, value: 080027C1B7A5, timestamp: 1697449466178830000, flags: \n"+
+				"Name: /VirtualBox/GuestInfo/Net/1/V4/IP, value: 192.168.56.101, timestamp: 1697449466178830000, flags: \n"+
+				"Name: /VirtualBox/GuestInfo/Net/1/MAC, value: 080027ee1df7, timestamp: 1697449466178830000, flags: \n"+
+				"Name: /VirtualBox/GuestInfo/Net/Count, value: 2, timestamp: 1697449466178830000, flags: \n", "", nil).Times(1)
+	} else {
+		m = testMachineWithGuestAdditions(t, "")
+		require.NotEmpty(t, m.NICs, "%s should have a NIC", m.Name)
+		n = 1
+	}
+
+	ip, err := m.ResolveGuestIP(n)
+	if ManageMock == nil && errors.Is(err, ErrGuestIPNotAvailable) {
+		t.Skipf("requires %s to have an IP address on nic%d: %v", m.Name, n, err)
+	}
+	require.NoError(t, err)
+	require.NotNil(t, ip.To4())
+	if ManageMock != nil {
+		require.Equal(t, net.ParseIP("192.168.56.101").To4(), ip)
+	}
+
+	_, err = m.ResolveGuestIP(len(m.NICs) + 1)
+	require.Error(t, err)
+}
+
+func TestResolveGuestIPNotAvailable(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", NICs: []NIC{
+		{Network: NICNetHostonly, Hardware: IntelPro1000MTDesktop, HostInterface: "vboxnet5", MacAddr: "080027EE1DF7"},
+	}}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("guestproperty", "enumerate", "go-virtualbox", "--patterns", "/VirtualBox/GuestInfo/Net/*").
+				Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOut("list", "dhcpservers").Return(ReadTestData("vboxmanage-list-dhcpservers-1.out"), nil).Times(1),
+		)
+	} else {
+		m.Name = VM
+		// a MAC address the guest does not have, so that it cannot have reported an IP address for it
+		m.NICs[0].MacAddr = "0A0027000001"
+	}
+
+	_, err := m.ResolveGuestIP(1)
+	require.ErrorIs(t, err, ErrGuestIPNotAvailable)
+	require.Contains(t, err.Error(), "network=HostInterfaceNetworking-vboxnet5")
+}
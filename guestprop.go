@@ -1,6 +1,8 @@
 package virtualbox
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"regexp"
@@ -12,11 +14,19 @@ import (
 type GuestProperty struct {
 	Name  string
 	Value string
+	// Deleted is set when the property has been deleted instead of changed, its Value is then empty.
+	Deleted bool
 }
 
 var (
 	getRegexp  = regexp.MustCompile("(?m)^Value: ([^,]*)$")
 	waitRegexp = regexp.MustCompile("^Name: ([^,]*), value: ([^,]*), flags:.*$")
+	// e.g. Property test_key was deleted (as of VirtualBox 7.0)
+	waitDeletedRegexp = regexp.MustCompile("^Property (.*) was deleted$")
+	// e.g. Name: /VirtualBox/GuestInfo/OS/Product, value: Linux, timestamp: 1697449466178830000, flags:
+	enumRegexp = regexp.MustCompile("^Name: (.*?), value: (.*), timestamp: .*$")
+	// e.g. /VirtualBox/GuestInfo/OS/Product = 'Linux' @ 2023-10-16T09:44:26.178Z (as of VirtualBox 7.0)
+	enumRegexp70 = regexp.MustCompile("^(\\S+) = '(.*)'( @ .*)?$")
 )
 
 // SetGuestProperty writes a VirtualBox guestproperty to the given value.
@@ -62,31 +72,56 @@ func GetGuestProperty(vm string, prop string) (string, error) {
 // The key to wait for can be a fully defined key or a key wild-card (glob-pattern).
 // The first returned value is the property name that was changed.
 // The second returned value is the new property value,
-// Deletion of the guestproperty causes WaitGuestProperty to return the
-// string.
+// Deletion of the guestproperty causes WaitGuestProperty to return an empty
+// value: use WaitGuestPropertyChange to tell a deletion from an empty value.
 func WaitGuestProperty(vm string, prop string) (string, string, error) {
+	changed, err := WaitGuestPropertyChange(vm, prop)
+	if err != nil {
+		return "", "", err
+	}
+	return changed.Name, changed.Value, nil
+}
+
+// WaitGuestPropertyChange blocks until a VirtualBox guestproperty is changed or deleted,
+// and returns it, with Deleted set in the latter case.
+//
+// The key to wait for can be a fully defined key or a key wild-card (glob-pattern).
+// Deletions are only reported as such as of VirtualBox 7.0, older versions report an empty value.
+func WaitGuestPropertyChange(vm string, prop string) (GuestProperty, error) {
 	var out string
 	var err error
 	Trace("WaitGuestProperty(): wait on '%s'", prop)
 	if Manage().isGuest() {
 		_, err = Manage().setOpts(sudo(true)).runOut("guestproperty", "wait", prop)
 		if err != nil {
-			return "", "", err
+			return GuestProperty{}, err
 		}
 	}
 	out, err = Manage().runOut("guestproperty", "wait", vm, prop)
 	if err != nil {
 		log.Print(err)
-		return "", "", err
+		return GuestProperty{}, err
 	}
+	changed, ok := parseWaitGuestProperty(out)
+	if !ok {
+		return GuestProperty{}, fmt.Errorf("no match with VBoxManage wait guestproperty output")
+	}
+	return changed, nil
+}
+
+// parseWaitGuestProperty parses the output of guestproperty wait, false if it reports no change.
+func parseWaitGuestProperty(out string) (GuestProperty, bool) {
 	out = strings.TrimSpace(out)
 	Trace("WaitGuestProperty(): out (trimmed): %q", out)
+	if match := waitDeletedRegexp.FindStringSubmatch(out); match != nil {
+		return GuestProperty{Name: match[1], Deleted: true}, true
+	}
 	var match = waitRegexp.FindStringSubmatch(out)
 	Debug("WaitGuestProperty(): match:", match)
 	if len(match) != 3 {
-		return "", "", fmt.Errorf("no match with VBoxManage wait guestproperty output")
+		return GuestProperty{}, false
 	}
-	return match[1], match[2], nil
+	return GuestProperty{Name: match[1], Value: match[2]}, true
 }
 
 // WaitGuestProperties wait for changes in GuestProperties
@@ -116,12 +151,11 @@ func WaitGuestProperties(vm string, propPattern string, done chan bool, wg *sync
 
 		for {
 			Trace("WaitGetProperties(): waiting for: '%s' changes", propPattern)
-			name, value, err := WaitGuestProperty(vm, propPattern)
+			prop, err := WaitGuestPropertyChange(vm, propPattern)
 			if err != nil {
 				Debug("WaitGetProperties(): err=%v", err)
 				return
 			}
-			prop := GuestProperty{name, value}
 			select {
 			case props <- prop:
 				Debug("WaitGetProperties(): stacked: %+v", prop)
@@ -135,6 +169,118 @@ func WaitGuestProperties(vm string, propPattern string, done chan bool, wg *sync
 	return props
 }
 
+// GuestPropertyEvent holds either a changed guest property or the error which ended the waiting.
+type GuestPropertyEvent struct {
+	Property GuestProperty
+	Err      error
+}
+
+// WaitGuestPropertyEvents waits for changes in the VirtualBox guestproperties matching the given
+// propPattern (glob-pattern) for the given VM, until ctx is done.
+//
+// Unlike WaitGuestProperties, the error of VBoxManage (e.g. when the VM has been shut down) is not
+// swallowed: it is sent as the last event before the returned channel is closed. The channel is also
+// closed, without error event, when ctx is done.
+//
+// Each event must be read from the channel before the waiting resumes.
+func WaitGuestPropertyEvents(ctx context.Context, vm string, propPattern string) (<-chan GuestPropertyEvent, error) {
+	if propPattern == "" {
+		return nil, errors.New("fail to wait for guestproperties: empty pattern")
+	}
+	events := make(chan GuestPropertyEvent)
+	go func() {
+		defer close(events)
+		for {
+			prop, err := waitGuestProperty(ctx, vm, propPattern)
+			if ctx.Err() != nil {
+				return
+			}
+			select {
+			case events <- GuestPropertyEvent{Property: prop, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// waitGuestProperty blocks until a VirtualBox guestproperty matching the given pattern is changed or ctx is done.
+func waitGuestProperty(ctx context.Context, vm string, propPattern string) (GuestProperty, error) {
+	var out, stderr string
+	var err error
+	if Manage().isGuest() {
+		out, stderr, err = Manage().setOpts(sudo(true), withContext(ctx)).runOutErr("guestproperty", "wait", propPattern)
+	} else {
+		out, stderr, err = Manage().setOpts(withContext(ctx)).runOutErr("guestproperty", "wait", vm, propPattern)
+	}
+	if err != nil {
+		return GuestProperty{}, fmt.Errorf("fail to wait for guestproperties: vm=%s, pattern=%s, stderr=%s, err=%w", vm, propPattern, stderr, err)
+	}
+	changed, ok := parseWaitGuestProperty(out)
+	if !ok {
+		return GuestProperty{}, fmt.Errorf("no match with VBoxManage wait guestproperty output: vm=%s, pattern=%s, out=%s", vm, propPattern, out)
+	}
+	return changed, nil
+}
+
+// EnumerateGuestProperties returns the VirtualBox guestproperties of the given VM whose name matches
+// the given pattern (glob-pattern), all of them if the pattern is empty.
+func EnumerateGuestProperties(vm string, pattern string) ([]GuestProperty, error) {
+	args := []string{"guestproperty", "enumerate", vm}
+	if pattern != "" {
+		args = append(args, "--patterns", pattern)
+	}
+	out, stderr, err := Manage().runOutErr(args...)
+	if err != nil {
+		return nil, fmt.Errorf("fail to enumerate guestproperties: vm=%s, pattern=%s, stderr=%s, err=%w", vm, pattern, stderr, err)
+	}
+	return parseGuestProperties(out), nil
+}
+
+// GetGuestProperties reads the given VirtualBox guestproperties of the given VM at once, with a single
+// enumeration instead of one VBoxManage run per property. Properties which are not set are absent from
+// the returned map.
+func GetGuestProperties(vm string, props []string) (map[string]string, error) {
+	values := make(map[string]string, len(props))
+	if len(props) == 0 {
+		return values, nil
+	}
+	enumerated, err := EnumerateGuestProperties(vm, strings.Join(props, "|"))
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(props))
+	for _, prop := range props {
+		wanted[prop] = true
+	}
+	for _, prop := range enumerated {
+		if wanted[prop.Name] {
+			values[prop.Name] = prop.Value
+		}
+	}
+	return values, nil
+}
+
+func parseGuestProperties(out string) []GuestProperty {
+	props := []GuestProperty{}
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		match := enumRegexp.FindStringSubmatch(line)
+		if match == nil {
+			match = enumRegexp70.FindStringSubmatch(line)
+		}
+		if match == nil {
+			continue
+		}
+		props = append(props, GuestProperty{Name: match[1], Value: match[2]})
+	}
+	return props
+}
+
 // DeleteGuestProperty deletes a VirtualBox guestproperty.
 func DeleteGuestProperty(vm string, prop string) error {
 	if Manage().isGuest() {
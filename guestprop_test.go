@@ -1,6 +1,7 @@
 package virtualbox
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sync"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGuestProperty(t *testing.T) {
@@ -96,6 +98,30 @@ func TestWaitGuestProperty(t *testing.T) {
 	Teardown()
 }
 
+func TestWaitGuestPropertyChangeDeleted(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	key := "test_key"
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().isGuest().Return(false),
+			ManageMock.EXPECT().runOut("guestproperty", "wait", VM, key).
+				Return(ReadTestData("vboxmanage-guestproperty-wait-deleted-1.out"), nil).Times(1),
+		)
+	} else {
+		require.NoError(t, SetGuestProperty(VM, key, "test_val1"))
+		go func() {
+			time.Sleep(1 * time.Second)
+			assert.NoError(t, DeleteGuestProperty(VM, key))
+		}()
+	}
+
+	changed, err := WaitGuestPropertyChange(VM, key)
+	require.NoError(t, err)
+	require.Equal(t, GuestProperty{Name: key, Deleted: true}, changed)
+}
+
 func TestWaitGuestProperties(t *testing.T) {
 	Setup(t)
 
@@ -199,3 +225,84 @@ func TestWaitGuestPropertiesQuit(t *testing.T) {
 
 	Teardown()
 }
+
+func TestWaitGuestPropertyEvents(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	vals := []string{"test_val1", "test_val2"}
+	if ManageMock != nil {
+		waitCall := func(out, stderr string, err error) *gomock.Call {
+			return ManageMock.EXPECT().runOutErr("guestproperty", "wait", VM, "test_*").Return(out, stderr, err).Times(1)
+		}
+		ManageMock.EXPECT().isGuest().Return(false).AnyTimes()
+		ManageMock.EXPECT().setOpts(gomock.Any()).Return(ManageMock).AnyTimes()
+		gomock.InOrder(
+			waitCall(ReadTestData("vboxmanage-guestproperty-wait-1.out"), "", nil),
+			waitCall("Name: test_key, value: test_val2, flags: \n", "", nil),
+			waitCall("", "VBoxManage: error: The machine 'go-virtualbox' is not running", errors.New("exit status 1")),
+		)
+	} else {
+		testMachine(t, "", Running)
+		go func() {
+			for _, val := range vals {
+				time.Sleep(time.Second)
+				assert.NoErrorf(t, SetGuestProperty(VM, "test_key", val), ">>> key='test_key', val='%s'", val)
+			}
+		}()
+	}
+
+	events, err := WaitGuestPropertyEvents(ctx, VM, "test_*")
+	require.NoError(t, err)
+	for _, val := range vals {
+		event := <-events
+		require.NoError(t, event.Err)
+		require.Equal(t, GuestProperty{Name: "test_key", Value: val}, event.Property)
+	}
+	if ManageMock != nil {
+		event, ok := <-events
+		require.True(t, ok, "the error should be sent before the channel is closed")
+		require.Error(t, event.Err)
+		require.Contains(t, event.Err.Error(), "is not running")
+	} else {
+		cancel()
+	}
+	_, ok := <-events
+	require.False(t, ok, "the channel should be closed")
+}
+
+func TestParseGuestProperties(t *testing.T) {
+	out := "Name: /VirtualBox/GuestInfo/OS/Product, value: Linux, timestamp: 1697449466178830000, flags: \n" +
+		"/VirtualBox/GuestInfo/OS/Release = '5.15.0-86-generic' @ 2023-10-16T09:44:26.178Z\n"
+	assert.Equal(t,
+		[]GuestProperty{
+			{Name: "/VirtualBox/GuestInfo/OS/Product", Value: "Linux"},
+			{Name: "/VirtualBox/GuestInfo/OS/Release", Value: "5.15.0-86-generic"},
+		},
+		parseGuestProperties(out))
+}
+
+func TestGetGuestProperties(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	props := []string{"/VirtualBox/GuestInfo/Net/0/V4/IP", "/VirtualBox/GuestInfo/Net/1/V4/IP", "/VirtualBox/GuestInfo/OS/Product"}
+	expected := map[string]string{"/VirtualBox/GuestInfo/Net/0/V4/IP": "10.0.2.15", "/VirtualBox/GuestInfo/OS/Product": "Linux"}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("guestproperty", "enumerate", VM, "--patterns",
+			"/VirtualBox/GuestInfo/Net/0/V4/IP|/VirtualBox/GuestInfo/Net/1/V4/IP|/VirtualBox/GuestInfo/OS/Product").
+			Return("Name: /VirtualBox/GuestInfo/Net/0/V4/IP, value: 10.0.2.15, timestamp: 1697449466178830000, flags: \n"+
+				"Name: /VirtualBox/GuestInfo/OS/Product, value: Linux, timestamp: 1697449466178830000, flags: \n", "", nil).Times(1)
+	} else {
+		require.NoError(t, SetGuestProperty(VM, "test_key", "test_val"))
+		t.Cleanup(func() { assert.NoError(t, DeleteGuestProperty(VM, "test_key")) })
+		props = []string{"test_key", "test_missing"}
+		expected = map[string]string{"test_key": "test_val"}
+	}
+
+	values, err := GetGuestProperties(VM, props)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, values)
+}
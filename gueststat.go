@@ -0,0 +1,126 @@
+package virtualbox
+
+import (
+	"errors"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// Guest file types of GuestFileInfo.Type.
+const (
+	GuestFileTypeFile      = "file"
+	GuestFileTypeDirectory = "directory"
+	GuestFileTypeSymlink   = "symlink"
+)
+
+// ErrGuestPathNotFound is returned when a guest path does not exist.
+var ErrGuestPathNotFound = errors.New("guest path not found")
+
+var (
+	// matches e.g. Element "/etc/hosts" found: Is a file (VirtualBox 6.1)
+	reGuestStatElement = regexp.MustCompile(`(?m)^Element "(.*)" found: Is a (\w+)`)
+	// matches e.g.   File: '/etc/hosts' (as of VirtualBox 7.0)
+	reGuestStatFile = regexp.MustCompile(`(?m)^\s*File: '(.*)'`)
+	// matches e.g.   Size: 221  Alloc: 4096  Type: file
+	reGuestStatSize = regexp.MustCompile(`(?m)^\s*Size: ([\d',]+)`)
+	reGuestStatType = regexp.MustCompile(`(?m)Type: (\S+)`)
+	// matches e.g.   Mode: -rw-r--r--  Attrs: ...
+	reGuestStatMode = regexp.MustCompile(`(?m)^\s*Mode: ([-dlcbpsrwxtST]{10})`)
+	// matches the messages of the different guest additions versions for a missing path
+	reGuestPathNotFound = regexp.MustCompile(`(?i)no such (file|element|directory)|VERR_(FILE|PATH)_NOT_FOUND`)
+)
+
+// GuestFileInfo holds the information about a guest file, as given by <VBoxManage guestcontrol stat>.
+type GuestFileInfo struct {
+	Path string
+	Type string      // file|directory|symlink, or the type as reported for other ones
+	Size int64       // 0 when not reported (VirtualBox 6.1)
+	Mode os.FileMode // type and permission bits, 0 when not reported (VirtualBox 6.1)
+}
+
+// IsDir returns true if the guest file is a directory.
+func (info GuestFileInfo) IsDir() bool {
+	return info.Type == GuestFileTypeDirectory
+}
+
+// GuestStat returns the information about the given guest path, e.g. to check whether a file already exists
+// in the guest before copying it. ErrGuestPathNotFound is returned if the path does not exist.
+func (m *Machine) GuestStat(path string, cred GuestCredentials) (*GuestFileInfo, error) {
+	var info *GuestFileInfo
+	err := cred.withPasswordFile(func(passwordFile string) error {
+		args := []string{"guestcontrol", m.Name, "stat"}
+		args = append(args, cred.cmdArgs(passwordFile)...)
+		args = append(args, path)
+		stdout, stderr, err := Manage().runOutErr(args...)
+		if err != nil {
+			if reGuestPathNotFound.MatchString(stdout + stderr) {
+				return pkgerrors.Wrapf(ErrGuestPathNotFound, "vm=%s, path=%s", m.Name, path)
+			}
+			return pkgerrors.Wrapf(err, "fail to stat guest path: vm=%s, path=%s, stdout=%s, stderr=%s",
+				m.Name, path, stdout, stderr)
+		}
+		info, err = parseGuestStat(path, stdout)
+		return err
+	})
+	return info, err
+}
+
+// parseGuestStat parses the output of <VBoxManage guestcontrol stat> for the given path.
+func parseGuestStat(path, out string) (*GuestFileInfo, error) {
+	if res := reGuestStatElement.FindStringSubmatch(out); res != nil {
+		return &GuestFileInfo{Path: res[1], Type: guestFileType(res[2])}, nil
+	}
+	res := reGuestStatFile.FindStringSubmatch(out)
+	if res == nil {
+		return nil, pkgerrors.Errorf("no match with guestcontrol stat output: path=%s, out=%s", path, out)
+	}
+	info := &GuestFileInfo{Path: res[1]}
+	if res := reGuestStatType.FindStringSubmatch(out); res != nil {
+		info.Type = guestFileType(res[1])
+	}
+	if res := reGuestStatSize.FindStringSubmatch(out); res != nil {
+		size, err := strconv.ParseInt(strings.NewReplacer("'", "", ",", "").Replace(res[1]), 10, 64)
+		if err != nil {
+			return nil, pkgerrors.Wrapf(err, "bad guest file size: path=%s, size=%s", path, res[1])
+		}
+		info.Size = size
+	}
+	if res := reGuestStatMode.FindStringSubmatch(out); res != nil {
+		info.Mode = parseGuestFileMode(res[1])
+	}
+	return info, nil
+}
+
+// guestFileType normalizes the file types reported by the different guest additions versions.
+func guestFileType(t string) string {
+	switch t = strings.ToLower(t); t {
+	case "dir", "directory":
+		return GuestFileTypeDirectory
+	case "link", "symlink":
+		return GuestFileTypeSymlink
+	case "file", "regular":
+		return GuestFileTypeFile
+	}
+	return t
+}
+
+// parseGuestFileMode converts a mode such as -rwxr-xr-x or drwxr-xr-x into an os.FileMode.
+func parseGuestFileMode(mode string) os.FileMode {
+	var m os.FileMode
+	switch mode[0] {
+	case 'd':
+		m |= os.ModeDir
+	case 'l':
+		m |= os.ModeSymlink
+	}
+	for i, c := range mode[1:] {
+		if c != '-' {
+			m |= 1 << uint(8-i)
+		}
+	}
+	return m
+}
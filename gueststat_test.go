@@ -0,0 +1,57 @@
+package virtualbox
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestStat(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	cred := GuestCredentials{Username: "vagrant", PasswordFile: "/etc/vbox/pw"}
+	dir := "/opt/provision"
+	if ManageMock != nil {
+		statArgs := func(path string) []interface{} {
+			return []interface{}{"guestcontrol", "go-virtualbox", "stat", "--username", "vagrant", "--passwordfile", "/etc/vbox/pw", path}
+		}
+		ManageMock.EXPECT().runOutErr(statArgs("/opt/provision/setup.sh")...).
+			Return(ReadTestData("vboxmanage-guestcontrol-stat-1.out"), "", nil).Times(1)
+		ManageMock.EXPECT().runOutErr(statArgs("/opt/provision")...).
+			Return(`Element "/opt/provision" found: Is a directory`+"\n", "", nil).Times(1)
+		ManageMock.EXPECT().runOutErr(statArgs("/opt/provision/missing")...).
+			Return("", "VBoxManage: error: File '/opt/provision/missing' not found: VERR_FILE_NOT_FOUND", errors.New("exit status 1")).Times(1)
+	} else {
+		m = testMachine(t, "", Running)
+		cred = testGuestCredentials(t)
+		dir = guestTempDir(t, cred)
+		guestShell(t, cred, "head -c 1536 /dev/zero >"+dir+"/setup.sh && chmod 755 "+dir+"/setup.sh")
+	}
+
+	info, err := m.GuestStat(dir+"/setup.sh", cred)
+	require.NoError(t, err)
+	require.Equal(t, dir+"/setup.sh", info.Path)
+	require.Equal(t, GuestFileTypeFile, info.Type)
+	require.False(t, info.IsDir())
+	if info.Mode != 0 {
+		// not reported by VirtualBox 6.1
+		require.Equal(t, &GuestFileInfo{Path: dir + "/setup.sh", Type: GuestFileTypeFile, Size: 1536, Mode: 0755}, info)
+	}
+
+	info, err = m.GuestStat(dir, cred)
+	require.NoError(t, err)
+	require.True(t, info.IsDir())
+
+	_, err = m.GuestStat(dir+"/missing", cred)
+	require.ErrorIs(t, err, ErrGuestPathNotFound)
+}
+
+func TestParseGuestFileMode(t *testing.T) {
+	require.Equal(t, os.ModeDir|0750, parseGuestFileMode("drwxr-x---"))
+	require.Equal(t, os.ModeSymlink|0777, parseGuestFileMode("lrwxrwxrwx"))
+	require.Equal(t, os.FileMode(0644), parseGuestFileMode("-rw-r--r--"))
+}
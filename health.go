@@ -0,0 +1,98 @@
+package virtualbox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// healthLogTailSize is the size of the end of VBox.log scanned by IsHealthy.
+	healthLogTailSize = 64 * 1024
+	// heartbeatGuestProp is rewritten by the VBoxService of the guest additions at each of its
+	// information update intervals (10s by default), even when its value does not change.
+	heartbeatGuestProp = "/VirtualBox/GuestInfo/OS/LoggedInUsers"
+)
+
+var (
+	// matches e.g. !!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!!
+	//              !!         VCPU0: Guru Meditation -2701 (VERR_VMM_RING0_ASSERTION)
+	reFatalLogLine = regexp.MustCompile(`(?i)guru meditation|!!assertion failed|fatal error`)
+)
+
+// IsHealthy refreshes the machine and tells whether it is healthy, with the reason when it is not:
+// the machine is not healthy when aborted, in guru meditation, or when the end of its current VBox.log
+// reports a fatal error.
+func (m *Machine) IsHealthy() (bool, string, error) {
+	if err := m.Refresh(); err != nil {
+		return false, "", err
+	}
+	switch m.State {
+	case Aborted, GuruMeditation:
+		return false, "machine state is " + string(m.State), nil
+	}
+	line, err := m.fatalLogLine()
+	if err != nil {
+		return false, "", err
+	}
+	if line != "" {
+		return false, "fatal error in log: " + line, nil
+	}
+	return true, "", nil
+}
+
+// fatalLogLine returns the first fatal error line at the end of the current VBox.log, if any.
+// A missing log is not an error, e.g. for a machine which has never been started.
+func (m *Machine) fatalLogLine() (string, error) {
+	f, err := os.Open(m.logFile(0))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", errors.Wrapf(err, "fail to open log: vm=%s", m.Name)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil && info.Size() > healthLogTailSize {
+		if _, err := f.Seek(-healthLogTailSize, io.SeekEnd); err != nil {
+			return "", errors.Wrapf(err, "fail to read log: vm=%s", m.Name)
+		}
+	}
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if reFatalLogLine.MatchString(s.Text()) {
+			return s.Text(), nil
+		}
+	}
+	return "", s.Err()
+}
+
+// Heartbeat tells whether the guest additions service of the running machine is responsive, i.e. whether
+// it updates its guest properties within the given timeout, which should be longer than the update interval
+// of the guest additions (10s by default). This distinguishes a hung guest from a healthy one, whereas
+// the machine state only tells that the VM is powered on.
+//
+// ErrMachineNotRunning is returned when the machine is not running and ErrGuestAdditionsNotAvailable
+// when the guest additions have never come up.
+func (m *Machine) Heartbeat(timeout time.Duration) (bool, error) {
+	if err := m.Refresh(); err != nil {
+		return false, err
+	}
+	if m.State != Running {
+		return false, errors.Wrapf(ErrMachineNotRunning, "fail to check heartbeat: vm=%s, state=%s", m.Name, m.State)
+	}
+	if m.GuestAdditionsRunLevel == 0 {
+		return false, errors.Wrapf(ErrGuestAdditionsNotAvailable, "fail to check heartbeat: vm=%s", m.Name)
+	}
+	out, stderr, err := Manage().runOutErr("guestproperty", "wait", m.Name, heartbeatGuestProp,
+		"--timeout", fmt.Sprintf("%d", timeout.Milliseconds()))
+	if err != nil {
+		return false, errors.Wrapf(err, "fail to check heartbeat: vm=%s, stderr=%s", m.Name, stderr)
+	}
+	// on timeout, VBoxManage only prints a notice and succeeds
+	return waitRegexp.MatchString(strings.TrimSpace(out)), nil
+}
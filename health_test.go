@@ -0,0 +1,107 @@
+package virtualbox
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsHealthy(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	// BaseFolder is derived from CfgFile on refresh
+	baseFolder := t.TempDir()
+	vmInfo := func(state MachineState) string {
+		return strings.Replace(vmInfoWithState(state, "0"),
+			`CfgFile="/Users/fix/VirtualBox VMs/go-virtualbox/go-virtualbox.vbox"`,
+			`CfgFile="`+filepath.Join(baseFolder, "go-virtualbox.vbox")+`"`, 1)
+	}
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		expectShowVMInfo("go-virtualbox", vmInfo(Running))
+	} else {
+		m.Name = VM
+	}
+
+	healthy, reason, err := m.IsHealthy()
+	require.NoError(t, err)
+	require.Equalf(t, healthy, reason == "", "reason should be given only when not healthy: %s", reason)
+	if m.State == Aborted || m.State == GuruMeditation {
+		require.Equal(t, "machine state is "+string(m.State), reason)
+	}
+	if ManageMock == nil {
+		return
+	}
+	require.Truef(t, healthy, "running VM without log should be healthy: %s", reason)
+
+	expectShowVMInfo("go-virtualbox", vmInfo(GuruMeditation))
+	healthy, reason, err = m.IsHealthy()
+	require.NoError(t, err)
+	require.False(t, healthy)
+	require.Equal(t, "machine state is gurumeditation", reason)
+
+	require.NoError(t, os.MkdirAll(m.LogPath(), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(m.LogPath(), "VBox.log"),
+		[]byte("00:00:01.000 VMM: started\n00:01:02.345 !!         VCPU0: Guru Meditation -2701 (VERR_VMM_RING0_ASSERTION)\n"), 0o644))
+	expectShowVMInfo("go-virtualbox", vmInfo(Running))
+	healthy, reason, err = m.IsHealthy()
+	require.NoError(t, err)
+	require.False(t, healthy)
+	require.Contains(t, reason, "VCPU0: Guru Meditation -2701")
+}
+
+func TestHeartbeat(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock == nil {
+		m.Name = VM
+		alive, err := m.Heartbeat(30 * time.Second)
+		switch {
+		case m.State != Running:
+			require.ErrorIs(t, err, ErrMachineNotRunning)
+		case m.GuestAdditionsRunLevel == 0:
+			require.ErrorIs(t, err, ErrGuestAdditionsNotAvailable)
+		default:
+			require.NoError(t, err)
+			t.Logf("%s alive=%t", m.Name, alive)
+		}
+		return
+	}
+
+	waitCall := func(out string) *gomock.Call {
+		return ManageMock.EXPECT().runOutErr("guestproperty", "wait", "go-virtualbox",
+			"/VirtualBox/GuestInfo/OS/LoggedInUsers", "--timeout", "30000").Return(out, "", nil).Times(1)
+	}
+
+	gomock.InOrder(
+		expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "2")),
+		waitCall("Name: /VirtualBox/GuestInfo/OS/LoggedInUsers, value: 0, flags: TRANSIENT, TRANSRESET\n"),
+	)
+	alive, err := m.Heartbeat(30 * time.Second)
+	require.NoError(t, err)
+	require.True(t, alive)
+
+	gomock.InOrder(
+		expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "2")),
+		waitCall("Time out or interruption while waiting for a notification.\n"),
+	)
+	alive, err = m.Heartbeat(30 * time.Second)
+	require.NoError(t, err)
+	require.False(t, alive, "hung guest should not be alive")
+
+	expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "0"))
+	_, err = m.Heartbeat(30 * time.Second)
+	require.ErrorIs(t, err, ErrGuestAdditionsNotAvailable)
+
+	expectShowVMInfo("go-virtualbox", vmInfoWithState(Poweroff, "0"))
+	_, err = m.Heartbeat(30 * time.Second)
+	require.ErrorIs(t, err, ErrMachineNotRunning)
+}
@@ -26,8 +26,8 @@ type HostonlyNet struct {
 	Name        string
 	GUID        string
 	DHCP        bool
-	IPv4        net.IPNet
-	IPv6        net.IPNet
+	IPv4        CIDR
+	IPv6        CIDR
 	HwAddr      net.HardwareAddr
 	Medium      string
 	Status      string
@@ -108,7 +108,9 @@ func HostonlyNets() (map[string]*HostonlyNet, error) {
 	for s.Scan() {
 		line := s.Text()
 		if line == "" {
-			m[n.NetworkName] = n
+			if n.Name != "" {
+				m[n.NetworkName] = n
+			}
 			n = &HostonlyNet{}
 			continue
 		}
@@ -152,5 +154,9 @@ func HostonlyNets() (map[string]*HostonlyNet, error) {
 	if err := s.Err(); err != nil {
 		return nil, err
 	}
+	if n.Name != "" {
+		// last entry not followed by an empty line
+		m[n.NetworkName] = n
+	}
 	return m, nil
 }
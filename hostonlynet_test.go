@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHostonlyNets(t *testing.T) {
@@ -22,6 +23,9 @@ func TestHostonlyNets(t *testing.T) {
 	for _, n := range m {
 		t.Logf("%+v", n)
 	}
+	if ManageMock != nil {
+		require.Contains(t, m, "HostInterfaceNetworking-vboxnet0", "last listed interface should be kept")
+	}
 
 	Teardown()
 }
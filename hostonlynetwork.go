@@ -0,0 +1,125 @@
+package virtualbox
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrHostonlyNetworkNotSupported holds the error message when host-only networks are used with
+// a VirtualBox version older than 7.0.
+var ErrHostonlyNetworkNotSupported = errors.New("host-only networks require VirtualBox 7.0 or later")
+
+// HostonlyNetwork is a host-only network of VirtualBox 7+ (<VBoxManage hostonlynet>), which unlike
+// the host-only interfaces of HostonlyNet is not bound to a host network adapter and has its own DHCP range.
+// It is the recommended host-only networking on macOS (Apple silicon) and modern VirtualBox.
+type HostonlyNetwork struct {
+	Name        string
+	GUID        string
+	Enabled     bool
+	NetworkMask net.IPMask
+	LowerIP     net.IP
+	UpperIP     net.IP
+	NetworkName string // VirtualBox network name, e.g. hostonly-HostNet
+}
+
+// requireHostonlyNetworks returns ErrHostonlyNetworkNotSupported if the VirtualBox version is older than 7.0.
+func requireHostonlyNetworks() error {
+	v, err := VersionInfo()
+	if err != nil {
+		return err
+	}
+	if !v.AtLeast(7, 0, 0) {
+		return pkgerrors.Wrapf(ErrHostonlyNetworkNotSupported, "version=%s", v)
+	}
+	return nil
+}
+
+// HostonlyNetworks returns the host-only networks of VirtualBox 7+.
+func HostonlyNetworks() ([]HostonlyNetwork, error) {
+	if err := requireHostonlyNetworks(); err != nil {
+		return nil, err
+	}
+	stdout, stderr, err := Manage().runOutErr("list", "hostonlynets")
+	if err != nil {
+		return nil, pkgerrors.Wrapf(err, "fail to list host-only networks: stderr=%s", stderr)
+	}
+	return parseHostonlyNetworks(stdout), nil
+}
+
+func parseHostonlyNetworks(out string) []HostonlyNetwork {
+	nets := []HostonlyNetwork{}
+	var n *HostonlyNetwork
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		res := reColonLine.FindStringSubmatch(s.Text())
+		if res == nil {
+			continue
+		}
+		switch key, val := res[1], res[2]; key {
+		case "Name":
+			nets = append(nets, HostonlyNetwork{Name: val})
+			n = &nets[len(nets)-1]
+		case "GUID":
+			n.GUID = val
+		case "State":
+			n.Enabled = val == "Enabled"
+		case "NetworkMask":
+			n.NetworkMask = ParseIPv4Mask(val)
+		case "LowerIP":
+			n.LowerIP = net.ParseIP(val).To4()
+		case "UpperIP":
+			n.UpperIP = net.ParseIP(val).To4()
+		case "VBoxNetworkName":
+			n.NetworkName = val
+		}
+	}
+	return nets
+}
+
+// cmdArgs returns the hostonlynet add|modify args applying the settings of the network.
+func (n HostonlyNetwork) cmdArgs() []string {
+	args := []string{"--name", n.Name}
+	if n.NetworkMask != nil {
+		args = append(args, "--netmask", net.IP(n.NetworkMask).String())
+	}
+	if n.LowerIP != nil {
+		args = append(args, "--lower-ip", n.LowerIP.String())
+	}
+	if n.UpperIP != nil {
+		args = append(args, "--upper-ip", n.UpperIP.String())
+	}
+	if n.Enabled {
+		return append(args, "--enable")
+	}
+	return append(args, "--disable")
+}
+
+// CreateHostonlyNetwork creates the given host-only network of VirtualBox 7+.
+func CreateHostonlyNetwork(n HostonlyNetwork) error {
+	return hostonlynet("add", n.Name, n.cmdArgs()...)
+}
+
+// Modify changes the settings of the host-only network of VirtualBox 7+ with the given name.
+func (n HostonlyNetwork) Modify() error {
+	return hostonlynet("modify", n.Name, n.cmdArgs()...)
+}
+
+// RemoveHostonlyNetwork removes the host-only network of VirtualBox 7+ with the given name.
+func RemoveHostonlyNetwork(name string) error {
+	return hostonlynet("remove", name, "--name", name)
+}
+
+func hostonlynet(op string, name string, args ...string) error {
+	if err := requireHostonlyNetworks(); err != nil {
+		return err
+	}
+	stdout, stderr, err := Manage().runOutErr(append([]string{"hostonlynet", op}, args...)...)
+	if err != nil {
+		return pkgerrors.Wrapf(err, "fail to %s host-only network: name=%s, stdout=%s, stderr=%s", op, name, stdout, stderr)
+	}
+	return nil
+}
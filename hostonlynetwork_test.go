@@ -0,0 +1,98 @@
+package virtualbox
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
+)
+
+// skipWithoutHostonlyNetworks skips the test when the real VirtualBox has no host-only networks.
+func skipWithoutHostonlyNetworks(t *testing.T) {
+	t.Helper()
+	err := requireHostonlyNetworks()
+	if errors.Is(err, ErrHostonlyNetworkNotSupported) {
+		t.Skip(err)
+	}
+	require.NoError(t, err)
+}
+
+func TestHostonlyNetworks(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("--version").Return("7.0.12r159484\n", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("list", "hostonlynets").
+				Return(ReadTestData("vboxmanage-list-hostonlynets-1.out"), "", nil).Times(1),
+		)
+	} else {
+		skipWithoutHostonlyNetworks(t)
+	}
+	nets, err := HostonlyNetworks()
+	require.NoError(t, err)
+	for _, n := range nets {
+		require.NotEmpty(t, n.Name)
+		require.Equal(t, "hostonly-"+n.Name, n.NetworkName)
+	}
+	if ManageMock != nil {
+		require.Equal(t, []HostonlyNetwork{
+			{
+				Name: "HostNet", GUID: "0b5c2e1a-8f3d-4c6e-9a7b-2d4f6e8a0c1e", Enabled: true,
+				NetworkMask: net.CIDRMask(24, 32), LowerIP: net.IPv4(192, 168, 60, 2).To4(), UpperIP: net.IPv4(192, 168, 60, 199).To4(),
+				NetworkName: "hostonly-HostNet",
+			},
+			{
+				Name: "Lab", GUID: "5e7a9c1b-3d5f-4b7d-8e9f-1a3c5e7b9d2f",
+				NetworkMask: net.CIDRMask(16, 32), LowerIP: net.IPv4(10, 10, 0, 2).To4(), UpperIP: net.IPv4(10, 10, 255, 254).To4(),
+				NetworkName: "hostonly-Lab",
+			},
+		}, nets)
+	}
+}
+
+func TestCreateHostonlyNetwork(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	n := HostonlyNetwork{Name: "HostNet", Enabled: true, NetworkMask: net.CIDRMask(24, 32),
+		LowerIP: net.IPv4(192, 168, 60, 2), UpperIP: net.IPv4(192, 168, 60, 199)}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("--version").Return("7.0.12r159484\n", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("hostonlynet", "add", "--name", "HostNet", "--netmask", "255.255.255.0",
+				"--lower-ip", "192.168.60.2", "--upper-ip", "192.168.60.199", "--enable").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("--version").Return("7.0.12r159484\n", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("hostonlynet", "remove", "--name", "HostNet").Return("", "", nil).Times(1),
+		)
+	} else {
+		skipWithoutHostonlyNetworks(t)
+		n.Name = "go-virtualbox-test"
+		// in case the test fails before removing it
+		t.Cleanup(func() { _ = RemoveHostonlyNetwork(n.Name) })
+	}
+
+	require.NoError(t, CreateHostonlyNetwork(n))
+	if ManageMock == nil {
+		nets, err := HostonlyNetworks()
+		require.NoError(t, err)
+		i := slices.IndexFunc(nets, func(created HostonlyNetwork) bool { return created.Name == n.Name })
+		require.NotEqualf(t, -1, i, "%s should be listed in %+v", n.Name, nets)
+		nets[i].GUID = ""
+		require.Equal(t, HostonlyNetwork{
+			Name: n.Name, Enabled: true, NetworkMask: n.NetworkMask,
+			LowerIP: n.LowerIP.To4(), UpperIP: n.UpperIP.To4(), NetworkName: "hostonly-" + n.Name,
+		}, nets[i])
+	}
+	require.NoError(t, RemoveHostonlyNetwork(n.Name))
+
+	if ManageMock != nil {
+		// VirtualBox 6.1 only has host-only interfaces
+		ManageMock.EXPECT().runOutErr("--version").Return("6.1.34r150636\n", "", nil).Times(1)
+		require.ErrorIs(t, n.Modify(), ErrHostonlyNetworkNotSupported)
+	}
+}
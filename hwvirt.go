@@ -0,0 +1,80 @@
+package virtualbox
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+var (
+	// ErrHardwareVirtUnavailable holds the error message when hardware virtualization (VT-x/AMD-V)
+	// is not available, e.g. disabled in the host BIOS or taken by Hyper-V or KVM.
+	ErrHardwareVirtUnavailable = errors.New("hardware virtualization not available")
+
+	// matches the VirtualBox errors reported when VT-x/AMD-V is not available, e.g.
+	// VT-x is disabled in the BIOS for all CPU modes (VERR_VMX_MSR_ALL_VMX_DISABLED)
+	reHardwareVirtUnavailable = regexp.MustCompile(
+		`VERR_(VMX_NO_VMX|VMX_MSR_ALL_VMX_DISABLED|VMX_MSR_VMX_DISABLED|VMX_MSR_LOCKING_FAILED|` +
+			`VMX_IN_VMX_ROOT_MODE|SVM_NO_SVM|SVM_DISABLED|SVM_IN_USE|NEM_NOT_AVAILABLE)\b`)
+)
+
+// hardwareVirtUnavailableError is returned when a machine cannot start because VT-x/AMD-V is not available.
+// It matches ErrHardwareVirtUnavailable with errors.Is and unwraps to the error of the start command,
+// e.g. an *exec.ExitError.
+type hardwareVirtUnavailableError struct {
+	vm  string
+	err error
+}
+
+func (e *hardwareVirtUnavailableError) Error() string {
+	return fmt.Sprintf("fail to start vm=%s: %v: %v", e.vm, ErrHardwareVirtUnavailable, e.err)
+}
+
+// Is reports whether target is ErrHardwareVirtUnavailable.
+func (e *hardwareVirtUnavailableError) Is(target error) bool {
+	return target == ErrHardwareVirtUnavailable
+}
+
+// Unwrap returns the error of the start command.
+func (e *hardwareVirtUnavailableError) Unwrap() error {
+	return e.err
+}
+
+// hardwareVirtError returns an error matching ErrHardwareVirtUnavailable if the given error
+// is due to VT-x/AMD-V not being available, otherwise err itself.
+func hardwareVirtError(err error, vm string) error {
+	if err == nil || !reHardwareVirtUnavailable.MatchString(err.Error()) {
+		return err
+	}
+	return &hardwareVirtUnavailableError{vm: vm, err: err}
+}
+
+// CheckVirtualizationAvailable checks that the host processor supports hardware virtualization
+// (VT-x/AMD-V) and that it is usable by VirtualBox, as a pre-flight to starting machines.
+// ErrHardwareVirtUnavailable is returned otherwise: check that it is enabled in the BIOS and, on Windows,
+// that Hyper-V (also used by WSL2) does not hold it.
+func CheckVirtualizationAvailable() error {
+	stdout, stderr, err := Manage().runOutErr("list", "hostinfo")
+	if err != nil {
+		return pkgerrors.Wrapf(err, "fail to get host info: stderr=%s", stderr)
+	}
+	s := bufio.NewScanner(strings.NewReader(stdout))
+	for s.Scan() {
+		res := reColonLine.FindStringSubmatch(s.Text())
+		if res == nil || res[1] != "Processor supports HW virtualization" {
+			continue
+		}
+		if strings.TrimSpace(res[2]) != "yes" {
+			return pkgerrors.Wrap(ErrHardwareVirtUnavailable, "the host processor does not support hardware virtualization or it is disabled")
+		}
+		return nil
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+	return pkgerrors.Errorf("no hardware virtualization support found in host info: %s", stdout)
+}
@@ -0,0 +1,95 @@
+package virtualbox
+
+import (
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testMachineWithoutHardwareVirt returns the powered off real machine when the host has no usable
+// hardware virtualization, skipping the test otherwise.
+func testMachineWithoutHardwareVirt(t *testing.T) *Machine {
+	t.Helper()
+	m := testMachine(t, "", Poweroff, Aborted)
+	if err := CheckVirtualizationAvailable(); err == nil {
+		t.Skip("requires a host without hardware virtualization")
+	} else {
+		require.ErrorIs(t, err, ErrHardwareVirtUnavailable)
+	}
+	return m
+}
+
+func TestStartHardwareVirtUnavailable(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", State: Poweroff}
+	if ManageMock != nil {
+		ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(errors.New(
+			"VBoxManage: error: VT-x is not available (VERR_VMX_NO_VMX)\nVBoxManage: error: Details: code E_FAIL")).Times(1)
+	} else {
+		m = testMachineWithoutHardwareVirt(t)
+	}
+
+	err := m.Start()
+	require.ErrorIs(t, err, ErrHardwareVirtUnavailable)
+	if ManageMock != nil {
+		require.Contains(t, err.Error(), "VERR_VMX_NO_VMX")
+	}
+}
+
+func TestCheckVirtualizationAvailable(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	hostInfo := ReadTestData("vboxmanage-list-hostinfo-1.out")
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("list", "hostinfo").Return(hostInfo, "", nil).Times(1)
+	}
+	err := CheckVirtualizationAvailable()
+	if ManageMock == nil {
+		if err != nil {
+			require.ErrorIs(t, err, ErrHardwareVirtUnavailable)
+		}
+		return
+	}
+	require.NoError(t, err)
+
+	hostInfo = strings.Replace(hostInfo, "Processor supports HW virtualization: yes", "Processor supports HW virtualization: no", 1)
+	ManageMock.EXPECT().runOutErr("list", "hostinfo").Return(hostInfo, "", nil).Times(1)
+	require.ErrorIs(t, CheckVirtualizationAvailable(), ErrHardwareVirtUnavailable)
+}
+
+func TestStartAndWaitBootedHardwareVirtUnavailable(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", State: Poweroff}
+	if ManageMock != nil {
+		if runtime.GOOS == osWindows {
+			t.Skip("requires a posix shell")
+		}
+		// the error of a failed VBoxManage, as returned by command.run
+		startErr := command{program: "sh"}.run("-c",
+			"echo 'VBoxManage: error: VT-x is not available (VERR_VMX_NO_VMX)' >&2; exit 1")
+		ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(startErr).Times(1)
+	} else {
+		m = testMachineWithoutHardwareVirt(t)
+	}
+
+	err := m.StartAndWaitBooted(time.Minute)
+	require.ErrorIs(t, err, ErrStartFailed)
+	require.ErrorIs(t, err, ErrHardwareVirtUnavailable)
+	var exitErr *exec.ExitError
+	require.Truef(t, errors.As(err, &exitErr) && exitErr.ExitCode() != 0,
+		"exit error should be reachable from the error: %v", err)
+	if ManageMock != nil {
+		require.Equal(t, 1, exitErr.ExitCode())
+		require.Contains(t, err.Error(), "VERR_VMX_NO_VMX")
+	}
+}
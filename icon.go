@@ -0,0 +1,41 @@
+package virtualbox
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// SetIcon sets the icon displayed for the machine by the VirtualBox GUI from the given PNG file.
+//
+// VirtualBox stores the icon itself in the machine settings, not the file path, so the icon
+// can not be read back from the VM info.
+func (m *Machine) SetIcon(pngPath string) error {
+	if err := checkPNG(pngPath); err != nil {
+		return errors.Wrapf(err, "fail to set icon: vm=%s", m.Name)
+	}
+	stdout, stderr, err := Manage().runOutErr("modifyvm", m.Name, "--iconfile", pngPath)
+	if err != nil {
+		return errors.Wrapf(err, "fail to set icon: vm=%s, icon=%s, stdout=%s, stderr=%s",
+			m.Name, pngPath, stdout, stderr)
+	}
+	return nil
+}
+
+// checkPNG returns an error if the given file does not exist or is not a PNG image.
+func checkPNG(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(f, header); err != nil || !bytes.Equal(header, pngSignature) {
+		return errors.Errorf("not a PNG file: %s", path)
+	}
+	return nil
+}
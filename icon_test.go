@@ -0,0 +1,32 @@
+package virtualbox
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetIcon(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	dir := t.TempDir()
+	png := filepath.Join(dir, "role.png")
+	require.NoError(t, os.WriteFile(png, append(pngSignature, 0, 0, 0, 13), 0o644))
+	notPNG := filepath.Join(dir, "role.txt")
+	require.NoError(t, os.WriteFile(notPNG, []byte("not an image"), 0o644))
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--iconfile", png).Return("", "", nil).Times(1)
+	} else {
+		// VBoxManage cannot reset the icon, it is left set on the real machine
+		m = testMachine(t, "", Poweroff, Aborted)
+	}
+
+	require.NoError(t, m.SetIcon(png))
+
+	require.Error(t, m.SetIcon(notPNG))
+	require.ErrorIs(t, m.SetIcon(filepath.Join(dir, "missing.png")), os.ErrNotExist)
+}
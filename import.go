@@ -1,6 +1,143 @@
 package virtualbox
 
+import (
+	"bufio"
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	reApplianceVirtualSystem = regexp.MustCompile(`^Virtual system (\d+):`)
+	reApplianceUnit          = regexp.MustCompile(`^\s*(\d+): (.+)$`)
+	reApplianceQuoted        = regexp.MustCompile(`"(.*)"`)
+	reApplianceDiskImage     = regexp.MustCompile(`source image=(.*), target path=(.*), controller=(.*)$`)
+)
+
 // ImportOV imports ova or ovf from the given path
-func ImportOV(path string) error {
-	return Manage().run("import", path)
+func ImportOV(path string, opts ...OperationOption) error {
+	return runOperation("import", func() error {
+		return Manage().run("import", path)
+	}, opts...)
+}
+
+// ImportApplianceCtx imports ova or ovf from the given path like ImportOV, cancelling the import
+// when ctx is done. VBoxManage then rolls back the import, removing the disks already written;
+// the returned error wraps ctx.Err().
+func ImportApplianceCtx(ctx context.Context, path string, opts ...OperationOption) error {
+	return runOperation("import", func() error {
+		err := Manage().setOpts(withContext(ctx)).run("import", path)
+		if errCancel := cancelled(ctx, "import", "", false); errCancel != nil {
+			return errCancel
+		}
+		return err
+	}, opts...)
+}
+
+// Appliance describes the content of an ova or ovf file as it would be imported.
+type Appliance struct {
+	Path           string
+	VirtualSystems []ApplianceVirtualSystem
+}
+
+// ApplianceVirtualSystem describes a VM of an appliance, as suggested by VirtualBox on import.
+type ApplianceVirtualSystem struct {
+	Index       int
+	Name        string
+	OSType      string
+	Description string
+	CPUs        uint
+	Memory      uint     // main memory (in MB)
+	NICs        []string // network adapter descriptions, e.g. "orig NAT, config 3, extra slot=0;type=NAT"
+	Disks       []ApplianceDisk
+}
+
+// ApplianceDisk describes a disk image of an appliance virtual system.
+type ApplianceDisk struct {
+	Unit        int    // unit number as used by <VBoxManage import --vsys <n> --unit <unit>>
+	SourceImage string // image file name in the appliance
+	TargetPath  string // path the image would be imported to
+	Controller  string // e.g. 12;channel=0
+}
+
+// InspectAppliance returns the description of the ova or ovf file at the given path without importing it.
+func InspectAppliance(path string) (*Appliance, error) {
+	stdout, stderr, err := Manage().runOutErr("import", path, "--dry-run")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to inspect appliance: path=%s, stdout=%s, stderr=%s", path, stdout, stderr)
+	}
+	appliance, err := parseApplianceDryRun(path, stdout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to parse appliance dry run output: path=%s", path)
+	}
+	return appliance, nil
+}
+
+func parseApplianceDryRun(path string, out string) (*Appliance, error) {
+	appliance := Appliance{Path: path}
+	var vsys *ApplianceVirtualSystem
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if res := reApplianceVirtualSystem.FindStringSubmatch(line); res != nil {
+			index, _ := strconv.Atoi(res[1])
+			appliance.VirtualSystems = append(appliance.VirtualSystems, ApplianceVirtualSystem{Index: index})
+			vsys = &appliance.VirtualSystems[len(appliance.VirtualSystems)-1]
+			continue
+		}
+		res := reApplianceUnit.FindStringSubmatch(line)
+		if vsys == nil || res == nil {
+			continue
+		}
+		unit, _ := strconv.Atoi(res[1])
+		if err := vsys.parseUnit(unit, res[2]); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return &appliance, nil
+}
+
+// parseUnit parses the description of a virtual system unit
+// e.g. <Suggested VM name "ubuntu">, <Number of CPUs: 2>, <Hard disk image: source image=...>.
+func (vsys *ApplianceVirtualSystem) parseUnit(unit int, desc string) error {
+	quoted := ""
+	if res := reApplianceQuoted.FindStringSubmatch(desc); res != nil {
+		quoted = res[1]
+	}
+	switch {
+	case strings.HasPrefix(desc, "Suggested OS type:"):
+		vsys.OSType = quoted
+	case strings.HasPrefix(desc, "Suggested VM name "):
+		vsys.Name = quoted
+	case strings.HasPrefix(desc, "Description "):
+		vsys.Description = quoted
+	case strings.HasPrefix(desc, "Number of CPUs:"):
+		n, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(desc, "Number of CPUs:")), 10, 32)
+		if err != nil {
+			return errors.Wrapf(err, "bad number of CPUs: %s", desc)
+		}
+		vsys.CPUs = uint(n)
+	case strings.HasPrefix(desc, "Guest memory:"):
+		memory, err := parseMemoryMB(strings.TrimPrefix(desc, "Guest memory:"))
+		if err != nil {
+			return errors.Wrapf(err, "bad guest memory: %s", desc)
+		}
+		vsys.Memory = memory
+	case strings.HasPrefix(desc, "Network adapter:"):
+		vsys.NICs = append(vsys.NICs, strings.TrimSpace(strings.TrimPrefix(desc, "Network adapter:")))
+	case strings.HasPrefix(desc, "Hard disk image:"):
+		res := reApplianceDiskImage.FindStringSubmatch(desc)
+		if res == nil {
+			return errors.Errorf("unexpected hard disk image description: %s", desc)
+		}
+		vsys.Disks = append(vsys.Disks,
+			ApplianceDisk{Unit: unit, SourceImage: res[1], TargetPath: res[2], Controller: res[3]})
+	}
+	return nil
 }
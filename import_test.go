@@ -0,0 +1,66 @@
+package virtualbox
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectAppliance(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	path := "/home/user/Downloads/ubuntu-focal.ova"
+	m := &Machine{OSType: "Ubuntu_64", CPUs: 2, Memory: 1024}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("import", path, "--dry-run").
+			Return(ReadTestData("vboxmanage-import-dry-run-1.out"), "", nil).Times(1)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		path = filepath.Join(t.TempDir(), m.Name+".ova")
+		require.NoError(t, ExportAppliance([]string{m.Name}, path))
+	}
+
+	appliance, err := InspectAppliance(path)
+
+	require.NoError(t, err)
+	require.Equal(t, path, appliance.Path)
+	require.Len(t, appliance.VirtualSystems, 1)
+	vsys := appliance.VirtualSystems[0]
+	require.Equal(t, m.OSType, vsys.OSType)
+	require.Equal(t, m.CPUs, vsys.CPUs)
+	require.Equal(t, m.Memory, vsys.Memory)
+	if ManageMock == nil {
+		return
+	}
+	targetFolder := "/home/user/VirtualBox VMs/ubuntu-focal-20.04-cloudimg-20221018/"
+	require.Equal(t,
+		&Appliance{
+			Path: path,
+			VirtualSystems: []ApplianceVirtualSystem{{
+				Index:       0,
+				Name:        "ubuntu-focal-20.04-cloudimg-20221018",
+				OSType:      "Ubuntu_64",
+				Description: "Ubuntu Cloud Image",
+				CPUs:        2,
+				Memory:      1024,
+				NICs:        []string{"orig NAT, config 3, extra slot=0;type=NAT"},
+				Disks: []ApplianceDisk{
+					{
+						Unit:        13,
+						SourceImage: "ubuntu-focal-20.04-cloudimg-20221018.vmdk",
+						TargetPath:  targetFolder + "ubuntu-focal-20.04-cloudimg-20221018.vmdk",
+						Controller:  "12;channel=0",
+					},
+					{
+						Unit:        14,
+						SourceImage: "ubuntu-focal-20.04-cloudimg-20221018-configdrive.vmdk",
+						TargetPath:  targetFolder + "ubuntu-focal-20.04-cloudimg-20221018-configdrive.vmdk",
+						Controller:  "12;channel=1",
+					},
+				},
+			}},
+		},
+		appliance)
+}
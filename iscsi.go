@@ -0,0 +1,54 @@
+package virtualbox
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// ISCSITarget describes an iSCSI target to be attached as a hard disk.
+type ISCSITarget struct {
+	Server    string // host name or IP address of the iSCSI server
+	Port      uint   // TCP port of the server, 0 for the default (3260)
+	Target    string // target name, e.g. iqn.2008-12.com.example:storage.disk1
+	LUN       uint
+	Username  string
+	Password  string // never put on the command line, written to a temporary password file instead
+	Initiator string // initiator name, empty for the VirtualBox default
+}
+
+func (cfg ISCSITarget) cmdArgs(passwordFile string) []string {
+	args := []string{"--type", "hdd", "--medium", "iscsi", "--server", cfg.Server, "--target", cfg.Target}
+	if cfg.Port > 0 {
+		args = append(args, "--tport", fmt.Sprintf("%d", cfg.Port))
+	}
+	args = append(args, "--lun", fmt.Sprintf("%d", cfg.LUN))
+	if cfg.Initiator != "" {
+		args = append(args, "--initiator", cfg.Initiator)
+	}
+	if cfg.Username != "" {
+		args = append(args, "--username", cfg.Username)
+	}
+	if passwordFile != "" {
+		args = append(args, "--passwordfile", passwordFile)
+	}
+	return args
+}
+
+// AttachISCSI attaches the given iSCSI target as a hard disk to the named storage controller.
+func (m *Machine) AttachISCSI(ctlName string, port, device uint, cfg ISCSITarget) error {
+	if cfg.Server == "" || cfg.Target == "" {
+		return errors.Errorf("iSCSI server(=%s) or target(=%s) is empty", cfg.Server, cfg.Target)
+	}
+	return withPasswordFile(cfg.Password, "", func(passwordFile string) error {
+		args := []string{"storageattach", m.Name, "--storagectl", ctlName,
+			"--port", fmt.Sprintf("%d", port), "--device", fmt.Sprintf("%d", device)}
+		args = append(args, cfg.cmdArgs(passwordFile)...)
+		stdout, stderr, err := Manage().runOutErr(args...)
+		if err != nil {
+			return errors.Wrapf(err, "fail to attach iSCSI target: vm=%s, server=%s, target=%s, stdout=%s, stderr=%s",
+				m.Name, cfg.Server, cfg.Target, stdout, stderr)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,42 @@
+package virtualbox
+
+import (
+	"os"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttachISCSI(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	if ManageMock == nil {
+		t.Skip("requires an iSCSI target")
+	}
+
+	m := &Machine{Name: "go-virtualbox"}
+	cfg := ISCSITarget{Server: "10.0.0.5", Target: "iqn.2008-12.com.example:storage.disk1", LUN: 1,
+		Username: "lab", Password: "s3cr3t"}
+	var attachArgs []string
+	var password []byte
+	ManageMock.EXPECT().runOutErr(gomock.Any()).DoAndReturn(func(args ...string) (string, string, error) {
+		attachArgs = args
+		pwFile, _ := argValue(args, "--passwordfile")
+		var err error
+		password, err = os.ReadFile(pwFile)
+		return "", "", err
+	}).Times(1)
+
+	require.NoError(t, m.AttachISCSI("SATA Controller", 1, 0, cfg))
+	require.Equal(t, "s3cr3t", string(password))
+	pwFile, _ := argValue(attachArgs, "--passwordfile")
+	require.Equal(t,
+		[]string{
+			"storageattach", "go-virtualbox", "--storagectl", "SATA Controller", "--port", "1", "--device", "0",
+			"--type", "hdd", "--medium", "iscsi", "--server", "10.0.0.5",
+			"--target", "iqn.2008-12.com.example:storage.disk1", "--lun", "1",
+			"--username", "lab", "--passwordfile", pwFile,
+		},
+		attachArgs)
+}
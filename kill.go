@@ -0,0 +1,86 @@
+package virtualbox
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// KillPoweroffTimeout is how long Kill waits for <VBoxManage controlvm poweroff> before killing the VM process.
+	KillPoweroffTimeout = 10 * time.Second
+
+	// vmProcessIDs and killProcess are variables so that tests can replace them.
+	vmProcessIDs = findVMProcessIDs
+	killProcess  = func(pid int) error {
+		p, err := os.FindProcess(pid)
+		if err != nil {
+			return err
+		}
+		return p.Kill()
+	}
+)
+
+// Kill is a last resort to stop a hung machine, when even Poweroff does not respond.
+//
+// It first tries <VBoxManage controlvm poweroff>. If it fails or does not return within KillPoweroffTimeout,
+// the host processes (e.g. VBoxHeadless) whose command line contains the machine UUID are killed.
+// Killing a machine which is not running is a no-op.
+func (m *Machine) Kill() error {
+	if m.UUID == "" {
+		return errors.Errorf("fail to kill vm=%s: machine UUID is unknown", m.Name)
+	}
+	poweroffErr := make(chan error, 1)
+	go func() {
+		// the command keeps running in the background if it hangs
+		poweroffErr <- Manage().run("controlvm", m.Name, "poweroff")
+	}()
+	select {
+	case err := <-poweroffErr:
+		if err == nil {
+			return nil
+		}
+		Debug("poweroff failed, killing vm process: vm=%s, err=%v", m.Name, err)
+	case <-time.After(KillPoweroffTimeout):
+		Debug("poweroff timed out, killing vm process: vm=%s, timeout=%s", m.Name, KillPoweroffTimeout)
+	}
+
+	pids, err := vmProcessIDs(m.UUID)
+	if err != nil {
+		return errors.Wrapf(err, "fail to find vm process: vm=%s, uuid=%s", m.Name, m.UUID)
+	}
+	if len(pids) == 0 {
+		// e.g. the machine was already stopped, so that poweroff failed
+		if err := m.Refresh(); err == nil && m.State != Running {
+			return nil
+		}
+		return errors.Errorf("fail to kill vm=%s: no process found for uuid=%s", m.Name, m.UUID)
+	}
+	for _, pid := range pids {
+		if err := killProcess(pid); err != nil {
+			return errors.Wrapf(err, "fail to kill vm process: vm=%s, pid=%d", m.Name, pid)
+		}
+	}
+	return nil
+}
+
+// parseProcessList returns the IDs of the processes whose command line contains the given string,
+// from a process list having one <pid> <command line> per line.
+func parseProcessList(out string, contained string) []int {
+	pids := []int{}
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(s.Text()), " ", 2)
+		if len(fields) != 2 || !strings.Contains(fields[1], contained) {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,85 @@
+package virtualbox
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcessList(t *testing.T) {
+	out := `    1 /sbin/init
+ 4242 /usr/lib/virtualbox/VBoxHeadless --comment go-virtualbox --startvm 37f5d336-bf07-48dd-947c-37e6a56420a7 --vrde config
+ 4250 /usr/lib/virtualbox/VBoxHeadless --comment other --startvm 2e16b1fc-675d-4a7a-a9a1-e89a8bde7874 --vrde config
+`
+	require.Equal(t, []int{4242}, parseProcessList(out, "37f5d336-bf07-48dd-947c-37e6a56420a7"))
+}
+
+func TestKillFallsBackToProcessKill(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", UUID: "37f5d336-bf07-48dd-947c-37e6a56420a7", State: Running}
+	if ManageMock == nil {
+		m = testMachine(t, "", Running)
+		t.Cleanup(func() {
+			require.NoError(t, m.Refresh())
+			require.NoError(t, m.Start())
+		})
+	}
+	origIDs, origKill := vmProcessIDs, killProcess
+	defer func() { vmProcessIDs, killProcess = origIDs, origKill }()
+	var killed []int
+	vmProcessIDs = func(uuid string) ([]int, error) {
+		require.Equal(t, m.UUID, uuid)
+		return []int{4242}, nil
+	}
+	killProcess = func(pid int) error {
+		killed = append(killed, pid)
+		return nil
+	}
+
+	if ManageMock != nil {
+		// a hung machine which does not power off
+		ManageMock.EXPECT().run("controlvm", "go-virtualbox", "poweroff").Return(errors.New("timeout")).Times(1)
+		require.NoError(t, m.Kill())
+		require.Equal(t, []int{4242}, killed)
+
+		killed = nil
+		ManageMock.EXPECT().run("controlvm", "go-virtualbox", "poweroff").Return(nil).Times(1)
+	}
+	require.NoError(t, m.Kill())
+	require.Empty(t, killed, "no process should be killed once powered off")
+}
+
+func TestKillStoppedMachine(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", UUID: "37f5d336-bf07-48dd-947c-37e6a56420a7", State: Running}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().run("controlvm", "go-virtualbox", "poweroff").
+				Return(errors.New("VBoxManage: error: Machine 'go-virtualbox' is not currently running")).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Poweroff, "0")),
+		)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+	}
+	origIDs := vmProcessIDs
+	defer func() { vmProcessIDs = origIDs }()
+	vmProcessIDs = func(string) ([]int, error) { return nil, nil }
+
+	require.NoError(t, m.Kill())
+	require.NotEqual(t, Running, m.State)
+
+	if ManageMock != nil {
+		// no process found for a machine still running
+		gomock.InOrder(
+			ManageMock.EXPECT().run("controlvm", "go-virtualbox", "poweroff").Return(errors.New("timeout")).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "0")),
+		)
+		require.Error(t, m.Kill())
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package virtualbox
+
+import (
+	"os/exec"
+)
+
+func findVMProcessIDs(uuid string) ([]int, error) {
+	out, err := exec.Command("ps", "-axo", "pid=,args=").Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseProcessList(string(out), uuid), nil
+}
@@ -0,0 +1,14 @@
+package virtualbox
+
+import (
+	"os/exec"
+)
+
+func findVMProcessIDs(uuid string) ([]int, error) {
+	out, err := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command",
+		`Get-CimInstance Win32_Process | ForEach-Object { "$($_.ProcessId) $($_.CommandLine)" }`).Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseProcessList(string(out), uuid), nil
+}
@@ -1,5 +1,7 @@
 package virtualbox
 
+import "time"
+
 // LogFunc is the signature to log traces.
 type LogFunc func(string, ...interface{})
 
@@ -8,3 +10,23 @@ func noLog(string, ...interface{}) {}
 // Debug is the Logger currently in use.
 var Debug LogFunc = noLog
 var Trace LogFunc = noLog
+
+// TimingFunc receives the arguments and the wall-clock duration of a command run.
+type TimingFunc func(args []string, d time.Duration)
+
+// Timing is called after each VBoxManage/VBoxControl run if set, e.g. to find out which commands
+// dominate a provisioning pipeline.
+var Timing TimingFunc
+
+// timed returns the function reporting the duration of the command run with the given arguments,
+// to be deferred when the command starts.
+func timed(args []string) func() {
+	start := time.Now()
+	return func() {
+		d := time.Since(start)
+		Debug("command took %s: %v", d, args)
+		if Timing != nil {
+			Timing(args, d)
+		}
+	}
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"runtime"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
 )
 
 var logger = log.New(os.Stderr, "", 0)
@@ -23,3 +27,26 @@ func LogF(format string, args ...interface{}) {
 	}
 	logLn(fmt.Sprintf(format, args...))
 }
+
+func TestTiming(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+	var timedArgs [][]string
+	var durations []time.Duration
+	Timing = func(args []string, d time.Duration) {
+		timedArgs = append(timedArgs, args)
+		durations = append(durations, d)
+	}
+	defer func() { Timing = nil }()
+	cmd := command{program: "sh"}
+
+	require.NoError(t, cmd.run("-c", "sleep 0.05"))
+	_, err := cmd.runOut("-c", "true")
+	require.NoError(t, err)
+	_, _, err = cmd.runOutErr("-c", "true")
+	require.NoError(t, err)
+
+	require.Equal(t, [][]string{{"-c", "sleep 0.05"}, {"-c", "true"}, {"-c", "true"}}, timedArgs)
+	require.GreaterOrEqual(t, durations[0], 50*time.Millisecond)
+}
@@ -27,32 +27,78 @@ const (
 	Saved = MachineState("saved")
 	// Aborted is a MachineState value.
 	Aborted = MachineState("aborted")
+	// GuruMeditation is a MachineState value.
+	GuruMeditation = MachineState("gurumeditation")
 )
 
 // Flag is an active VM configuration toggle
 type Flag int
 
+// Firmware values of Machine.Firmware.
+const (
+	FirmwareBIOS  = "bios"
+	FirmwareEFI   = "efi"
+	FirmwareEFI32 = "efi32"
+	FirmwareEFI64 = "efi64"
+)
+
+// isEFI returns true if the machine has an EFI firmware, whatever its bitness.
+func (m *Machine) isEFI() bool {
+	return strings.HasPrefix(m.Firmware, FirmwareEFI)
+}
+
 // Flag names in lowercases to be consistent with VBoxManage options.
 const (
-	ACPI       Flag = 1 << iota // --apic on|off: Enables and disables I/O APIC. With I/O APIC, operating systems can use more than 16 interrupt requests (IRQs) thus avoiding IRQ sharing for improved reliability. This setting is enabled by default.
-	IOAPIC                      //--acpi on|off and --ioapic on|off: Determines whether the VM has ACPI and I/O APIC support.
-	RTCUSEUTC                   // --rtcuseutc on|off: Sets the real-time clock (RTC) to operate in UTC time
-	CPUHOTPLUG                  // -cpuhotplug on|off: Enables CPU hot-plugging. When enabled, virtual CPUs can be added to and removed from a virtual machine while it is running.
-	PAE                         // --pae on|off: Enables and disables PAE
-	LONGMODE                    // --longmode on|off: Enables and disables long mode.
-	SYNTHCPU
-	HPET             // --hpet on|off: Enables and disables a High Precision Event Timer (HPET) which can replace the legacy system timers. This is turned off by default. Note that Windows supports a HPET only from Vista onwards.
-	HWVIRTEX         // --hwvirtex on|off: Enables and disables the use of hardware virtualization extensions, such as Intel VT-x or AMD-V, in the processor of your host system
-	TRIPLEFAULTRESET // --triplefaultreset on|off: Enables resetting of the guest instead of triggering a Guru Meditation. Some guests raise a triple fault to reset the CPU so sometimes this is desired behavior. Works only for non-SMP guests.
-	NESTEDPAGING     // --nestedpaging on|off: If hardware virtualization is enabled, this additional setting enables or disables the use of the nested paging feature in the processor of your host system
-	LARGEPAGES       // --largepages on|off: If hardware virtualization and nested paging are enabled, for Intel VT-x only, an additional performance improvement of up to 5% can be obtained by enabling this setting. This causes the hypervisor to use large pages to reduce TLB use and overhead.
-	VTXVPID          // -vtxvpid on|off: If hardware virtualization is enabled, for Intel VT-x only, this additional setting enables or disables the use of the tagged TLB (VPID) feature in the processor of your host system
-	VTXUX            // --vtxux on|off: If hardware virtualization is enabled, for Intel VT-x only, this setting enables or disables the use of the unrestricted guest mode feature for executing your guest.
-	ACCELERATE3D     // --accelerate3d on|off: If the Guest Additions are installed, this setting enables or disables hardware 3D acceleration.
-	NESTED_HW_VIRT   //--nested-hw-virt on|off: If hardware virtualization is enabled, this setting enables or disables passthrough of hardware virtualization features to the guest.
-	X2APIC           // --x2apic on|off: Enables and disables CPU x2APIC support. CPU x2APIC support helps operating systems run more efficiently on high core count configurations, and optimizes interrupt distribution in virtualized environments. This setting is enabled by default. Disable this setting when using host or guest operating systems that are incompatible with x2APIC support.
+	ACPI              Flag = 1 << iota // --acpi on|off: Determines whether the VM has ACPI support.
+	IOAPIC                             // --ioapic on|off: Enables and disables I/O APIC. With I/O APIC, operating systems can use more than 16 interrupt requests (IRQs) thus avoiding IRQ sharing for improved reliability. This setting is enabled by default.
+	RTCUSEUTC                          // --rtcuseutc on|off: Sets the real-time clock (RTC) to operate in UTC time
+	CPUHOTPLUG                         // --cpuhotplug on|off: Enables CPU hot-plugging. When enabled, virtual CPUs can be added to and removed from a virtual machine while it is running.
+	PAE                                // --pae on|off: Enables and disables PAE
+	LONGMODE                           // --longmode on|off: Enables and disables long mode.
+	_                                  // formerly SYNTHCPU: --synthcpu is no longer supported by VBoxManage
+	HPET                               // --hpet on|off: Enables and disables a High Precision Event Timer (HPET) which can replace the legacy system timers. This is turned off by default. Note that Windows supports a HPET only from Vista onwards.
+	HWVIRTEX                           // --hwvirtex on|off: Enables and disables the use of hardware virtualization extensions, such as Intel VT-x or AMD-V, in the processor of your host system
+	TRIPLEFAULTRESET                   // --triplefaultreset on|off: Enables resetting of the guest instead of triggering a Guru Meditation. Some guests raise a triple fault to reset the CPU so sometimes this is desired behavior. Works only for non-SMP guests.
+	NESTEDPAGING                       // --nestedpaging on|off: If hardware virtualization is enabled, this additional setting enables or disables the use of the nested paging feature in the processor of your host system
+	LARGEPAGES                         // --largepages on|off: If hardware virtualization and nested paging are enabled, for Intel VT-x only, an additional performance improvement of up to 5% can be obtained by enabling this setting. This causes the hypervisor to use large pages to reduce TLB use and overhead.
+	VTXVPID                            // --vtxvpid on|off: If hardware virtualization is enabled, for Intel VT-x only, this additional setting enables or disables the use of the tagged TLB (VPID) feature in the processor of your host system
+	VTXUX                              // --vtxux on|off: If hardware virtualization is enabled, for Intel VT-x only, this setting enables or disables the use of the unrestricted guest mode feature for executing your guest.
+	ACCELERATE3D                       // --accelerate3d on|off: If the Guest Additions are installed, this setting enables or disables hardware 3D acceleration.
+	NESTED_HW_VIRT                     //--nested-hw-virt on|off: If hardware virtualization is enabled, this setting enables or disables passthrough of hardware virtualization features to the guest.
+	X2APIC                             // --x2apic on|off: Enables and disables CPU x2APIC support. CPU x2APIC support helps operating systems run more efficiently on high core count configurations, and optimizes interrupt distribution in virtualized environments. This setting is enabled by default. Disable this setting when using host or guest operating systems that are incompatible with x2APIC support. Opt-in: Modify only turns it off when in Machine.ExplicitFlags.
+	APIC                               // --apic on|off: Enables and disables the local APIC, which is required by the I/O APIC, x2APIC and guests with multiple CPUs. This setting is enabled by default. Opt-in: Modify only turns it off when in Machine.ExplicitFlags.
+	ACCELERATE2DVIDEO                  // --accelerate2dvideo on|off: If the Guest Additions are installed, this setting enables or disables 2D video acceleration, which some legacy Windows guests benefit from. Opt-in, as VirtualBox 7 dropped it: Modify only turns it off when in Machine.ExplicitFlags.
 )
 
+// flagOptions maps each flag to the modifyvm option setting it, in the order used by Modify.
+// The option names are the ones of VirtualBox 6.1, which VirtualBox 7 still accepts.
+// Without the leading --, they are also the keys of the flags in the VM info.
+// Opt-in options were not sent by Modify before, so they are only sent when set in Flag or ExplicitFlags.
+var flagOptions = []struct {
+	flag   Flag
+	option string
+	optIn  bool
+}{
+	{ACPI, "--acpi", false},
+	{IOAPIC, "--ioapic", false},
+	{APIC, "--apic", true},
+	{RTCUSEUTC, "--rtcuseutc", false},
+	{CPUHOTPLUG, "--cpuhotplug", false},
+	{PAE, "--pae", false},
+	{LONGMODE, "--longmode", false},
+	{HPET, "--hpet", false},
+	{HWVIRTEX, "--hwvirtex", false},
+	{TRIPLEFAULTRESET, "--triplefaultreset", false},
+	{NESTEDPAGING, "--nestedpaging", false},
+	{LARGEPAGES, "--largepages", false},
+	{VTXVPID, "--vtxvpid", false},
+	{VTXUX, "--vtxux", false},
+	{ACCELERATE3D, "--accelerate3d", false},
+	{ACCELERATE2DVIDEO, "--accelerate2dvideo", true},
+	{NESTED_HW_VIRT, "--nested-hw-virt", false},
+	{X2APIC, "--x2apic", true},
+}
+
 // Convert bool to "on"/"off"
 func bool2string(b bool) string {
 	if b {
@@ -66,6 +112,42 @@ func (f Flag) Get(o Flag) string {
 	return bool2string(f&o == o)
 }
 
+// flagsFromPropMap returns the flags set to on in the given VM info map.
+func flagsFromPropMap(propMap map[string]string) Flag {
+	var f Flag
+	for _, fo := range flagOptions {
+		if propMap[strings.TrimPrefix(fo.option, "--")] == "on" {
+			f |= fo.flag
+		}
+	}
+	return f
+}
+
+// flagCmdArgs returns the modifyvm args applying the flags. An opt-in flag is only applied when set in Flag
+// or ExplicitFlags, so that Modify keeps its current value otherwise.
+func (m *Machine) flagCmdArgs() []CmdArg {
+	args := make([]CmdArg, 0, len(flagOptions))
+	for _, fo := range flagOptions {
+		if fo.optIn && (m.Flag|m.ExplicitFlags)&fo.flag == 0 {
+			continue
+		}
+		args = append(args, NewCmdArg(fo.option, m.Flag.Get(fo.flag)))
+	}
+	return args
+}
+
+// optInFlagsFromPropMap returns the opt-in flags found in the given VM info map, whether on or off,
+// so that Modify keeps them as read.
+func optInFlagsFromPropMap(propMap map[string]string) Flag {
+	var f Flag
+	for _, fo := range flagOptions {
+		if _, ok := propMap[strings.TrimPrefix(fo.option, "--")]; ok && fo.optIn {
+			f |= fo.flag
+		}
+	}
+	return f
+}
+
 // Machine information.
 type Machine struct {
 	Name               string
@@ -76,12 +158,35 @@ type Machine struct {
 	VRAM               uint // video memory (in MB)
 	CfgFile            string
 	BaseFolder         string
-	OSType             string
+	OSType             string // e.g. Ubuntu_64, empty to keep the current one
 	Flag               Flag
+	ExplicitFlags      Flag     // opt-in flags (X2APIC, APIC, ACCELERATE2DVIDEO) which Modify turns off when not in Flag, instead of keeping them; the ones in the VM info when read
 	BootOrder          []string // max 4 slots, each in {none|floppy|dvd|disk|net}
 	NICs               []NIC
 	UARTs              UARTs
 	StorageControllers StorageControllers
+	SharedFolders      []SharedFolder
+	ProcessPriority    string // VirtualBox 7+: default|flat|low|normal|high, empty to keep the current one
+	SnapshotFolder     string // folder of the snapshots, empty to keep the current one
+	CPUProfile         string // host or a name from CPUProfiles (e.g. Intel Core i7-6700K), empty to keep the current one
+	Tracing            Tracing
+	HardwareUUID       string // UUID presented to the guest (DMI system UUID), empty to keep the current one
+	DMI                DMI    // applied with ApplyDMI, not read back from the VM
+	TPM                TPM
+	BIOS               BIOS
+	USBCardReader      *bool  // emulated USB card reader, nil to keep the current one
+	CurrentSnapshot    string // UUID of the snapshot the current state is based on, empty without snapshots
+
+	Firmware           string // bios|efi|efi32|efi64, empty for bios
+	Chipset            string // piix3|ich9
+	GraphicsController string // none|vboxvga|vmsvga|vboxsvga
+	ParavirtConfigured string // paravirtualization provider set: none|default|legacy|minimal|hyperv|kvm
+	ParavirtEffective  string // paravirtualization provider used, e.g. kvm or hyperv (depending on the OS type) for default
+	Audio              string // host audio driver, none when audio is disabled
+	VRDE               bool   // remote display server enabled
+	Clipboard          string // disabled|hosttoguest|guesttohost|bidirectional
+
+	GuestAdditionsRunLevel uint // 0 when the guest additions are not running, 1 (system) to 3 (desktop) otherwise
 }
 
 // New creates a new machine.
@@ -108,10 +213,16 @@ func (m *Machine) Refresh() error {
 }
 
 // Start starts the machine.
+//
+// The VM process is detached (e.g. headless), so its output is not available to the caller:
+// use TailLog to follow the boot from the VirtualBox log of the machine.
+//
+// ErrHardwareVirtUnavailable is returned if the machine cannot start because VT-x/AMD-V is not available,
+// an *ErrDanglingHostonlyAdapter if one of its NICs references a host-only adapter which does not exist anymore.
 func (m *Machine) Start(startVmParamOverrides ...CmdArg) error {
 	switch m.State {
 	case Paused:
-		return Manage().run("controlvm", m.Name, "resume")
+		return m.Resume()
 	case Poweroff, Saved, Aborted:
 		startVmParams := CmdArgs{}
 		startVmParams.Append("--type", "headless")
@@ -123,7 +234,11 @@ func (m *Machine) Start(startVmParamOverrides ...CmdArg) error {
 		cmdArgs = append(cmdArgs, startVmParams.Args()...)
 
 		// default of no override: run("startvm", m.Name, "--type", "headless")
-		return Manage().run(cmdArgs...)
+		err := Manage().run(cmdArgs...)
+		if dangling := danglingHostonlyAdapterError(err, m.Name); dangling != nil {
+			return dangling
+		}
+		return hardwareVirtError(err, m.Name)
 	}
 	return nil
 }
@@ -155,6 +270,15 @@ func (m *Machine) Pause() error {
 	return Manage().run("controlvm", m.Name, "pause")
 }
 
+// Resume resumes the execution of the machine paused with Pause.
+// ErrMachineNotPaused is returned if the machine is not paused, e.g. if it has been saved with Save.
+func (m *Machine) Resume() error {
+	if m.State != Paused {
+		return errors.Wrapf(ErrMachineNotPaused, "fail to resume vm=%s, state=%s", m.Name, m.State)
+	}
+	return Manage().run("controlvm", m.Name, "resume")
+}
+
 // Stop gracefully stops the machine.
 func (m *Machine) Stop() error {
 	switch m.State {
@@ -166,7 +290,7 @@ func (m *Machine) Stop() error {
 		}
 	}
 
-	for m.State != Poweroff { // busy wait until the machine is stopped
+	for m.State != Poweroff && m.State != Aborted { // busy wait until the machine is stopped
 		if err := Manage().run("controlvm", m.Name, "acpipowerbutton"); err != nil {
 			return err
 		}
@@ -277,29 +401,56 @@ func GetMachine(id string) (*Machine, error) {
 	if err != nil {
 		return nil, err
 	}
+	return newMachineFromPropMap(propMap)
+}
+
+// newMachineFromPropMap creates a machine from a VM info map, as returned by vminfoAsPropMap.
+func newMachineFromPropMap(propMap map[string]string) (*Machine, error) {
+	var err error
 
 	/* Extract basic info */
 	m := New()
 	m.Name = propMap["name"]
 	m.UUID = propMap["UUID"]
 	m.State = MachineState(propMap["VMState"])
-	n, err := strconv.ParseUint(propMap["memory"], 10, 32)
+	m.CurrentSnapshot = propMap["CurrentSnapshotUUID"]
+	m.Memory, err = parseMemoryMB(propMap["memory"])
 	if err != nil {
 		return nil, err
 	}
-	m.Memory = uint(n)
-	n, err = strconv.ParseUint(propMap["cpus"], 10, 32)
+	n, err := strconv.ParseUint(propMap["cpus"], 10, 32)
 	if err != nil {
 		return nil, err
 	}
 	m.CPUs = uint(n)
-	n, err = strconv.ParseUint(propMap["vram"], 10, 32)
+	m.VRAM, err = parseMemoryMB(propMap["vram"])
 	if err != nil {
 		return nil, err
 	}
-	m.VRAM = uint(n)
+	m.Flag = flagsFromPropMap(propMap)
+	m.ExplicitFlags = optInFlagsFromPropMap(propMap)
+	m.OSType = osTypeID(propMap["ostype"]) // the VM info has the description, e.g. ostype="Ubuntu (64-bit)"
+	for i := 1; i <= 4; i++ {
+		if dev, ok := propMap[fmt.Sprintf("boot%d", i)]; ok {
+			m.BootOrder = append(m.BootOrder, dev)
+		}
+	}
 	m.CfgFile = propMap["CfgFile"]
 	m.BaseFolder = filepath.Dir(m.CfgFile)
+	m.ProcessPriority = propMap["vmprocpriority"]
+	m.SnapshotFolder = propMap["SnapFldr"]
+	m.CPUProfile = propMap["cpu-profile"]
+	m.Tracing = tracingFromPropMap(propMap)
+	m.HardwareUUID = propMap["hardwareuuid"]
+	m.TPM = tpmFromPropMap(propMap)
+	m.BIOS = biosFromPropMap(propMap)
+	if runLevel, ok := propMap["GuestAdditionsRunLevel"]; ok {
+		n, err := strconv.ParseUint(runLevel, 10, 32)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad guest additions run level: %s", runLevel)
+		}
+		m.GuestAdditionsRunLevel = uint(n)
+	}
 
 	/* Extract NIC info */
 	for i := 1; i <= 4; i++ {
@@ -336,6 +487,9 @@ func GetMachine(id string) (*Machine, error) {
 	}
 	m.UARTs = *pUARTs
 
+	m.SharedFolders = sharedFoldersFromPropMap(propMap)
+	readSettingsFromPropMap(m, propMap)
+
 	scs, err := NewStorageControllersFromProps(propMap)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to read storage controllers")
@@ -348,6 +502,24 @@ func GetMachine(id string) (*Machine, error) {
 	return m, nil
 }
 
+// readSettingsFromPropMap reads the machine settings which are only kept for drift detection.
+// Absent keys (e.g. from older VirtualBox versions) leave the settings empty.
+func readSettingsFromPropMap(m *Machine, propMap map[string]string) {
+	// firmware="BIOS", firmware="EFI"
+	m.Firmware = strings.ToLower(propMap["firmware"])
+	m.Chipset = propMap["chipset"]
+	m.GraphicsController = propMap["graphicscontroller"]
+	m.ParavirtConfigured = propMap["paravirtprovider"]
+	m.ParavirtEffective = propMap["effparavirtprovider"]
+	m.Audio = propMap["audio"]
+	m.VRDE = propMap["vrde"] == "on"
+	m.Clipboard = propMap["clipboard"]
+	if cardReader, ok := propMap["usbcardreader"]; ok {
+		enabled := cardReader == "on"
+		m.USBCardReader = &enabled
+	}
+}
+
 // ListMachines lists all registered machines.
 func ListMachines() ([]*Machine, error) {
 	out, err := Manage().runOut("list", "vms")
@@ -378,6 +550,69 @@ func ListMachines() ([]*Machine, error) {
 	return ms, nil
 }
 
+// GetMachines reads the machines with the given ids (names or UUIDs) in one pass, returning them by id
+// with the errors of the ones which could not be read, e.g. ErrMachineNotExist (wrapped with the id).
+// The machines are read one after the other, see GetMachine.
+func GetMachines(ids []string) (map[string]*Machine, []error) {
+	ms := make(map[string]*Machine, len(ids))
+	var errs []error
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		m, err := GetMachine(id)
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "fail to get machine: id=%s", id))
+			continue
+		}
+		ms[id] = m
+	}
+	return ms, errs
+}
+
+// CPUProfiles returns the names of the CPU profiles which can be used as Machine.CPUProfile, besides host.
+func CPUProfiles() ([]string, error) {
+	stdout, stderr, err := Manage().runOutErr("list", "cpu-profiles")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to list cpu profiles: stderr=%s", stderr)
+	}
+	profiles := []string{}
+	s := bufio.NewScanner(strings.NewReader(stdout))
+	for s.Scan() {
+		if name := strings.TrimPrefix(s.Text(), "Name:"); name != s.Text() {
+			profiles = append(profiles, strings.TrimSpace(name))
+		}
+	}
+	return profiles, s.Err()
+}
+
+// ListMachinesDetailed lists all registered machines using a single <VBoxManage list vms --long>,
+// instead of one <VBoxManage showvminfo> per machine as ListMachines does.
+//
+// The long listing is not machine readable, so only the basic info (name, UUID, state, CPUs, memory,
+// VRAM, config file) and the NICs are filled. Use Refresh to get the full machine info.
+func ListMachinesDetailed() ([]*Machine, error) {
+	stdout, stderr, err := Manage().runOutErr("list", "vms", "--long")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to list vms: stderr=%s", stderr)
+	}
+	ms := []*Machine{}
+	for _, block := range splitVMInfoLongBlocks(stdout) {
+		propMap, err := vminfoLongAsPropMap(strings.NewReader(block))
+		if err != nil {
+			return nil, err
+		}
+		m, err := newMachineFromPropMap(propMap)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to read vm info from list: vm=%s", propMap["name"])
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
 // CreateMachine creates a new machine. If basefolder is empty, use default.
 func CreateMachine(uuid, name, basefolder string) (*Machine, error) {
 	if name == "" || uuid == "" {
@@ -412,38 +647,119 @@ func CreateMachine(uuid, name, basefolder string) (*Machine, error) {
 	return m, nil
 }
 
+// CreateConfig holds the initial settings of a machine created with CreateMachineWith.
+type CreateConfig struct {
+	UUID       string   // generated if empty
+	Name       string   // required
+	BaseFolder string   // empty for the default one
+	OSType     string   // e.g. Ubuntu_64, empty to keep the VirtualBox default
+	Memory     uint     // main memory (in MB), 0 to keep the VirtualBox default
+	CPUs       uint     // 0 to keep the VirtualBox default
+	Overrides  []CmdArg // further modifyvm args, replacing the ones above for the same keys
+}
+
+// CreateMachineWith creates a new machine and applies its initial settings at once with a single modifyvm,
+// instead of CreateMachine followed by Modify. Only the given settings are applied, not the defaults of Modify.
+// The machine is unregistered and deleted if its initial settings cannot be applied.
+func CreateMachineWith(cfg CreateConfig) (*Machine, error) {
+	uuid := cfg.UUID
+	if uuid == "" {
+		var err error
+		if uuid, err = newUUID(); err != nil {
+			return nil, err
+		}
+	}
+	m, err := CreateMachine(uuid, cfg.Name, cfg.BaseFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdArgs := CmdArgs{}
+	if cfg.OSType != "" {
+		cmdArgs.Append("--ostype", cfg.OSType)
+	}
+	if cfg.Memory > 0 {
+		cmdArgs.Append("--memory", fmt.Sprintf("%d", cfg.Memory))
+	}
+	if cfg.CPUs > 0 {
+		cmdArgs.Append("--cpus", fmt.Sprintf("%d", cfg.CPUs))
+	}
+	cmdArgs.AppendOverride(cfg.Overrides...)
+	args := cmdArgs.Args()
+	if len(args) == 0 {
+		return m, nil
+	}
+	args = append([]string{"modifyvm", m.Name}, args...)
+	if stdout, stderr, err := Manage().runOutErr(args...); err != nil {
+		return nil, rollbackCreation(m, errors.Wrapf(err, "fail to apply initial settings: vm=%s, args=%v, stdout=%s, stderr=%s",
+			m.Name, args, stdout, stderr))
+	}
+	if err := m.Refresh(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// rollbackCreation unregisters and deletes the machine m, whose creation failed with err, and returns err.
+func rollbackCreation(m *Machine, err error) error {
+	if errDel := Manage().run("unregistervm", m.Name, "--delete"); errDel != nil {
+		return errors.Wrapf(err, "fail to roll back creation: err=%v", errDel)
+	}
+	return err
+}
+
 // Modify changes the settings of the machine.
+//
+// The given overrides replace the args Modify would otherwise use for the same keys.
+// An override created with NewCmdArgDeleted removes the arg, including one of the
+// hardcoded defaults (e.g. --bioslogofadein), from the modifyvm command.
 func (m *Machine) Modify(override ...CmdArg) error {
+	if err := m.Tracing.validate(); err != nil {
+		return err
+	}
+	if err := m.TPM.validate(); err != nil {
+		return err
+	}
+	if err := m.BIOS.validate(); err != nil {
+		return err
+	}
 	cmdArgs := CmdArgs{}
 	args := []string{"modifyvm", m.Name}
-	cmdArgs.Append("--firmware", "bios")
+	firmware := m.Firmware
+	if firmware == "" {
+		firmware = FirmwareBIOS
+	}
+	cmdArgs.Append("--firmware", firmware)
 	cmdArgs.Append("--bioslogofadein", "off")
 	cmdArgs.Append("--bioslogofadeout", "off")
 	cmdArgs.Append("--bioslogodisplaytime", "0")
-	cmdArgs.Append("--biosbootmenu", "disabled")
+	cmdArgs.AppendCmdArgs(m.BIOS.cmdArgs()...)
 
-	cmdArgs.Append("--ostype", m.OSType)
+	if m.OSType != "" {
+		cmdArgs.Append("--ostype", m.OSType)
+	}
 	cmdArgs.Append("--cpus", fmt.Sprintf("%d", m.CPUs))
 	cmdArgs.Append("--memory", fmt.Sprintf("%d", m.Memory))
 	cmdArgs.Append("--vram", fmt.Sprintf("%d", m.VRAM))
+	if m.ProcessPriority != "" {
+		cmdArgs.Append("--vm-process-priority", m.ProcessPriority)
+	}
+	if m.SnapshotFolder != "" {
+		cmdArgs.Append("--snapshotfolder", m.SnapshotFolder)
+	}
+	if m.CPUProfile != "" {
+		cmdArgs.Append("--cpu-profile", m.CPUProfile)
+	}
+	if m.HardwareUUID != "" {
+		cmdArgs.Append("--hardwareuuid", m.HardwareUUID)
+	}
+	if m.USBCardReader != nil {
+		cmdArgs.Append("--usbcardreader", bool2string(*m.USBCardReader))
+	}
+	cmdArgs.AppendCmdArgs(m.Tracing.cmdArgs()...)
+	cmdArgs.AppendCmdArgs(m.TPM.cmdArgs()...)
 
-	cmdArgs.Append("--acpi", m.Flag.Get(ACPI))
-	cmdArgs.Append("--ioapic", m.Flag.Get(IOAPIC))
-	cmdArgs.Append("--rtcuseutc", m.Flag.Get(RTCUSEUTC))
-	cmdArgs.Append("--cpuhotplug", m.Flag.Get(CPUHOTPLUG))
-	cmdArgs.Append("--pae", m.Flag.Get(PAE))
-	cmdArgs.Append("--longmode", m.Flag.Get(LONGMODE))
-	//TODO check cause error VBoxManage: error: Unknown option: --synthcpu
-	//"--synthcpu", m.Flag.Get(SYNTHCPU),
-	cmdArgs.Append("--hpet", m.Flag.Get(HPET))
-	cmdArgs.Append("--hwvirtex", m.Flag.Get(HWVIRTEX))
-	cmdArgs.Append("--triplefaultreset", m.Flag.Get(TRIPLEFAULTRESET))
-	cmdArgs.Append("--nestedpaging", m.Flag.Get(NESTEDPAGING))
-	cmdArgs.Append("--largepages", m.Flag.Get(LARGEPAGES))
-	cmdArgs.Append("--vtxvpid", m.Flag.Get(VTXVPID))
-	cmdArgs.Append("--vtxux", m.Flag.Get(VTXUX))
-	cmdArgs.Append("--accelerate3d", m.Flag.Get(ACCELERATE3D))
-	cmdArgs.Append("--nested-hw-virt", m.Flag.Get(NESTED_HW_VIRT))
+	cmdArgs.AppendCmdArgs(m.flagCmdArgs()...)
 
 	for i, dev := range m.BootOrder {
 		if i > 3 {
@@ -477,10 +793,81 @@ func (m *Machine) Modify(override ...CmdArg) error {
 	return m.Refresh()
 }
 
+// SetMemory sets the main memory (in MB) of the machine. Unlike Modify, only --memory is changed.
+func (m *Machine) SetMemory(mb uint) error {
+	return m.modifyOne("--memory", fmt.Sprintf("%d", mb))
+}
+
+// SetCPUs sets the number of CPUs of the machine. Unlike Modify, only --cpus is changed.
+func (m *Machine) SetCPUs(n uint) error {
+	return m.modifyOne("--cpus", fmt.Sprintf("%d", n))
+}
+
+// SetVRAM sets the video memory (in MB) of the machine. Unlike Modify, only --vram is changed.
+func (m *Machine) SetVRAM(mb uint) error {
+	return m.modifyOne("--vram", fmt.Sprintf("%d", mb))
+}
+
+// modifyOne runs <VBoxManage modifyvm> for the given setting only, then refreshes the machine.
+func (m *Machine) modifyOne(key, value string) error {
+	stdout, stderr, err := Manage().runOutErr("modifyvm", m.Name, key, value)
+	if err != nil {
+		return errors.Wrapf(err, "fail to modify vm: vm=%s, %s=%s, stdout=%s, stderr=%s",
+			m.Name, key, value, stdout, stderr)
+	}
+	return m.Refresh()
+}
+
+// RenameUSBController renames the USB controller of the machine named oldName to newName.
+func (m *Machine) RenameUSBController(oldName, newName string) error {
+	stdout, stderr, err := Manage().runOutErr("modifyvm", m.Name, "--usbrename", oldName, newName)
+	if err != nil {
+		return errors.Wrapf(err, "fail to rename usb controller: vm=%s, old=%s, new=%s, stdout=%s, stderr=%s",
+			m.Name, oldName, newName, stdout, stderr)
+	}
+	return nil
+}
+
+// SetHardwareUUID sets the UUID presented to the guest (DMI system UUID) and returns it, e.g. to give
+// a cloned machine an identity of its own for guest software bound to it. A random UUID is generated
+// if the given one is empty. Unlike Modify, only --hardwareuuid is changed.
+func (m *Machine) SetHardwareUUID(uuid string) (string, error) {
+	if uuid == "" {
+		var err error
+		if uuid, err = newUUID(); err != nil {
+			return "", errors.Wrap(err, "fail to generate hardware UUID")
+		}
+	}
+	if err := m.modifyOne("--hardwareuuid", uuid); err != nil {
+		return "", err
+	}
+	return uuid, nil
+}
+
+// SetRTCUseUTC sets whether the real-time clock of the machine operates in UTC (e.g. for Linux guests)
+// or in local time (e.g. for Windows guests). Unlike Modify, only --rtcuseutc is changed.
+func (m *Machine) SetRTCUseUTC(utc bool) error {
+	stdout, stderr, err := Manage().runOutErr("modifyvm", m.Name, "--rtcuseutc", bool2string(utc))
+	if err != nil {
+		return errors.Wrapf(err, "fail to set rtcuseutc: vm=%s, utc=%t, stdout=%s, stderr=%s",
+			m.Name, utc, stdout, stderr)
+	}
+	if utc {
+		m.Flag |= RTCUSEUTC
+	} else {
+		m.Flag &^= RTCUSEUTC
+	}
+	return nil
+}
+
 // AddNATPF adds a NAT port forarding rule to the n-th NIC with the given name.
+// ErrInvalidPFRule is returned, without calling VBoxManage, if the name or the rule is not valid.
 func (m *Machine) AddNATPF(n int, name string, rule PFRule) error {
-	return Manage().run("controlvm", m.Name, fmt.Sprintf("natpf%d", n),
-		fmt.Sprintf("%s,%s", name, rule.Format()))
+	arg, err := formatNamedPFRule(name, rule)
+	if err != nil {
+		return errors.Wrapf(err, "fail to add nat port forwarding: vm=%s, nic=%d", m.Name, n)
+	}
+	return Manage().run("controlvm", m.Name, fmt.Sprintf("natpf%d", n), arg)
 }
 
 // DelNATPF deletes the NAT port forwarding rule with the given name from the n-th NIC.
@@ -488,6 +875,12 @@ func (m *Machine) DelNATPF(n int, name string) error {
 	return Manage().run("controlvm", m.Name, fmt.Sprintf("natpf%d", n), "delete", name)
 }
 
+// RandomizeNICMac has VirtualBox generate a new random MAC address for the n-th NIC.
+// This is e.g. needed after cloning a VM without keeping its MAC addresses.
+func (m *Machine) RandomizeNICMac(n int) error {
+	return m.modifyOne(fmt.Sprintf("--macaddress%d", n), NICMacAddrAuto)
+}
+
 func appendNicParams(n int, nic NIC, cmdArgs *CmdArgs) error {
 	cmdArgs.Append(fmt.Sprintf("--nic%d", n), string(nic.Network))
 	cmdArgs.Append(fmt.Sprintf("--nictype%d", n), string(nic.Hardware))
@@ -543,6 +936,66 @@ func (m *Machine) SetNIC(rank int, nic NIC) error {
 	return Manage().run(args...)
 }
 
+// defaultNICSlots is the number of NICs of a machine with the default (piix3) chipset.
+const defaultNICSlots = 8
+
+// ResetNetworking recovers the NIC configuration of the powered off machine when it is in a bad state,
+// e.g. referencing a host-only adapter which has been deleted: all the NICs are detached (set to none),
+// then the given NICs are set from the first one, and the machine is refreshed.
+//
+// Errors detaching a NIC are tolerated, as they can be caused by the bad state being recovered from.
+func (m *Machine) ResetNetworking(nics ...NIC) error {
+	slots := defaultNICSlots
+	if len(m.NICs) > slots {
+		slots = len(m.NICs)
+	}
+	if len(nics) > slots {
+		slots = len(nics)
+	}
+	for n := 1; n <= slots; n++ {
+		if err := Manage().run("modifyvm", m.Name, fmt.Sprintf("--nic%d", n), string(NICNetAbsent)); err != nil {
+			Debug("fail to detach nic: vm=%s, nic=%d, err=%v", m.Name, n, err)
+		}
+	}
+	for i, nic := range nics {
+		if err := m.SetNIC(i+1, nic); err != nil {
+			return errors.Wrapf(err, "fail to reset networking: vm=%s, nic=%d", m.Name, i+1)
+		}
+	}
+	return m.Refresh()
+}
+
+// EnsureNIC sets the n-th NIC unless it is already as desired, and returns whether a change was made,
+// in which case the machine is refreshed. An empty MAC address or network name in the desired NIC matches any.
+func (m *Machine) EnsureNIC(n int, nic NIC) (bool, error) {
+	if n >= 1 && n <= len(m.NICs) && nicMatches(m.NICs[n-1], nic) {
+		return false, nil
+	}
+	if err := m.SetNIC(n, nic); err != nil {
+		return true, err
+	}
+	return true, m.Refresh()
+}
+
+// nicMatches tells whether the current NIC is as desired.
+func nicMatches(current, desired NIC) bool {
+	if current.Network != desired.Network || current.Hardware != desired.Hardware ||
+		current.HostInterface != desired.HostInterface {
+		return false
+	}
+	if desired.MacAddr != "" && desired.MacAddr != NICMacAddrAuto && desired.MacAddr != current.MacAddr {
+		return false
+	}
+	if desired.NetworkName == "" {
+		return true
+	}
+	if desired.Network == NICNetNAT && (desired.NetworkName == "default" || desired.NetworkName == "nat") {
+		// the default NAT network is reported as nat
+		return current.NetworkName == "" || current.NetworkName == "nat"
+	}
+	return desired.NetworkName == current.NetworkName
+}
+
 // AddStorageCtl adds a storage controller with the given name.
 func (m *Machine) AddStorageCtl(name string, ctl StorageController) error {
 	args := []string{"storagectl", m.Name, "--name", name}
@@ -560,6 +1013,39 @@ func (m *Machine) AddStorageCtl(name string, ctl StorageController) error {
 	return Manage().run(args...)
 }
 
+// EnsureStorageCtl adds the storage controller with the given name unless the machine already has it,
+// in which case only its port count and bootable setting are updated when they differ.
+// The machine is refreshed if a change was made.
+func (m *Machine) EnsureStorageCtl(name string, ctl StorageController) error {
+	var current *StorageController
+	for i := range m.StorageControllers {
+		if m.StorageControllers[i].Name == name {
+			current = &m.StorageControllers[i]
+			break
+		}
+	}
+	if current == nil {
+		if err := m.AddStorageCtl(name, ctl); err != nil {
+			return err
+		}
+		return m.Refresh()
+	}
+	args := []string{"storagectl", m.Name, "--name", name}
+	if ctl.Ports > 0 && ctl.Ports != current.Ports {
+		args = append(args, "--portcount", fmt.Sprintf("%d", ctl.Ports))
+	}
+	if ctl.Bootable != current.Bootable {
+		args = append(args, "--bootable", bool2string(ctl.Bootable))
+	}
+	if len(args) == 4 {
+		return nil
+	}
+	if err := Manage().run(args...); err != nil {
+		return err
+	}
+	return m.Refresh()
+}
+
 // DelStorageCtl deletes the storage controller with the given name.
 func (m *Machine) DelStorageCtl(name string) error {
 	return Manage().run("storagectl", m.Name, "--name", name, "--remove")
@@ -590,7 +1076,8 @@ func (m *Machine) SetExtraData(key, val string) error {
 	return Manage().run("setextradata", m.Name, key, val)
 }
 
-// GetExtraData retrieves custom string from the VM.
+// GetExtraData retrieves custom string from the VM, with leading and trailing white space removed.
+// Use GetExtraDataRaw to get values in which white space matters.
 func (m *Machine) GetExtraData(key string) (*string, error) {
 	value, err := Manage().runOut("getextradata", m.Name, key)
 	if err != nil {
@@ -606,11 +1093,52 @@ func (m *Machine) GetExtraData(key string) (*string, error) {
 	return &trimmed, nil
 }
 
+// GetExtraDataRaw retrieves custom string from the VM exactly as it has been set.
+// nil is returned if the key is not set.
+func (m *Machine) GetExtraDataRaw(key string) (*string, error) {
+	out, err := Manage().runOut("getextradata", m.Name, key)
+	if err != nil {
+		return nil, err
+	}
+	// e.g. "Value:   indented\n", the output ends with the single newline printed after the value
+	if !strings.HasPrefix(out, "Value: ") {
+		// 'getextradata' returns 0 even when the key is not found, printing "No value set!"
+		return nil, nil
+	}
+	value := strings.TrimPrefix(out, "Value: ")
+	if strings.HasSuffix(value, "\r\n") {
+		value = strings.TrimSuffix(value, "\r\n")
+	} else {
+		value = strings.TrimSuffix(value, "\n")
+	}
+	return &value, nil
+}
+
 // DeleteExtraData removes custom string from the VM.
 func (m *Machine) DeleteExtraData(key string) error {
 	return Manage().run("setextradata", m.Name, key)
 }
 
+// Rename renames the machine, which must be powered off (or aborted), otherwise ErrMachineNotPoweredOff
+// is returned. ErrMachineExist is returned if a machine with the new name already exists.
+// VirtualBox also renames the settings file and folder of the machine if they are named after it.
+func (m *Machine) Rename(newName string) error {
+	if m.State != Poweroff && m.State != Aborted {
+		return errors.Wrapf(ErrMachineNotPoweredOff, "fail to rename vm=%s, state=%s", m.Name, m.State)
+	}
+	if _, err := GetMachine(newName); err == nil {
+		return errors.Wrapf(ErrMachineExist, "fail to rename vm=%s, name=%s", m.Name, newName)
+	} else if err != ErrMachineNotExist {
+		return err
+	}
+	stdout, stderr, err := Manage().runOutErr("modifyvm", m.Name, "--name", newName)
+	if err != nil {
+		return errors.Wrapf(err, "fail to rename vm=%s, name=%s, stdout=%s, stderr=%s", m.Name, newName, stdout, stderr)
+	}
+	m.Name = newName
+	return m.Refresh()
+}
+
 // CloneMachine clones the given machine name into a new one.
 func CloneMachine(baseImageName string, newImageName string, register bool) error {
 	if register {
@@ -618,3 +1146,39 @@ func CloneMachine(baseImageName string, newImageName string, register bool) erro
 	}
 	return Manage().run("clonevm", baseImageName, "--name", newImageName)
 }
+
+// DiskUsage returns the logical and actual sizes (in bytes) of the disks attached to the machine.
+// The actual size includes all the differencing images of the snapshots of the machine in the trees of
+// the attached disks, each image being counted once even if shared by several attachments.
+// DVD and floppy images, as reported by VirtualBox, and empty drives are ignored.
+func (m *Machine) DiskUsage() (logical, actual uint64, err error) {
+	counted := map[string]bool{}
+	for _, medium := range m.StorageControllers.DeviceMedia() {
+		if !isAttachedMedium(medium) {
+			continue
+		}
+		md, err := diskMediumInfo(medium)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "fail to compute disk usage: vm=%s, medium=%s", m.Name, medium)
+		}
+		if md == nil || counted[md.UUID] {
+			continue
+		}
+		logical += md.CapacityMB << 20
+		chain, err := mediumChainFrom(md)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "fail to compute disk usage: vm=%s, medium=%s", m.Name, medium)
+		}
+		tree, err := machineMediumTree(chain[len(chain)-1], m.Name)
+		if err != nil {
+			return 0, 0, errors.Wrapf(err, "fail to compute disk usage: vm=%s, medium=%s", m.Name, medium)
+		}
+		for _, md := range append(chain, tree...) {
+			if !counted[md.UUID] {
+				counted[md.UUID] = true
+				actual += md.SizeMB << 20
+			}
+		}
+	}
+	return logical, actual, nil
+}
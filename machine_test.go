@@ -1,9 +1,16 @@
 package virtualbox
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
 )
 
 func TestMachine(t *testing.T) {
@@ -28,3 +35,794 @@ func TestMachine(t *testing.T) {
 
 	Teardown()
 }
+
+// expectShowVMInfo expects the machine info of vm to be requested once and answers with vmInfoOut.
+func expectShowVMInfo(vm string, vmInfoOut string) *gomock.Call {
+	return ManageMock.EXPECT().runOutErr("showvminfo", vm, "--machinereadable").Return(vmInfoOut, "", nil).Times(1)
+}
+
+// expectModifyVM expects a <VBoxManage modifyvm vm ...> to be run followed by a refresh answered with vmInfoOut.
+// The returned slice pointer holds the modifyvm args once the call has been made.
+func expectModifyVM(vm string, vmInfoOut string) *[]string {
+	var modifyArgs []string
+	gomock.InOrder(
+		ManageMock.EXPECT().runOutErr(gomock.Any()).DoAndReturn(func(args ...string) (string, string, error) {
+			modifyArgs = args
+			return "", "", nil
+		}).Times(1),
+		expectShowVMInfo(vm, vmInfoOut),
+	)
+	return &modifyArgs
+}
+
+// argValue returns the value following the given key in args, and whether the key was found.
+func argValue(args []string, key string) (string, bool) {
+	for i, arg := range args {
+		if arg == key && i+1 < len(args) {
+			return args[i+1], true
+		}
+	}
+	return "", false
+}
+
+// testMachine returns the machine a test runs against: go-virtualbox read from vmInfoOut when VBoxManage is mocked,
+// otherwise TEST_VM, the test being skipped unless it is in one of the given states (any if none).
+func testMachine(t *testing.T, vmInfoOut string, states ...MachineState) *Machine {
+	t.Helper()
+	vm := VM
+	if ManageMock != nil {
+		vm = "go-virtualbox"
+		expectShowVMInfo(vm, vmInfoOut)
+	}
+	m, err := GetMachine(vm)
+	require.NoError(t, err)
+	if ManageMock == nil && len(states) > 0 && !slices.Contains(states, m.State) {
+		t.Skipf("requires %s to be in one of the states %v, got %s", m.Name, states, m.State)
+	}
+	return m
+}
+
+// skipBeforeVirtualBox skips the test when the real VirtualBox is older than the given version.
+func skipBeforeVirtualBox(t *testing.T, major, minor, patch int) {
+	t.Helper()
+	v, err := VersionInfo()
+	require.NoError(t, err)
+	if !v.AtLeast(major, minor, patch) {
+		t.Skipf("requires VirtualBox %d.%d.%d or later, got %s", major, minor, patch, v)
+	}
+}
+
+// restoreOnCleanup runs a modifyvm of the real machine m with the given args once the test is done,
+// so that a test only restores the settings it changes.
+func restoreOnCleanup(t *testing.T, m *Machine, args ...CmdArg) {
+	t.Helper()
+	cmdArgs := CmdArgs{}
+	cmdArgs.AppendCmdArgs(args...)
+	name := m.Name
+	t.Cleanup(func() {
+		if _, stderr, err := Manage().runOutErr(append([]string{"modifyvm", name}, cmdArgs.Args()...)...); err != nil {
+			t.Errorf("fail to restore the settings of %s: %v: %s", name, err, stderr)
+		}
+	})
+}
+
+// nicRestoreArgs returns the modifyvm args restoring the NIC n of the real machine m as it is now.
+func nicRestoreArgs(t *testing.T, m *Machine, n int) []CmdArg {
+	t.Helper()
+	if n > len(m.NICs) {
+		return []CmdArg{NewCmdArg(fmt.Sprintf("--nic%d", n), string(NICNetAbsent))}
+	}
+	cmdArgs := CmdArgs{}
+	require.NoError(t, appendNicParams(n, m.NICs[n-1], &cmdArgs))
+	return cmdArgs.args
+}
+
+func TestMachineProcessPriority(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out") + "\nvmprocpriority=\"low\"\n"
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		require.Equal(t, "low", m.ProcessPriority)
+		vmInfoOut = strings.Replace(vmInfoOut, `vmprocpriority="low"`, `vmprocpriority="high"`, 1)
+		modifyArgs = expectModifyVM("go-virtualbox", vmInfoOut)
+	} else {
+		skipBeforeVirtualBox(t, 7, 0, 0)
+		restoreOnCleanup(t, m, NewCmdArg("--vm-process-priority", m.ProcessPriority))
+	}
+
+	m.ProcessPriority = "high"
+	require.NoError(t, m.Modify())
+	require.Equal(t, "high", m.ProcessPriority, "read back from the VM info")
+	if ManageMock != nil {
+		priority, ok := argValue(*modifyArgs, "--vm-process-priority")
+		require.Truef(t, ok && priority == "high", "modifyvm should set the process priority: args=%v", *modifyArgs)
+		modifyArgs = expectModifyVM("go-virtualbox", vmInfoOut)
+	}
+
+	m.ProcessPriority = ""
+	require.NoError(t, m.Modify())
+	require.Equal(t, "high", m.ProcessPriority, "the process priority should be kept when not set")
+	if ManageMock != nil {
+		_, ok := argValue(*modifyArgs, "--vm-process-priority")
+		require.Falsef(t, ok, "modifyvm should not touch the process priority when not set: args=%v", *modifyArgs)
+	}
+}
+
+func TestModifyOverridesCanDeleteDefaults(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := New()
+	m.Name = "go-virtualbox"
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		modifyArgs = expectModifyVM("go-virtualbox", strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"),
+			`firmware="BIOS"`, `firmware="EFI"`, 1))
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		vmInfoOut, _, err := Manage().runOutErr("showvminfo", m.Name, "--machinereadable")
+		require.NoError(t, err)
+		propMap, err := vminfoAsPropMap(strings.NewReader(vmInfoOut))
+		require.NoError(t, err)
+		restoreOnCleanup(t, m, append(NewCmdArgsBootOrder(propMap["boot1"], propMap["boot2"], propMap["boot3"], propMap["boot4"]),
+			NewCmdArg("--firmware", m.Firmware))...)
+	}
+	m.BootOrder = []string{"disk", "dvd"}
+
+	overrides := append(NewCmdArgsBootOrder("net"),
+		NewCmdArgDeleted("--biosbootmenu"), NewCmdArg("--firmware", "efi"))
+	require.NoError(t, m.Modify(overrides...))
+	require.Equal(t, "efi", m.Firmware, "read back from the VM info")
+
+	if ManageMock != nil {
+		_, ok := argValue(*modifyArgs, "--biosbootmenu")
+		require.Falsef(t, ok, "--biosbootmenu should have been deleted: args=%v", *modifyArgs)
+		for key, expected := range map[string]string{
+			"--firmware": "efi", "--boot1": "net", "--boot2": "none", "--boot3": "none", "--boot4": "none",
+		} {
+			actual, _ := argValue(*modifyArgs, key)
+			require.Equalf(t, expected, actual, "unexpected value for %s: args=%v", key, *modifyArgs)
+		}
+	}
+}
+
+func TestSetNICAndModifyRenderSameNICParams(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	nic := NIC{Network: NICNetHostonly, Hardware: VirtIO, HostInterface: "vboxnet0", MacAddr: "080027EE1DF7"}
+	expectedNICParams := []string{
+		"--nic1", "hostonly", "--nictype1", "virtio", "--cableconnected1", "on",
+		"--macaddress1", "080027EE1DF7", "--hostonlyadapter1", "vboxnet0",
+	}
+	m := New()
+	m.Name = "go-virtualbox"
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		ManageMock.EXPECT().run(append([]interface{}{"modifyvm", "go-virtualbox"}, toInterfaces(expectedNICParams)...)...).
+			Return(nil).Times(1)
+		modifyArgs = expectModifyVM("go-virtualbox", strings.NewReplacer(
+			`nic1="nat"`, `nic1="hostonly"`,
+			`nictype1="82540EM"`, `nictype1="virtio"`+"\n"+`hostonlyadapter1="vboxnet0"`,
+		).Replace(ReadTestData("vboxmanage-showvminfo-1.out")))
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		restoreOnCleanup(t, m, nicRestoreArgs(t, m, 1)...)
+	}
+
+	require.NoError(t, m.SetNIC(1, nic))
+
+	m.NICs = []NIC{nic}
+	require.NoError(t, m.Modify())
+	require.NotEmpty(t, m.NICs)
+	require.Equal(t, nic, m.NICs[0], "read back from the VM info")
+	if ManageMock != nil {
+		i := indexOf(*modifyArgs, "--nic1")
+		require.Truef(t, i >= 0 && i+len(expectedNICParams) <= len(*modifyArgs), "--nic1 expected in %v", *modifyArgs)
+		require.Equal(t, expectedNICParams, (*modifyArgs)[i:i+len(expectedNICParams)])
+	}
+}
+
+func toInterfaces(strs []string) []interface{} {
+	is := make([]interface{}, 0, len(strs))
+	for _, s := range strs {
+		is = append(is, s)
+	}
+	return is
+}
+
+func indexOf(strs []string, str string) int {
+	for i, s := range strs {
+		if s == str {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestRandomizeNICMac(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	require.NotEmpty(t, m.NICs)
+	mac := m.NICs[0].MacAddr
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--macaddress1", "auto").Return("", "", nil).Times(1),
+			expectShowVMInfo("go-virtualbox", strings.Replace(vmInfoOut, mac, "0800279A3C5E", 1)),
+		)
+	} else {
+		restoreOnCleanup(t, m, NewCmdArg("--macaddress1", mac))
+	}
+
+	require.NoError(t, m.RandomizeNICMac(1))
+	require.NotEmpty(t, m.NICs, "machine should have been refreshed")
+	require.NotEqual(t, mac, m.NICs[0].MacAddr)
+}
+
+func TestListMachinesDetailed(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("list", "vms", "--long").
+			Return(ReadTestData("vboxmanage-list-vms-long-1.out"), "", nil).Times(1)
+	}
+
+	ms, err := ListMachinesDetailed()
+	require.NoError(t, err)
+	for _, m := range ms {
+		require.NotEmpty(t, m.Name)
+		require.NotEmpty(t, m.UUID)
+		require.Equal(t, filepath.Dir(m.CfgFile), m.BaseFolder)
+	}
+	if ManageMock == nil {
+		require.NotEqualf(t, -1, slices.IndexFunc(ms, func(m *Machine) bool { return m.Name == VM }), "%s should be listed", VM)
+		return
+	}
+	require.Len(t, ms, 2)
+
+	ubuntu := ms[0]
+	require.Equal(t, "Ubuntu", ubuntu.Name)
+	require.Equal(t, "2e16b1fc-675d-4a7a-a9a1-e89a8bde7874", ubuntu.UUID)
+	require.Equal(t, Poweroff, ubuntu.State)
+	require.Equal(t, uint(2), ubuntu.CPUs)
+	require.Equal(t, uint(2048), ubuntu.Memory)
+	require.Equal(t, uint(16), ubuntu.VRAM)
+	require.Equal(t, "/Users/fix/VirtualBox VMs/Ubuntu/Ubuntu.vbox", ubuntu.CfgFile)
+	require.Equal(t, "/Users/fix/VirtualBox VMs/Ubuntu", ubuntu.BaseFolder)
+	require.Equal(t,
+		[]NIC{
+			{Network: NICNetNAT, Hardware: IntelPro1000MTDesktop, MacAddr: "080027A1B2C3"},
+			{Network: NICNetHostonly, Hardware: VirtIO, MacAddr: "080027D4E5F6", HostInterface: "vboxnet0"},
+		},
+		ubuntu.NICs)
+
+	gvb := ms[1]
+	require.Equal(t, "go-virtualbox", gvb.Name)
+	require.Equal(t, Running, gvb.State)
+	require.Equal(t,
+		[]NIC{{Network: NICNetBridged, Hardware: IntelPro1000MTDesktop, MacAddr: "080027EE1DF7",
+			HostInterface: "en0: Wi-Fi (AirPort)"}},
+		gvb.NICs)
+}
+
+func TestMachineSnapshotFolder(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	folder := "/media/fast/go-virtualbox-snapshots"
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		require.Equal(t, "/Users/fix/VirtualBox VMs/go-virtualbox/Snapshots", m.SnapshotFolder)
+		modifyArgs = expectModifyVM("go-virtualbox", strings.Replace(vmInfoOut,
+			"/Users/fix/VirtualBox VMs/go-virtualbox/Snapshots", folder, 1))
+	} else {
+		if m.CurrentSnapshot != "" {
+			t.Skipf("requires %s without snapshots to move the snapshot folder", m.Name)
+		}
+		restoreOnCleanup(t, m, NewCmdArg("--snapshotfolder", m.SnapshotFolder))
+		folder = t.TempDir()
+	}
+
+	m.SnapshotFolder = folder
+	require.NoError(t, m.Modify())
+	require.Equal(t, folder, m.SnapshotFolder, "read back from the VM info")
+	if ManageMock != nil {
+		snapshotFolder, _ := argValue(*modifyArgs, "--snapshotfolder")
+		require.Equal(t, folder, snapshotFolder)
+	}
+}
+
+func TestResume(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", State: Saved}
+	require.ErrorIs(t, m.Resume(), ErrMachineNotPaused)
+
+	if ManageMock != nil {
+		m.State = Paused
+		ManageMock.EXPECT().run("controlvm", "go-virtualbox", "resume").Return(nil).Times(1)
+	} else {
+		m = testMachine(t, "", Running)
+		require.NoError(t, m.Pause())
+		require.NoError(t, m.Refresh())
+		require.Equal(t, Paused, m.State)
+	}
+	require.NoError(t, m.Resume())
+	if ManageMock == nil {
+		require.NoError(t, m.Refresh())
+		require.Equal(t, Running, m.State)
+	}
+}
+
+func TestCPUProfile(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("list", "cpu-profiles").
+			Return(ReadTestData("vboxmanage-list-cpu-profiles-1.out"), "", nil).Times(1)
+	}
+	profiles, err := CPUProfiles()
+	require.NoError(t, err)
+	require.NotEmpty(t, profiles)
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out") + "cpu-profile=\"host\"\n"
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	profile := profiles[0]
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		require.Equal(t, []string{"Intel 8086", "Intel 80286", "Intel Core i7-6700K", "AMD Ryzen 7 1800X"}, profiles)
+		require.Equal(t, "host", m.CPUProfile)
+		profile = profiles[2]
+		modifyArgs = expectModifyVM("go-virtualbox", strings.Replace(vmInfoOut,
+			`cpu-profile="host"`, `cpu-profile="`+profile+`"`, 1))
+	} else {
+		restoreOnCleanup(t, m, NewCmdArg("--cpu-profile", m.CPUProfile))
+	}
+
+	m.CPUProfile = profile
+	require.NoError(t, m.Modify())
+	require.Equal(t, profile, m.CPUProfile, "read back from the VM info")
+	if ManageMock != nil {
+		cpuProfile, _ := argValue(*modifyArgs, "--cpu-profile")
+		require.Equal(t, "Intel Core i7-6700K", cpuProfile)
+	}
+}
+
+func TestSingleSettingSetters(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock == nil {
+		m = testMachine(t, "", Poweroff, Aborted)
+		restoreOnCleanup(t, m, NewCmdArg("--memory", fmt.Sprint(m.Memory)), NewCmdArg("--cpus", fmt.Sprint(m.CPUs)),
+			NewCmdArg("--vram", fmt.Sprint(m.VRAM)))
+	}
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	for _, tc := range []struct {
+		set     func() error
+		args    []string
+		setting string
+		get     func() uint
+	}{
+		{func() error { return m.SetMemory(2048) }, []string{"--memory", "2048"}, "memory=1024", func() uint { return m.Memory }},
+		{func() error { return m.SetCPUs(2) }, []string{"--cpus", "2"}, "cpus=1", func() uint { return m.CPUs }},
+		{func() error { return m.SetVRAM(16) }, []string{"--vram", "16"}, "vram=8", func() uint { return m.VRAM }},
+	} {
+		if ManageMock != nil {
+			vmInfoOut = strings.Replace(vmInfoOut, tc.setting, tc.args[0][2:]+"="+tc.args[1], 1)
+			gomock.InOrder(
+				ManageMock.EXPECT().runOutErr(toInterfaces(append([]string{"modifyvm", "go-virtualbox"}, tc.args...))...).
+					Return("", "", nil).Times(1),
+				expectShowVMInfo("go-virtualbox", vmInfoOut),
+			)
+		}
+		require.NoError(t, tc.set())
+		require.Equalf(t, tc.args[1], fmt.Sprint(tc.get()), "machine should have been refreshed after %v", tc.args)
+	}
+}
+
+func TestEnsureNIC(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	require.NotEmpty(t, m.NICs)
+	current := NIC{Network: NICNetNAT, Hardware: IntelPro1000MTDesktop, NetworkName: "default"}
+	n := len(m.NICs) + 1
+	hostonly := NIC{Network: NICNetHostonly, Hardware: VirtIO, HostInterface: "vboxnet0"}
+	var setArgs []string
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().run(gomock.Any()).DoAndReturn(func(args ...string) error {
+				setArgs = args
+				return nil
+			}).Times(1),
+			expectShowVMInfo("go-virtualbox", strings.Replace(vmInfoOut, `nic2="none"`,
+				`nic2="hostonly"`+"\n"+`nictype2="virtio"`+"\n"+`macaddress2="080027C1B7A5"`+"\n"+`hostonlyadapter2="vboxnet0"`, 1)),
+		)
+	} else {
+		if n > 4 {
+			t.Skipf("requires %s to have a NIC slot left", m.Name)
+		}
+		current = m.NICs[0]
+		restoreOnCleanup(t, m, nicRestoreArgs(t, m, n)...)
+	}
+
+	changed, err := m.EnsureNIC(1, current)
+	require.NoError(t, err)
+	require.False(t, changed, "NIC 1 is already as desired")
+
+	changed, err = m.EnsureNIC(n, hostonly)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Len(t, m.NICs, n, "machine should have been refreshed")
+	require.True(t, nicMatches(m.NICs[n-1], hostonly), "read back from the VM info")
+	if ManageMock != nil {
+		adapter, _ := argValue(setArgs, "--hostonlyadapter2")
+		require.Equal(t, "vboxnet0", adapter)
+	}
+
+	changed, err = m.EnsureNIC(n, hostonly)
+	require.NoError(t, err)
+	require.False(t, changed, "NIC should be kept once as desired")
+}
+
+func TestGetMachineReadsAllSettings(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-full-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	require.Contains(t, []string{"bios", "efi", "efi32", "efi64"}, m.Firmware)
+	require.NotEmpty(t, m.Chipset)
+	require.NotEmpty(t, m.GraphicsController)
+	require.NotEmpty(t, m.ParavirtConfigured)
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		require.Equal(t, "efi", m.Firmware)
+		require.Equal(t, "ich9", m.Chipset)
+		require.Equal(t, "vmsvga", m.GraphicsController)
+		require.Equal(t, "kvm", m.ParavirtConfigured)
+		require.Equal(t, "kvm", m.ParavirtEffective)
+		require.Equal(t, "pulse", m.Audio)
+		require.True(t, m.VRDE)
+		require.Equal(t, "bidirectional", m.Clipboard)
+		require.Equal(t, "Ubuntu_64", m.OSType)
+		require.Equal(t, []string{"disk", "dvd", "none", "none"}, m.BootOrder)
+
+		notInVMInfo := map[string]bool{
+			"ProcessPriority": true, // VirtualBox 7+
+			"DMI":             true, // kept in the extradata
+		}
+		v := reflect.ValueOf(*m)
+		for i := 0; i < v.NumField(); i++ {
+			name := v.Type().Field(i).Name
+			if notInVMInfo[name] {
+				continue
+			}
+			require.Falsef(t, v.Field(i).IsZero(), "Machine.%s should have been read from the VM info", name)
+		}
+		modifyArgs = expectModifyVM("go-virtualbox", vmInfoOut)
+	}
+
+	read := *m
+	require.NoError(t, m.Modify())
+	require.Equal(t, read.Firmware, m.Firmware, "modify should keep the firmware read from the VM info")
+	require.Equal(t, read.Chipset, m.Chipset)
+	require.Equal(t, read.GraphicsController, m.GraphicsController)
+	require.Equal(t, read.ParavirtConfigured, m.ParavirtConfigured)
+	if ManageMock != nil {
+		firmware, _ := argValue(*modifyArgs, "--firmware")
+		require.Equal(t, "efi", firmware)
+	}
+}
+
+func TestGetExtraDataRaw(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	key, missing := "payload", "missing"
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOut("getextradata", "go-virtualbox", "payload").Return("Value: Value:  {\"a\": 1}  \n", nil).Times(1)
+		ManageMock.EXPECT().runOut("getextradata", "go-virtualbox", "missing").Return("No value set!\n", nil).Times(1)
+	} else {
+		m = testMachine(t, "")
+		key, missing = "go-virtualbox/test/payload", "go-virtualbox/test/missing"
+		require.NoError(t, m.SetExtraData(key, "Value:  {\"a\": 1}  "))
+		t.Cleanup(func() { require.NoError(t, m.DeleteExtraData(key)) })
+	}
+
+	value, err := m.GetExtraDataRaw(key)
+	require.NoError(t, err)
+	require.NotNil(t, value)
+	require.Equal(t, "Value:  {\"a\": 1}  ", *value)
+
+	value, err = m.GetExtraDataRaw(missing)
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestSetHardwareUUID(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	previous := m.HardwareUUID
+	var setUUID string
+	var modifyArgs []string
+	if ManageMock != nil {
+		withHardwareUUID := func(args ...string) (string, string, error) {
+			return strings.Replace(vmInfoOut, `hardwareuuid="`+previous+`"`, `hardwareuuid="`+setUUID+`"`, 1), "", nil
+		}
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--hardwareuuid", gomock.Any()).
+				DoAndReturn(func(args ...string) (string, string, error) {
+					setUUID = args[3]
+					return "", "", nil
+				}).Times(1),
+			ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").DoAndReturn(withHardwareUUID).Times(1),
+			ManageMock.EXPECT().runOutErr(gomock.Any()).DoAndReturn(func(args ...string) (string, string, error) {
+				modifyArgs = args
+				return "", "", nil
+			}).Times(1),
+			ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").DoAndReturn(withHardwareUUID).Times(1),
+		)
+	} else {
+		restoreOnCleanup(t, m, NewCmdArg("--hardwareuuid", previous))
+	}
+
+	uuid, err := m.SetHardwareUUID("")
+	require.NoError(t, err)
+	require.NotEqual(t, previous, uuid)
+	require.Equal(t, uuid, m.HardwareUUID, "read back from the VM info")
+	if ManageMock != nil {
+		require.Equal(t, setUUID, uuid)
+	}
+
+	require.NoError(t, m.Modify())
+	require.Equal(t, uuid, m.HardwareUUID, "modify should keep the hardware UUID")
+	if ManageMock != nil {
+		hardwareUUID, _ := argValue(modifyArgs, "--hardwareuuid")
+		require.Equal(t, uuid, hardwareUUID)
+	}
+}
+
+func TestGetMachineParavirtDefault(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := testMachine(t, ReadTestData("vboxmanage-showvminfo-1.out"))
+	if ManageMock == nil && m.ParavirtConfigured != "default" {
+		t.Skipf("requires %s to use the default paravirtualization provider, got %s", m.Name, m.ParavirtConfigured)
+	}
+	require.Equal(t, "default", m.ParavirtConfigured)
+	require.NotEmpty(t, m.ParavirtEffective)
+	require.NotEqual(t, "default", m.ParavirtEffective, "the effective provider should be resolved")
+	if ManageMock != nil {
+		require.Equal(t, "kvm", m.ParavirtEffective)
+	}
+}
+
+func TestResetNetworking(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		calls := []*gomock.Call{}
+		for n := 1; n <= 8; n++ {
+			var err error
+			if n == 2 {
+				err = errors.New("Could not find a host-only adapter named vboxnet3")
+			}
+			calls = append(calls, ManageMock.EXPECT().run("modifyvm", "go-virtualbox", fmt.Sprintf("--nic%d", n), "none").
+				Return(err).Times(1))
+		}
+		calls = append(calls,
+			ManageMock.EXPECT().run("modifyvm", "go-virtualbox", "--nic1", "nat", "--nictype1", "virtio",
+				"--cableconnected1", "on", "--natnet1", "default").Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"),
+				`nictype1="82540EM"`, `nictype1="virtio"`, 1)),
+		)
+		gomock.InOrder(calls...)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		var args []CmdArg
+		for n := 1; n <= 4; n++ {
+			args = append(args, nicRestoreArgs(t, m, n)...)
+		}
+		restoreOnCleanup(t, m, args...)
+	}
+
+	require.NoError(t, m.ResetNetworking(NIC{Network: NICNetNAT, Hardware: VirtIO}))
+	require.Len(t, m.NICs, 1, "machine should have been refreshed")
+	require.Equal(t, NICNetNAT, m.NICs[0].Network)
+	require.Equal(t, VirtIO, m.NICs[0].Hardware)
+}
+
+func TestGetMachines(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	names := []string{"go-virtualbox", "gone", "go-virtualbox", "locked"}
+	if ManageMock != nil {
+		gomock.InOrder(
+			expectShowVMInfo("go-virtualbox", ReadTestData("vboxmanage-showvminfo-1.out")),
+			ManageMock.EXPECT().runOutErr("showvminfo", "gone", "--machinereadable").
+				Return("", "VBoxManage: error: Could not find a registered machine named 'gone'", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().runOutErr("showvminfo", "locked", "--machinereadable").
+				Return("", "VBoxManage: error: The object is not ready (E_ACCESSDENIED)", errors.New("exit status 1")).Times(1),
+		)
+	} else {
+		names = []string{VM, "go-virtualbox-gone", VM}
+	}
+
+	ms, errs := GetMachines(names)
+	require.Len(t, ms, 1)
+	require.Equal(t, names[0], ms[names[0]].Name)
+	require.NotEmpty(t, errs)
+	require.ErrorIs(t, errs[0], ErrMachineNotExist)
+	require.Contains(t, errs[0].Error(), "id="+names[1])
+	if ManageMock != nil {
+		require.Len(t, errs, 2)
+		require.Contains(t, errs[1].Error(), "id=locked")
+	}
+}
+
+func TestUSBCardReader(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		modifyArgs = expectModifyVM("go-virtualbox", ReadTestData("vboxmanage-showvminfo-1.out"))
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		enabled := m.USBCardReader != nil && *m.USBCardReader
+		restoreOnCleanup(t, m, NewCmdArg("--usbcardreader", bool2string(enabled)))
+	}
+	cardReader := m.USBCardReader
+	require.NoError(t, m.Modify())
+	require.Equal(t, cardReader, m.USBCardReader, "card reader should be kept when nil")
+	if ManageMock != nil {
+		_, found := argValue(*modifyArgs, "--usbcardreader")
+		require.False(t, found, "card reader should be kept when nil")
+	}
+
+	enabled := true
+	m.USBCardReader = &enabled
+	if ManageMock != nil {
+		modifyArgs = expectModifyVM("go-virtualbox", strings.Replace(ReadTestData("vboxmanage-showvminfo-full-1.out"),
+			`usbcardreader="off"`, `usbcardreader="on"`, 1))
+	}
+	require.NoError(t, m.Modify())
+	require.NotNil(t, m.USBCardReader)
+	require.True(t, *m.USBCardReader, "read back from the VM info")
+	if ManageMock != nil {
+		value, _ := argValue(*modifyArgs, "--usbcardreader")
+		require.Equal(t, "on", value)
+
+		ManageMock.EXPECT().runOutErr("modifyvm", m.Name, "--usbrename", "OHCI", "USB 1.1").Return("", "", nil).Times(1)
+		require.NoError(t, m.RenameUSBController("OHCI", "USB 1.1"))
+	}
+}
+
+func TestRename(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "template-wip", State: Poweroff}
+	newName, existing := "worker", "go-virtualbox"
+	if ManageMock != nil {
+		renamedInfo := strings.Replace(vmInfoWithState(Poweroff, "0"), `name="go-virtualbox"`, `name="worker"`, 1)
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("showvminfo", "worker", "--machinereadable").
+				Return("", "VBoxManage: error: Could not find a registered machine named 'worker'", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().runOutErr("modifyvm", "template-wip", "--name", "worker").Return("", "", nil).Times(1),
+			expectShowVMInfo("worker", renamedInfo),
+			expectShowVMInfo("go-virtualbox", ReadTestData("vboxmanage-showvminfo-1.out")),
+		)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		name := m.Name
+		newName = name + "-renamed"
+		// the machine itself once renamed
+		existing = newName
+		t.Cleanup(func() { require.NoError(t, m.Rename(name)) })
+	}
+
+	require.NoError(t, m.Rename(newName))
+	require.Equal(t, newName, m.Name)
+	require.ErrorIs(t, m.Rename(existing), ErrMachineExist)
+
+	running := *m
+	running.State = Running
+	require.ErrorIs(t, running.Rename("worker"), ErrMachineNotPoweredOff)
+}
+
+func TestCreateMachineWith(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	cfg := CreateConfig{
+		Name:      "go-virtualbox",
+		OSType:    "Debian_64",
+		Memory:    2048,
+		CPUs:      2,
+		Overrides: []CmdArg{NewCmdArg("--cpus", "4"), NewCmdArg("--vram", "32")},
+	}
+	deleteOnCleanup := func(name string) {
+		t.Cleanup(func() {
+			if _, err := GetMachine(name); err == nil {
+				require.NoError(t, Manage().run("unregistervm", name, "--delete"))
+			}
+		})
+	}
+	expectCreate := func() *gomock.Call {
+		return ManageMock.EXPECT().run("createvm", "--uuid", gomock.Any(), "--name", "go-virtualbox", "--register").
+			Return(nil).Times(1)
+	}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOut("list", "vms").Return("", nil).Times(1),
+			expectCreate(),
+			expectShowVMInfo("go-virtualbox", ReadTestData("vboxmanage-showvminfo-1.out")),
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--ostype", "Debian_64", "--memory", "2048",
+				"--cpus", "4", "--vram", "32").Return("", "", nil).Times(1),
+			expectShowVMInfo("go-virtualbox", strings.NewReplacer("memory=1024", "memory=2048", "cpus=1", "cpus=4",
+				"vram=8", "vram=32", `ostype="Ubuntu (64-bit)"`, `ostype="Debian (64-bit)"`).
+				Replace(ReadTestData("vboxmanage-showvminfo-1.out"))),
+		)
+	} else {
+		cfg.Name = "go-virtualbox-test-create"
+		deleteOnCleanup(cfg.Name)
+	}
+	m, err := CreateMachineWith(cfg)
+	require.NoError(t, err)
+	require.Equal(t, cfg.Name, m.Name)
+	require.Equal(t, "Debian_64", m.OSType, "read back from the VM info")
+	require.Equal(t, uint(2048), m.Memory)
+	require.Equal(t, uint(4), m.CPUs, "overrides should take precedence")
+	require.Equal(t, uint(32), m.VRAM)
+
+	// rolled back when the initial settings cannot be applied
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOut("list", "vms").Return("", nil).Times(1),
+			expectCreate(),
+			expectShowVMInfo("go-virtualbox", ReadTestData("vboxmanage-showvminfo-1.out")),
+			ManageMock.EXPECT().runOutErr(gomock.Any()).
+				Return("", "VBoxManage: error: Invalid OS type 'Ubuntu_65'", errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().run("unregistervm", "go-virtualbox", "--delete").Return(nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+				Return("", "VBoxManage: error: Could not find a registered machine named 'go-virtualbox'", errors.New("exit status 1")).Times(1),
+		)
+	} else {
+		cfg.Name += "-rollback"
+		deleteOnCleanup(cfg.Name)
+	}
+	cfg.OSType = "Ubuntu_65"
+	_, err = CreateMachineWith(cfg)
+	require.Error(t, err)
+	if ManageMock != nil {
+		require.Contains(t, err.Error(), "Invalid OS type")
+	}
+	_, err = GetMachine(cfg.Name)
+	require.ErrorIs(t, err, ErrMachineNotExist, "the machine should have been deleted")
+}
@@ -1,6 +1,15 @@
 package virtualbox
 
-import "github.com/pkg/errors"
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
 
 func UnregisterDisk(idOrFn string) error {
 	stdout, stderr, err := Manage().runOutErr("closemedium", "disk", idOrFn)
@@ -19,3 +28,278 @@ func UnregisterDvd(idOrFn string) error {
 	}
 	return nil
 }
+
+var (
+	reMediumInfoLine  = regexp.MustCompile(`^([^:\s][^:]*):\s*(.*)$`)
+	reMediumInUseByVM = regexp.MustCompile(`^(.+?) \(UUID: `)
+	// e.g. [base (UUID: 1bd0f0a6-a0ab-4e36-b7a5-5ea0a3a2d58f)]
+	reMediumInUseBySnapshot = regexp.MustCompile(`\[[^\]]*?\(UUID: ([^)]+)\)\]`)
+)
+
+// ErrMediumInUse is returned when an operation requires a medium not to be attached to any VM.
+var ErrMediumInUse = errors.New("medium is attached to a VM")
+
+// MediumType is the type of medium, as set with <VBoxManage modifymedium --type>.
+type MediumType string
+
+const (
+	// MediumTypeNormal is for a medium with differencing images created on snapshots.
+	MediumTypeNormal = MediumType("normal")
+	// MediumTypeWritethrough is for a medium not affected by snapshots.
+	MediumTypeWritethrough = MediumType("writethrough")
+	// MediumTypeImmutable is for a read-only medium whose differencing image is reset on each VM start.
+	MediumTypeImmutable = MediumType("immutable")
+	// MediumTypeShareable is for a medium which can be attached to several running VMs at once.
+	MediumTypeShareable = MediumType("shareable")
+	// MediumTypeReadonly is for a medium which can only be read, e.g. a DVD image.
+	MediumTypeReadonly = MediumType("readonly")
+	// MediumTypeMultiattach is for a read-only base medium shared by several VMs, each with its own differencing image.
+	MediumTypeMultiattach = MediumType("multiattach")
+)
+
+// Medium holds the information of a registered medium as reported by <VBoxManage showmediuminfo>.
+type Medium struct {
+	UUID       string
+	ParentUUID string // empty for a base medium
+	State      string
+	Type       string // e.g. normal (base), normal (differencing), immutable, multiattach
+	Location   string
+	Format     string // e.g. VDI, VMDK
+	Variant    string
+	CapacityMB uint64 // logical size
+	SizeMB     uint64 // actual size on disk
+	ChildUUIDs []string
+	InUseBy    []string // names of the VMs the medium is attached to
+	// UUIDs of the snapshots the medium is attached to
+	SnapshotUUIDs []string
+}
+
+// IsBase returns true if the medium is not a differencing image.
+func (md Medium) IsBase() bool {
+	return md.ParentUUID == ""
+}
+
+// MediumInfo returns the information of the disk with the given UUID or file name.
+func MediumInfo(idOrFn string) (*Medium, error) {
+	stdout, stderr, err := Manage().runOutErr("showmediuminfo", "disk", idOrFn)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to get medium info: disk=%q, err=%q, out=%q", idOrFn, stderr, stdout)
+	}
+	md, err := parseMediumInfo(stdout)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to parse medium info: disk=%q", idOrFn)
+	}
+	return md, nil
+}
+
+func parseMediumInfo(out string) (*Medium, error) {
+	md := Medium{}
+	lastKey := ""
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		res := reMediumInfoLine.FindStringSubmatch(line)
+		if res == nil {
+			// multi-valued keys (e.g. Child UUIDs) continue on indented lines
+			if val := strings.TrimSpace(line); val != "" {
+				md.appendMultiValue(lastKey, val)
+			}
+			continue
+		}
+		lastKey = res[1]
+		var err error
+		switch key, val := res[1], strings.TrimSpace(res[2]); key {
+		case "UUID":
+			md.UUID = val
+		case "Parent UUID":
+			if val != "base" {
+				md.ParentUUID = val
+			}
+		case "State":
+			md.State = val
+		case "Type":
+			md.Type = val
+		case "Location":
+			md.Location = val
+		case "Storage format":
+			md.Format = val
+		case "Format variant":
+			md.Variant = val
+		case "Capacity":
+			md.CapacityMB, err = parseMediumSizeMB(val)
+		case "Size on disk":
+			md.SizeMB, err = parseMediumSizeMB(val)
+		case "Child UUIDs", "In use by VMs":
+			md.appendMultiValue(key, val)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if md.UUID == "" {
+		return nil, fmt.Errorf("no medium UUID found in medium info: %s", out)
+	}
+	return &md, nil
+}
+
+// appendMultiValue appends one of the values of a multi-valued medium info key.
+func (md *Medium) appendMultiValue(key, val string) {
+	switch key {
+	case "Child UUIDs":
+		md.ChildUUIDs = append(md.ChildUUIDs, val)
+	case "In use by VMs":
+		// e.g. worker2 (UUID: 6ad3c1f2-5b0b-4d66-a3b0-0f56b8e3d4c1) [base (UUID: 1bd0f0a6-...)]
+		if res := reMediumInUseByVM.FindStringSubmatch(val); res != nil {
+			md.InUseBy = append(md.InUseBy, res[1])
+		}
+		for _, res := range reMediumInUseBySnapshot.FindAllStringSubmatch(val, -1) {
+			md.SnapshotUUIDs = append(md.SnapshotUUIDs, res[1])
+		}
+	}
+}
+
+// parseMediumSizeMB parses sizes as in <Capacity: 20480 MBytes>.
+func parseMediumSizeMB(val string) (uint64, error) {
+	fields := strings.Fields(val)
+	if len(fields) != 2 || fields[1] != "MBytes" {
+		return 0, fmt.Errorf("unexpected medium size format, expected <n> MBytes, got: %q", val)
+	}
+	return strconv.ParseUint(fields[0], 10, 64)
+}
+
+// isAttachedMedium returns true if the given storage device medium is an image or a disk,
+// false for empty and host drives.
+func isAttachedMedium(medium string) bool {
+	return medium != "" && medium != "none" && medium != "emptydrive" && !strings.HasPrefix(medium, "host:")
+}
+
+// diskMediumInfo returns the information of the given attached medium if it is a disk, nil if it is
+// a DVD or floppy image.
+//
+// VirtualBox registers media per device type, so that <VBoxManage showmediuminfo> only finds a DVD
+// or floppy image with its type, whatever its file extension (e.g. .iso, .dmg, .cdr or raw images).
+func diskMediumInfo(idOrFn string) (*Medium, error) {
+	md, err := MediumInfo(idOrFn)
+	if err == nil {
+		return md, nil
+	}
+	for _, deviceType := range []string{"dvd", "floppy"} {
+		if _, _, errT := Manage().runOutErr("showmediuminfo", deviceType, idOrFn); errT == nil {
+			return nil, nil
+		}
+	}
+	return nil, err
+}
+
+// mediumChain returns the medium with the given UUID or file name followed by its ancestors, up to the base medium.
+func mediumChain(idOrFn string) ([]Medium, error) {
+	md, err := MediumInfo(idOrFn)
+	if err != nil {
+		return nil, err
+	}
+	return mediumChainFrom(md)
+}
+
+// mediumChainFrom returns the given medium followed by its ancestors, up to the base medium.
+func mediumChainFrom(md *Medium) ([]Medium, error) {
+	chain := make([]Medium, 0, 4)
+	chain = append(chain, *md)
+	for next := md.ParentUUID; next != ""; {
+		md, err := MediumInfo(next)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, *md)
+		next = md.ParentUUID
+	}
+	return chain, nil
+}
+
+// machineMediumTree returns the descendants of the given medium which are in use by the given machine,
+// e.g. the differencing images of its snapshots, leaving out the ones of other machines sharing the medium.
+func machineMediumTree(md Medium, vm string) ([]Medium, error) {
+	var tree []Medium
+	for _, childUUID := range md.ChildUUIDs {
+		child, err := MediumInfo(childUUID)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(child.InUseBy, vm) {
+			continue
+		}
+		descendants, err := machineMediumTree(*child, vm)
+		if err != nil {
+			return nil, err
+		}
+		tree = append(append(tree, *child), descendants...)
+	}
+	return tree, nil
+}
+
+// SetMediumType sets the type of the disk with the given UUID or file name.
+//
+// VirtualBox only changes the type of a medium which is not attached to any VM. To turn the base disk of
+// existing VMs into an immutable or multiattach disk: detach it from each VM (Machine.DetachStorage),
+// set its type, then attach it again (Machine.AttachStorage). VirtualBox then creates a differencing
+// image per VM on top of the shared read-only base.
+// ErrMediumInUse is returned if the disk is still attached when changing to immutable or multiattach.
+func SetMediumType(idOrFn string, t MediumType) error {
+	if t == MediumTypeImmutable || t == MediumTypeMultiattach {
+		md, err := MediumInfo(idOrFn)
+		if err != nil {
+			return err
+		}
+		if len(md.InUseBy) > 0 {
+			return errors.Wrapf(ErrMediumInUse, "fail to set medium type: disk=%q, type=%s, vms=%v", idOrFn, t, md.InUseBy)
+		}
+	}
+	stdout, stderr, err := Manage().runOutErr("modifymedium", "disk", idOrFn, "--type", string(t))
+	if err != nil {
+		return errors.Wrapf(err, "fail to set medium type: disk=%q, type=%s, err=%q, out=%q", idOrFn, t, stderr, stdout)
+	}
+	return nil
+}
+
+// SetMediumUUID sets the UUID of the disk with the given UUID or file name and returns it.
+// A random UUID is generated if the given one is empty, e.g. to avoid conflicts when registering
+// a copy of a disk file or a disk restored from a backup.
+func SetMediumUUID(idOrFn, uuid string) (string, error) {
+	if uuid == "" {
+		var err error
+		if uuid, err = newUUID(); err != nil {
+			return "", errors.Wrap(err, "fail to generate disk UUID")
+		}
+	}
+	stdout, stderr, err := Manage().runOutErr("modifymedium", "disk", idOrFn, "--setuuid", uuid)
+	if err != nil {
+		return "", errors.Wrapf(err, "fail to set medium UUID: disk=%q, uuid=%s, err=%q, out=%q", idOrFn, uuid, stderr, stdout)
+	}
+	return uuid, nil
+}
+
+// SetMediumProperty sets a property of the disk with the given UUID or file name,
+// e.g. the properties of iSCSI media.
+func SetMediumProperty(idOrFn, key, value string) error {
+	stdout, stderr, err := Manage().runOutErr("mediumproperty", "disk", "set", idOrFn, key, value)
+	if err != nil {
+		return errors.Wrapf(err, "fail to set medium property: disk=%q, key=%q, err=%q, out=%q", idOrFn, key, stderr, stdout)
+	}
+	return nil
+}
+
+// GetMediumProperty returns a property of the disk with the given UUID or file name.
+func GetMediumProperty(idOrFn, key string) (string, error) {
+	stdout, stderr, err := Manage().runOutErr("mediumproperty", "disk", "get", idOrFn, key)
+	if err != nil {
+		return "", errors.Wrapf(err, "fail to get medium property: disk=%q, key=%q, err=%q, out=%q", idOrFn, key, stderr, stdout)
+	}
+	// e.g. AllocationBlockSize=1048576
+	value := strings.TrimRight(stdout, "\r\n")
+	if !strings.HasPrefix(value, key+"=") {
+		return "", fmt.Errorf("unexpected medium property output: disk=%q, key=%q, out=%q", idOrFn, key, stdout)
+	}
+	return strings.TrimPrefix(value, key+"="), nil
+}
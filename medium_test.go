@@ -0,0 +1,235 @@
+package virtualbox
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMediumInfo(t *testing.T) {
+	md, err := parseMediumInfo(ReadTestData("vboxmanage-showmediuminfo-diff-1.out"))
+
+	require.NoError(t, err)
+	require.Equal(t,
+		&Medium{
+			UUID:       "3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf",
+			ParentUUID: "8c80c269-8569-4c90-b745-bac723810dab",
+			State:      "created",
+			Type:       "normal (differencing)",
+			Location:   "/media/bigstorage/Snapshots/{3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf}.vdi",
+			Format:     "VDI",
+			Variant:    "differencing default",
+			CapacityMB: 20480,
+			SizeMB:     513,
+			InUseBy:    []string{"worker2"},
+		},
+		md)
+}
+
+// testDiskImage creates a detached VDI disk, unregistered from VirtualBox once the test is done.
+func testDiskImage(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	raw, vdi := filepath.Join(dir, "disk.img"), filepath.Join(dir, "disk.vdi")
+	require.NoError(t, os.WriteFile(raw, make([]byte, 1<<20), 0600))
+	require.NoError(t, ConvertDisk(raw, vdi, DiskFormatVDI))
+	t.Cleanup(func() { _ = UnregisterDisk(vdi) })
+	return vdi
+}
+
+// attachedDisk returns the first disk attached to the machine, empty if it has none.
+func attachedDisk(t *testing.T, m *Machine) string {
+	t.Helper()
+	for _, ctl := range m.StorageControllers {
+		for _, dev := range ctl.Devices {
+			if !isAttachedMedium(dev.Medium) {
+				continue
+			}
+			md, err := diskMediumInfo(dev.Medium)
+			require.NoError(t, err)
+			if md != nil {
+				return dev.Medium
+			}
+		}
+	}
+	return ""
+}
+
+func TestDiskUsage(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock == nil {
+		m := testMachine(t, "")
+		disk := attachedDisk(t, m)
+
+		logical, actual, err := m.DiskUsage()
+
+		require.NoError(t, err)
+		if disk != "" {
+			md, err := MediumInfo(disk)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, logical, md.CapacityMB<<20)
+			require.GreaterOrEqual(t, actual, md.SizeMB<<20)
+		}
+		return
+	}
+
+	m := &Machine{Name: "worker2", StorageControllers: StorageControllers{
+		{Name: "IDE", Devices: []StorageMedium{{Port: 1, Medium: "emptydrive"}, {Port: 0, Device: 1, Medium: "/isos/macos.dmg"}}},
+		{Name: "SATA", Devices: []StorageMedium{{Medium: "/media/bigstorage/Snapshots/{3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf}.vdi"}}},
+	}}
+	diff := ReadTestData("vboxmanage-showmediuminfo-diff-1.out")
+	// the base disk has the differencing images of the current state, of another snapshot of the machine
+	// and of another machine
+	base := strings.Replace(ReadTestData("vboxmanage-showmediuminfo-base-1.out"),
+		"Child UUIDs:    3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf",
+		"Child UUIDs:    3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf\n"+
+			"                5f0d1c2b-3a4e-4f5a-8b6c-7d8e9f0a1b2c\n"+
+			"                0b1c2d3e-4f5a-4b6c-8d7e-9f0a1b2c3d4e", 1)
+	snapshotDiff := strings.NewReplacer(
+		"3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf", "5f0d1c2b-3a4e-4f5a-8b6c-7d8e9f0a1b2c",
+		"Size on disk:   513 MBytes", "Size on disk:   100 MBytes",
+		"(UUID: 6ad3c1f2-5b0b-4d66-a3b0-0f56b8e3d4c1)",
+		"(UUID: 6ad3c1f2-5b0b-4d66-a3b0-0f56b8e3d4c1) [experiment (UUID: 77f1a0c2-6d0e-4b5f-9a54-1c2f4a6b8e90)]",
+	).Replace(diff)
+	otherVMDiff := strings.NewReplacer(
+		"3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf", "0b1c2d3e-4f5a-4b6c-8d7e-9f0a1b2c3d4e",
+		"worker2", "worker3",
+	).Replace(diff)
+	gomock.InOrder(
+		// not a disk
+		ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/isos/macos.dmg").
+			Return("", "VBoxManage: error: Could not find file for the medium", errors.New("exit status 1")).Times(1),
+		ManageMock.EXPECT().runOutErr("showmediuminfo", "dvd", "/isos/macos.dmg").
+			Return("UUID: 2c5e8a4d-1b3f-4a6e-9c8d-7e6f5a4b3c2d", "", nil).Times(1),
+		ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/media/bigstorage/Snapshots/{3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf}.vdi").
+			Return(diff, "", nil).Times(1),
+		ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "8c80c269-8569-4c90-b745-bac723810dab").
+			Return(base, "", nil).Times(1),
+		ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf").
+			Return(diff, "", nil).Times(1),
+		ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "5f0d1c2b-3a4e-4f5a-8b6c-7d8e9f0a1b2c").
+			Return(snapshotDiff, "", nil).Times(1),
+		ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "0b1c2d3e-4f5a-4b6c-8d7e-9f0a1b2c3d4e").
+			Return(otherVMDiff, "", nil).Times(1),
+	)
+
+	logical, actual, err := m.DiskUsage()
+
+	require.NoError(t, err)
+	require.Equal(t, uint64(20480)<<20, logical)
+	require.Equal(t, uint64(2379+513+100)<<20, actual)
+}
+
+func TestSetMediumType(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	attached, detached := "/media/bigstorage/worker2.vdi", "/media/bigstorage/worker2.vdi"
+	if ManageMock != nil {
+		info := strings.Replace(ReadTestData("vboxmanage-showmediuminfo-base-1.out"),
+			"In use by VMs:  worker2 (UUID: 6ad3c1f2-5b0b-4d66-a3b0-0f56b8e3d4c1) [base (UUID: 1bd0f0a6-a0ab-4e36-b7a5-5ea0a3a2d58f)]\n", "", 1)
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/media/bigstorage/worker2.vdi").
+				Return(ReadTestData("vboxmanage-showmediuminfo-base-1.out"), "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/media/bigstorage/worker2.vdi").Return(info, "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifymedium", "disk", "/media/bigstorage/worker2.vdi", "--type", "immutable").
+				Return("", "", nil).Times(1),
+			// no attachment check needed
+			ManageMock.EXPECT().runOutErr("modifymedium", "disk", "/media/bigstorage/worker2.vdi", "--type", "normal").
+				Return("", "", nil).Times(1),
+		)
+	} else {
+		attached = attachedDisk(t, testMachine(t, ""))
+		if attached == "" {
+			t.Skipf("requires %s to have a disk", VM)
+		}
+		detached = testDiskImage(t)
+	}
+
+	err := SetMediumType(attached, MediumTypeMultiattach)
+	require.ErrorIs(t, err, ErrMediumInUse)
+
+	require.NoError(t, SetMediumType(detached, MediumTypeImmutable))
+	if ManageMock == nil {
+		md, err := MediumInfo(detached)
+		require.NoError(t, err)
+		require.Equal(t, "immutable", md.Type)
+	}
+
+	require.NoError(t, SetMediumType(detached, MediumTypeNormal))
+	if ManageMock == nil {
+		md, err := MediumInfo(detached)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(md.Type, "normal"), "got %s", md.Type)
+	}
+}
+
+func TestMediumProperty(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	disk := "/media/bigstorage/worker2.vdi"
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("mediumproperty", "disk", "set", "/media/bigstorage/worker2.vdi", "AllocationBlockSize", "2097152").
+				Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("mediumproperty", "disk", "get", "/media/bigstorage/worker2.vdi", "AllocationBlockSize").
+				Return("AllocationBlockSize=2097152\n", "", nil).Times(1),
+		)
+	} else {
+		disk = testDiskImage(t)
+	}
+
+	require.NoError(t, SetMediumProperty(disk, "AllocationBlockSize", "2097152"))
+
+	value, err := GetMediumProperty(disk, "AllocationBlockSize")
+	require.NoError(t, err)
+	require.Equal(t, "2097152", value)
+}
+
+func TestSetMediumUUID(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	disk, given := "/backup/worker2.vdi", "8c80c269-8569-4c90-b745-bac723810dab"
+	var generated string
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("modifymedium", "disk", "/backup/worker2.vdi", "--setuuid", "8c80c269-8569-4c90-b745-bac723810dab").
+				Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifymedium", "disk", "/backup/worker2.vdi", "--setuuid", gomock.Any()).
+				DoAndReturn(func(args ...string) (string, string, error) {
+					generated = args[4]
+					return "", "", nil
+				}).Times(1),
+		)
+	} else {
+		disk = testDiskImage(t)
+		var err error
+		given, err = newUUID()
+		require.NoError(t, err)
+	}
+
+	uuid, err := SetMediumUUID(disk, given)
+	require.NoError(t, err)
+	require.Equal(t, given, uuid)
+
+	uuid, err = SetMediumUUID(disk, "")
+	require.NoError(t, err)
+	require.NotEmpty(t, uuid)
+	require.NotEqual(t, given, uuid)
+	if ManageMock != nil {
+		require.Equal(t, generated, uuid)
+	} else {
+		md, err := MediumInfo(disk)
+		require.NoError(t, err)
+		require.Equal(t, uuid, md.UUID)
+	}
+}
@@ -2,17 +2,33 @@ package virtualbox
 
 import (
 	"bufio"
+	"encoding/json"
+	"fmt"
 	"net"
 	"strings"
 )
 
 // A NATNet defines a NAT network.
 type NATNet struct {
-	Name    string
-	IPv4    net.IPNet
-	IPv6    net.IPNet
-	DHCP    bool
-	Enabled bool
+	Name    string `json:"name"`
+	IPv4    CIDR   `json:"ipv4"`
+	IPv6    CIDR   `json:"ipv6"`
+	DHCP    bool   `json:"dhcp"`
+	Enabled bool   `json:"enabled"`
+}
+
+func (n NATNet) String() string {
+	return fmt.Sprintf("NATNet[%s, ipv4=%s, ipv6=%s, dhcp=%t, enabled=%t]",
+		n.Name, n.IPv4, n.IPv6, n.DHCP, n.Enabled)
+}
+
+// NATNetsJSON returns all NAT networks as a JSON object keyed by NATNet.Name.
+func NATNetsJSON() ([]byte, error) {
+	natnets, err := NATNets()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(natnets)
 }
 
 // NATNets gets all NAT networks in a  map keyed by NATNet.Name.
@@ -52,7 +68,9 @@ func NATNets() (map[string]NATNet, error) {
 	for s.Scan() {
 		line := s.Text()
 		if line == "" {
-			m[n.Name] = n
+			if n.Name != "" {
+				m[n.Name] = n
+			}
 			n = NATNet{}
 			continue
 		}
@@ -64,13 +82,14 @@ func NATNets() (map[string]NATNet, error) {
 		case "Name", "NetworkName":
 			n.Name = val
 		case "IP", "Gateway":
-			n.IPv4.IP = net.ParseIP(val)
+			// 4 bytes form, as in DHCPs, so that addresses of both compare equal
+			n.IPv4.IP = net.ParseIP(val).To4()
 		case "Network":
 			_, ipnet, err := net.ParseCIDR(val)
 			if err != nil {
 				return nil, err
 			}
-			n.IPv4.Mask = ipnet.Mask
+			n.IPv4.Mask = CIDR{*ipnet}.normalized().Mask
 		case "IPv6 Prefix":
 			// TODO: IPv6 CIDR parsing works fine on macOS, check on Windows
 			// if val == "" {
@@ -85,7 +104,7 @@ func NATNets() (map[string]NATNet, error) {
 			if err != nil {
 				return nil, err
 			}
-			n.IPv6.Mask = ipnet.Mask
+			n.IPv6 = CIDR{net.IPNet{IP: ipnet.IP.To16(), Mask: ipnet.Mask}}
 		case "DHCP Enabled":
 			n.DHCP = (val == stringYes)
 		case "Enabled":
@@ -95,5 +114,9 @@ func NATNets() (map[string]NATNet, error) {
 	if err := s.Err(); err != nil {
 		return nil, err
 	}
+	if n.Name != "" {
+		// last entry not followed by an empty line
+		m[n.Name] = n
+	}
 	return m, nil
 }
@@ -1,9 +1,12 @@
 package virtualbox
 
 import (
+	"encoding/json"
+	"net"
 	"testing"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNATNets(t *testing.T) {
@@ -23,3 +26,61 @@ func TestNATNets(t *testing.T) {
 
 	Teardown()
 }
+
+func TestNATNetsJSON(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOut("list", "natnets").Return(ReadTestData("vboxmanage-list-natnets-1.out"), nil).Times(1)
+	}
+	b, err := NATNetsJSON()
+	require.NoError(t, err)
+	if ManageMock != nil {
+		require.JSONEq(t, `{
+		"NatNetwork": {"name": "NatNetwork", "ipv4": "10.0.2.1/24", "ipv6": "fd17:625c:f037:2::/64", "dhcp": true, "enabled": true}
+	}`, string(b))
+		return
+	}
+	// the NAT networks depend on the host: each one is keyed by its name
+	var natnets map[string]map[string]interface{}
+	require.NoError(t, json.Unmarshal(b, &natnets))
+	for name, natnet := range natnets {
+		require.Equal(t, name, natnet["name"])
+	}
+}
+
+func TestNATNetsIPRepresentation(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOut("list", "natnets").Return(ReadTestData("vboxmanage-list-natnets-1.out"), nil).Times(1)
+		ManageMock.EXPECT().runOut("list", "dhcpservers").
+			Return(ReadTestData("vboxmanage-list-dhcpservers-natnet-1.out"), nil).Times(1)
+	}
+	natnets, err := NATNets()
+	require.NoError(t, err)
+	dhcps, err := DHCPs()
+	require.NoError(t, err)
+	if ManageMock != nil {
+		require.Contains(t, natnets, "NatNetwork")
+		require.Contains(t, dhcps, "NatNetwork")
+	}
+
+	for name, natnet := range natnets {
+		// same representation as the addresses of the DHCP server of the network, if any
+		if dhcp, ok := dhcps[name]; ok {
+			require.Lenf(t, natnet.IPv4.IP, len(dhcp.IPv4.IP), "gateway %s and dhcp server %s", natnet.IPv4.IP, dhcp.IPv4.IP)
+			require.Equal(t, dhcp.IPv4.Mask, natnet.IPv4.Mask)
+			require.Equal(t, dhcp.IPv4.IP.Mask(dhcp.IPv4.Mask), natnet.IPv4.IP.Mask(natnet.IPv4.Mask), "same network")
+		}
+		if natnet.IPv6.IP != nil {
+			require.Len(t, natnet.IPv6.IP, net.IPv6len)
+		}
+	}
+	if ManageMock != nil {
+		require.Equal(t, mustParseCIDR(t, "10.0.2.1/24"), natnets["NatNetwork"].IPv4)
+		require.Len(t, natnets["NatNetwork"].IPv6.IP, net.IPv6len)
+	}
+}
@@ -0,0 +1,111 @@
+package virtualbox
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	reVMInfoNIC        = regexp.MustCompile(`^nic(\d+)=`)
+	reVMInfoForwarding = regexp.MustCompile(`^Forwarding\(\d+\)="(.*)"$`)
+)
+
+// ListNATPF returns the NAT port forwarding rules of the n-th NIC.
+//
+// Rules added with AddNATPF on a running machine and the ones of the machine settings are listed alike,
+// as both are reported by the VM info.
+func (m *Machine) ListNATPF(n int) ([]NamedPFRule, error) {
+	rules, _, err := m.natpfRules(n)
+	return rules, err
+}
+
+// natpfRules returns the NAT port forwarding rules of the n-th NIC and the current machine state.
+func (m *Machine) natpfRules(n int) ([]NamedPFRule, MachineState, error) {
+	mutex.Lock()
+	stdout, stderr, err := Manage().runOutErr("showvminfo", m.Name, "--machinereadable")
+	mutex.Unlock()
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "fail to list nat port forwardings: vm=%s, stderr=%s", m.Name, stderr)
+	}
+	// Forwarding(<i>) keys are not numbered by NIC: they follow the nic<n> key of their NIC.
+	rules := []NamedPFRule{}
+	var state MachineState
+	curNIC := ""
+	s := bufio.NewScanner(strings.NewReader(stdout))
+	for s.Scan() {
+		line := s.Text()
+		if res := reVMInfoNIC.FindStringSubmatch(line); res != nil {
+			curNIC = res[1]
+		} else if strings.HasPrefix(line, "VMState=") {
+			state = MachineState(strings.Trim(strings.TrimPrefix(line, "VMState="), `"`))
+		} else if res := reVMInfoForwarding.FindStringSubmatch(line); res != nil && curNIC == fmt.Sprintf("%d", n) {
+			rule, err := parseNamedPFRule(res[1])
+			if err != nil {
+				return nil, "", errors.Wrapf(err, "fail to read nat port forwarding: vm=%s, nic=%d", m.Name, n)
+			}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, state, s.Err()
+}
+
+// SyncNATPF converges the NAT port forwarding rules of the n-th NIC to the desired ones:
+// rules missing or differing are (re)added, the other ones are deleted.
+// The rules are changed on the running machine if it is running, in its settings otherwise.
+// ErrInvalidPFRule is returned, before any change, if a desired rule is not valid.
+func (m *Machine) SyncNATPF(n int, desired []NamedPFRule) error {
+	args := make(map[string]string, len(desired))
+	for _, rule := range desired {
+		arg, err := formatNamedPFRule(rule.Name, rule.PFRule)
+		if err != nil {
+			return errors.Wrapf(err, "fail to sync nat port forwardings: vm=%s, nic=%d", m.Name, n)
+		}
+		args[rule.Name] = arg
+	}
+	current, state, err := m.natpfRules(n)
+	if err != nil {
+		return err
+	}
+	running := state == Running || state == Paused
+	currentByName := make(map[string]NamedPFRule, len(current))
+	for _, rule := range current {
+		currentByName[rule.Name] = rule
+	}
+	desiredByName := make(map[string]NamedPFRule, len(desired))
+	for _, rule := range desired {
+		desiredByName[rule.Name] = rule
+	}
+	for _, rule := range current {
+		if want, ok := desiredByName[rule.Name]; !ok || want.Format() != rule.Format() {
+			if err := m.natpf(running, n, "delete", rule.Name); err != nil {
+				return err
+			}
+		}
+	}
+	for _, rule := range desired {
+		if have, ok := currentByName[rule.Name]; !ok || have.Format() != rule.Format() {
+			if err := m.natpf(running, n, args[rule.Name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// natpf changes the NAT port forwarding rules of the n-th NIC with <VBoxManage controlvm <vm> natpf<n> ...>
+// for a running machine, <VBoxManage modifyvm <vm> --natpf<n> ...> otherwise.
+func (m *Machine) natpf(running bool, n int, args ...string) error {
+	cmd := []string{"modifyvm", m.Name, fmt.Sprintf("--natpf%d", n)}
+	if running {
+		cmd = []string{"controlvm", m.Name, fmt.Sprintf("natpf%d", n)}
+	}
+	cmd = append(cmd, args...)
+	if stdout, stderr, err := Manage().runOutErr(cmd...); err != nil {
+		return errors.Wrapf(err, "fail to change nat port forwarding: args=%v, stdout=%s, stderr=%s", cmd, stdout, stderr)
+	}
+	return nil
+}
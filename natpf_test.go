@@ -0,0 +1,130 @@
+package virtualbox
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// testNATNIC returns the rank of the first NAT NIC of the real machine, skipping the test if it has none.
+func testNATNIC(t *testing.T, m *Machine) int {
+	t.Helper()
+	for i, nic := range m.NICs {
+		if nic.Network == NICNetNAT {
+			return i + 1
+		}
+	}
+	t.Skipf("requires %s to have a NAT NIC", m.Name)
+	return 0
+}
+
+func TestListNATPF(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock == nil {
+		m := testMachine(t, "")
+		rules, err := m.ListNATPF(testNATNIC(t, m))
+		require.NoError(t, err)
+		for _, rule := range rules {
+			require.NotEmpty(t, rule.Name)
+			require.Contains(t, []PFProto{PFTCP, PFUDP}, rule.Proto)
+		}
+		return
+	}
+
+	vmInfo := strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"), `nic2="none"`,
+		"nic2=\"nat\"\nnictype2=\"82540EM\"\nForwarding(0)=\"web,tcp,,8080,,80\"", 1)
+	ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").Return(vmInfo, "", nil).Times(2)
+	m := &Machine{Name: "go-virtualbox"}
+
+	rules, err := m.ListNATPF(1)
+	require.NoError(t, err)
+	require.Equal(t,
+		[]NamedPFRule{{Name: "ssh", PFRule: PFRule{Proto: PFTCP, HostIP: net.ParseIP("127.0.0.1"), HostPort: 2222, GuestPort: 22}}},
+		rules)
+
+	rules, err = m.ListNATPF(2)
+	require.NoError(t, err)
+	require.Equal(t, []NamedPFRule{{Name: "web", PFRule: PFRule{Proto: PFTCP, HostPort: 8080, GuestPort: 80}}}, rules)
+}
+
+func TestSyncNATPF(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	n := 1
+	desired := []NamedPFRule{
+		{Name: "ssh", PFRule: PFRule{Proto: PFTCP, HostIP: net.ParseIP("127.0.0.1"), HostPort: 2200, GuestPort: 22}},
+		{Name: "web", PFRule: PFRule{Proto: PFTCP, HostPort: 8080, GuestPort: 80}},
+	}
+	converged := desired
+	if ManageMock != nil {
+		// saved VM: rules are changed with modifyvm; current rule is ssh,tcp,127.0.0.1,2222,,22
+		gomock.InOrder(
+			expectShowVMInfo("go-virtualbox", ReadTestData("vboxmanage-showvminfo-1.out")),
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--natpf1", "delete", "ssh").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--natpf1", "ssh,tcp,127.0.0.1,2200,,22").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifyvm", "go-virtualbox", "--natpf1", "web,tcp,,8080,,80").Return("", "", nil).Times(1),
+			// running VM already converged: nothing to do
+			expectShowVMInfo("go-virtualbox", strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"),
+				`VMState="saved"`, `VMState="running"`, 1)),
+		)
+		converged = []NamedPFRule{
+			{Name: "ssh", PFRule: PFRule{Proto: PFTCP, HostIP: net.ParseIP("127.0.0.1"), HostPort: 2222, GuestPort: 22}},
+		}
+	} else {
+		m = testMachine(t, "")
+		n = testNATNIC(t, m)
+		current, err := m.ListNATPF(n)
+		require.NoError(t, err)
+		t.Cleanup(func() { require.NoError(t, m.SyncNATPF(n, current)) })
+	}
+
+	require.NoError(t, m.SyncNATPF(n, desired))
+	if ManageMock == nil {
+		rules, err := m.ListNATPF(n)
+		require.NoError(t, err)
+		require.ElementsMatch(t, desired, rules)
+	}
+
+	require.NoError(t, m.SyncNATPF(n, converged))
+}
+
+func TestAddNATPFValidation(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	n := 1
+	rule := PFRule{Proto: PFTCP, HostIP: net.ParseIP("127.0.0.1"), HostPort: 2222, GuestPort: 22}
+	name := "ssh"
+	if ManageMock != nil {
+		ManageMock.EXPECT().run("controlvm", "go-virtualbox", "natpf1", "ssh,tcp,127.0.0.1,2222,,22").Return(nil).Times(1)
+	} else {
+		m = testMachine(t, "", Running)
+		n = testNATNIC(t, m)
+		name, rule.HostPort = "go-virtualbox-test", 52222
+		t.Cleanup(func() { require.NoError(t, m.DelNATPF(n, name)) })
+	}
+
+	for _, invalid := range []string{"", "ssh,alt", "my ssh", "ssh\t"} {
+		require.ErrorIsf(t, m.AddNATPF(n, invalid, rule), ErrInvalidPFRule, "name=%q", invalid)
+	}
+	require.ErrorIs(t, m.AddNATPF(n, name, PFRule{Proto: "sctp", HostPort: 2222, GuestPort: 22}), ErrInvalidPFRule)
+
+	require.NoError(t, m.AddNATPF(n, name, rule))
+	if ManageMock == nil {
+		rules, err := m.ListNATPF(n)
+		require.NoError(t, err)
+		require.Contains(t, rules, NamedPFRule{Name: name, PFRule: rule})
+	}
+
+	// nothing is changed when one of the desired rules is not valid
+	err := m.SyncNATPF(n, []NamedPFRule{{Name: name, PFRule: rule}, {Name: "web,8080", PFRule: rule}})
+	require.ErrorIs(t, err, ErrInvalidPFRule)
+}
@@ -6,9 +6,25 @@ type NIC struct {
 	NetworkName   string
 	Hardware      NICHardware
 	HostInterface string // The host interface name to bind to in 'hostonly' and 'bridged' mode
-	MacAddr       string
+	MacAddr       string // MAC address, NICMacAddrAuto to have a new one generated by VirtualBox
 }
 
+// hostonlyNetworkNamePrefix prefixes the host interface name of a host-only network
+// in its VirtualBox network name, e.g. HostInterfaceNetworking-vboxnet0.
+const hostonlyNetworkNamePrefix = "HostInterfaceNetworking-"
+
+// HostonlyDHCPKey returns the key of the DHCP server of the host-only network of the NIC in the map
+// returned by DHCPs (i.e. its DHCP.NetworkName), empty if the NIC is not attached to a host-only network.
+func (nic NIC) HostonlyDHCPKey() string {
+	if nic.Network != NICNetHostonly || nic.HostInterface == "" {
+		return ""
+	}
+	return hostonlyNetworkNamePrefix + nic.HostInterface
+}
+
+// NICMacAddrAuto requests VirtualBox to generate a new random MAC address for a NIC.
+const NICMacAddrAuto = "auto"
+
 // NICNetwork represents the type of NIC networks.
 type NICNetwork string
 
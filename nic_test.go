@@ -0,0 +1,38 @@
+package virtualbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNICHostonlyDHCPKey(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOut("list", "dhcpservers").Return(ReadTestData("vboxmanage-list-dhcpservers-1.out"), nil).Times(1)
+	}
+	dhcps, err := DHCPs()
+	require.NoError(t, err)
+
+	nic := NIC{Network: NICNetHostonly, Hardware: VirtIO, HostInterface: "vboxnet5"}
+	require.Equal(t, "HostInterfaceNetworking-vboxnet5", nic.HostonlyDHCPKey())
+	// the DHCP servers of the host-only interfaces are found by the key of their NICs
+	for key := range dhcps {
+		if adapter := strings.TrimPrefix(key, "HostInterfaceNetworking-"); adapter != key {
+			nic = NIC{Network: NICNetHostonly, HostInterface: adapter}
+			require.Equal(t, key, nic.HostonlyDHCPKey())
+		}
+	}
+	if ManageMock != nil {
+		nic = NIC{Network: NICNetHostonly, Hardware: VirtIO, HostInterface: "vboxnet5"}
+		require.Contains(t, dhcps, nic.HostonlyDHCPKey())
+		nic = NIC{Network: NICNetHostonly, HostInterface: "VirtualBox Host-Only Ethernet Adapter"}
+		require.Contains(t, dhcps, nic.HostonlyDHCPKey())
+	}
+
+	nic = NIC{Network: NICNetBridged, HostInterface: "eth0"}
+	require.Empty(t, nic.HostonlyDHCPKey())
+}
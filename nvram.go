@@ -0,0 +1,68 @@
+package virtualbox
+
+import (
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// ErrFirmwareNotEFI holds the error message when an operation requires a machine with an EFI firmware.
+var ErrFirmwareNotEFI = errors.New("machine firmware is not EFI")
+
+// NVRAMOps changes the EFI variable store (NVRAM) of a machine with <VBoxManage modifynvram>,
+// e.g. to set up Secure Boot for Windows 11 guests:
+//
+//	nvram := m.NVRAM()
+//	err := nvram.InitSecureBoot()
+//	err = nvram.EnrollMSKeys()
+//	err = nvram.EnrollPlatformKey("pk.der", "")
+//
+// The machine must have an EFI firmware (FirmwareEFI), otherwise ErrFirmwareNotEFI is returned,
+// and must not be running.
+type NVRAMOps struct {
+	m *Machine
+}
+
+// NVRAM returns the operations on the EFI variable store of the machine.
+func (m *Machine) NVRAM() NVRAMOps {
+	return NVRAMOps{m: m}
+}
+
+// InitSecureBoot initializes the EFI variable store with the default content,
+// which is required before enrolling Secure Boot keys.
+func (nvram NVRAMOps) InitSecureBoot() error {
+	return nvram.modify("inituefivarstore")
+}
+
+// EnrollMSKeys enrolls the Microsoft key exchange key and signature databases, which Windows
+// and the Microsoft signed boot loaders of Linux distributions require with Secure Boot.
+func (nvram NVRAMOps) EnrollMSKeys() error {
+	return nvram.modify("enrollmssignatures")
+}
+
+// EnrollPlatformKey enrolls the platform key read from the given file, which enables Secure Boot.
+// ownerUUID identifies the owner of the key, a random one is generated if empty.
+func (nvram NVRAMOps) EnrollPlatformKey(pk, ownerUUID string) error {
+	pk, err := filepath.Abs(pk)
+	if err != nil {
+		return errors.Wrapf(err, "fail to enroll platform key: vm=%s, pk=%s", nvram.m.Name, pk)
+	}
+	if ownerUUID == "" {
+		if ownerUUID, err = newUUID(); err != nil {
+			return errors.Wrapf(err, "fail to generate platform key owner uuid: vm=%s", nvram.m.Name)
+		}
+	}
+	return nvram.modify("enrollpk", "--platform-key", pk, "--owner-uuid", ownerUUID)
+}
+
+func (nvram NVRAMOps) modify(op string, args ...string) error {
+	m := nvram.m
+	if !m.isEFI() {
+		return errors.Wrapf(ErrFirmwareNotEFI, "fail to modify nvram: vm=%s, op=%s, firmware=%s", m.Name, op, m.Firmware)
+	}
+	stdout, stderr, err := Manage().runOutErr(append([]string{"modifynvram", m.Name, op}, args...)...)
+	if err != nil {
+		return errors.Wrapf(err, "fail to modify nvram: vm=%s, op=%s, stdout=%s, stderr=%s", m.Name, op, stdout, stderr)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package virtualbox
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// testPlatformKey writes a self-signed certificate in DER format to be enrolled as platform key.
+func testPlatformKey(t *testing.T) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "go-virtualbox test PK"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	pk := filepath.Join(t.TempDir(), "pk.der")
+	require.NoError(t, os.WriteFile(pk, der, 0600))
+	return pk
+}
+
+// restoreNVRAMOnCleanup restores the EFI variable store files of the real machine once the test is done.
+func restoreNVRAMOnCleanup(t *testing.T, m *Machine) {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(m.BaseFolder, "*.nvram"))
+	require.NoError(t, err)
+	saved := make(map[string][]byte, len(files))
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		require.NoError(t, err)
+		saved[f] = data
+	}
+	t.Cleanup(func() {
+		for f, data := range saved {
+			require.NoError(t, os.WriteFile(f, data, 0600))
+		}
+	})
+}
+
+func TestNVRAMSecureBoot(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", Firmware: FirmwareBIOS}
+	require.ErrorIs(t, m.NVRAM().InitSecureBoot(), ErrFirmwareNotEFI)
+
+	pk := "pk.der"
+	if ManageMock != nil {
+		m.Firmware = FirmwareEFI64
+		abs, err := filepath.Abs(pk)
+		require.NoError(t, err)
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("modifynvram", "go-virtualbox", "inituefivarstore").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifynvram", "go-virtualbox", "enrollmssignatures").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifynvram", "go-virtualbox", "enrollpk", "--platform-key", abs,
+				"--owner-uuid", "a3dbf4b0-9d2e-4c7e-8b52-1f3c3b4a0c11").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifynvram", "go-virtualbox", "enrollpk", "--platform-key", abs,
+				"--owner-uuid", gomock.Any()).Return("", "", nil).Times(1),
+		)
+	} else {
+		skipBeforeVirtualBox(t, 7, 0, 0)
+		m = testMachine(t, "", Poweroff, Aborted)
+		if !m.isEFI() {
+			t.Skipf("requires %s to have an EFI firmware, got %s", m.Name, m.Firmware)
+		}
+		restoreNVRAMOnCleanup(t, m)
+		pk = testPlatformKey(t)
+	}
+	nvram := m.NVRAM()
+	require.NoError(t, nvram.InitSecureBoot())
+	require.NoError(t, nvram.EnrollMSKeys())
+	require.NoError(t, nvram.EnrollPlatformKey(pk, "a3dbf4b0-9d2e-4c7e-8b52-1f3c3b4a0c11"))
+	require.NoError(t, nvram.EnrollPlatformKey(pk, ""))
+}
@@ -0,0 +1,104 @@
+package virtualbox
+
+import (
+	"bufio"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// OSType defines a guest OS type known by VirtualBox.
+type OSType struct {
+	ID          string // e.g. Ubuntu_64, as used by modifyvm --ostype
+	Description string // e.g. Ubuntu (64-bit), as in the VM info
+	FamilyID    string // e.g. Linux
+	Is64Bit     bool
+}
+
+// ListOSTypes returns the guest OS types known by VirtualBox.
+func ListOSTypes() ([]OSType, error) {
+	stdout, stderr, err := Manage().runOutErr("list", "ostypes")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to list os types: stderr=%s", stderr)
+	}
+	return parseOSTypes(stdout), nil
+}
+
+func parseOSTypes(out string) []OSType {
+	types := []OSType{}
+	o := OSType{}
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if line == "" {
+			if o.ID != "" {
+				types = append(types, o)
+			}
+			o = OSType{}
+			continue
+		}
+		res := reColonLine.FindStringSubmatch(line)
+		if res == nil {
+			continue
+		}
+		switch key, val := res[1], res[2]; key {
+		case "ID":
+			o.ID = val
+		case "Description":
+			o.Description = val
+		case "Family ID":
+			o.FamilyID = val
+		case "64 bit":
+			o.Is64Bit = val == "true"
+		}
+	}
+	if o.ID != "" {
+		// last entry not followed by an empty line
+		types = append(types, o)
+	}
+	return types
+}
+
+// osTypeIDs caches the OS type IDs by description, as the OS types of a VirtualBox installation do not change.
+var osTypeIDs struct {
+	sync.Mutex
+	byDescription map[string]string // nil until listed
+}
+
+// setOSTypes replaces the cached OS types with the given ones, none to list them again when needed.
+func setOSTypes(types []OSType) {
+	osTypeIDs.Lock()
+	defer osTypeIDs.Unlock()
+	osTypeIDs.byDescription = nil
+	if types == nil {
+		return
+	}
+	osTypeIDs.byDescription = make(map[string]string, len(types))
+	for _, o := range types {
+		osTypeIDs.byDescription[o.Description] = o.ID
+	}
+}
+
+// osTypeID returns the ID of the OS type with the given description, as the VM info has the OS type description
+// while modifyvm --ostype needs its ID. It returns an empty ID for an unknown OS type, or when the OS types
+// cannot be listed, so that Modify keeps the current OS type.
+func osTypeID(description string) string {
+	if description == "" {
+		return ""
+	}
+	osTypeIDs.Lock()
+	listed := osTypeIDs.byDescription != nil
+	osTypeIDs.Unlock()
+	if !listed {
+		types, err := ListOSTypes()
+		if err != nil {
+			Debug("fail to map os type description to its id: description=%s, err=%v", description, err)
+			return ""
+		}
+		setOSTypes(types)
+	}
+	osTypeIDs.Lock()
+	defer osTypeIDs.Unlock()
+	return osTypeIDs.byDescription[description]
+}
@@ -0,0 +1,41 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	useTestOSTypes()
+}
+
+// useTestOSTypes maps the OS type descriptions of the VM info fixtures to IDs without listing the OS types.
+func useTestOSTypes() {
+	setOSTypes(parseOSTypes(ReadTestData("vboxmanage-list-ostypes-1.out")))
+}
+
+func TestListOSTypes(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("list", "ostypes").Return(ReadTestData("vboxmanage-list-ostypes-1.out"), "", nil).Times(1)
+	}
+	types, err := ListOSTypes()
+	require.NoError(t, err)
+	require.Contains(t, types, OSType{ID: "Other", Description: "Other/Unknown", FamilyID: "Other"})
+	if ManageMock != nil {
+		require.Len(t, types, 7)
+		require.Contains(t, types, OSType{ID: "Ubuntu_64", Description: "Ubuntu (64-bit)", FamilyID: "Linux", Is64Bit: true})
+	}
+}
+
+func TestOSTypeID(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	require.Equal(t, "Other", osTypeID("Other/Unknown"))
+	require.Empty(t, osTypeID("Plan 9"), "unknown os type")
+	require.Empty(t, osTypeID(""))
+}
@@ -1,10 +1,16 @@
 package virtualbox
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"strings"
 )
 
+// ErrInvalidPFRule holds the error message when a port forwarding rule cannot be passed to VBoxManage.
+var ErrInvalidPFRule = errors.New("invalid port forwarding rule")
+
 // PFRule represents a port forwarding rule.
 type PFRule struct {
 	Proto     PFProto
@@ -49,3 +55,52 @@ func grab(r PFRule) (string, string) {
 	}
 	return hostip, guestip
 }
+
+// formatNamedPFRule returns the <name>,<rule> argument adding the given rule with VBoxManage.
+// ErrInvalidPFRule is returned if the name is empty or contains commas or spaces, which would shift
+// the fields of the rule, or if the rule has an unknown protocol.
+func formatNamedPFRule(name string, rule PFRule) (string, error) {
+	if name == "" || strings.ContainsAny(name, ", \t\r\n") {
+		return "", fmt.Errorf("%w: name must be non-empty without commas or spaces: name=%q", ErrInvalidPFRule, name)
+	}
+	if rule.Proto != PFTCP && rule.Proto != PFUDP {
+		return "", fmt.Errorf("%w: protocol must be %s or %s: name=%q, proto=%q", ErrInvalidPFRule, PFTCP, PFUDP, name, rule.Proto)
+	}
+	arg := fmt.Sprintf("%s,%s", name, rule.Format())
+	if _, err := parseNamedPFRule(arg); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidPFRule, err)
+	}
+	return arg, nil
+}
+
+// NamedPFRule is a port forwarding rule with its name, as reported in the VM info.
+type NamedPFRule struct {
+	Name string
+	PFRule
+}
+
+// parseNamedPFRule parses a port forwarding rule from the VM info, e.g. ssh,tcp,127.0.0.1,2222,,22.
+func parseNamedPFRule(s string) (NamedPFRule, error) {
+	fields := strings.Split(s, ",")
+	if len(fields) != 6 {
+		return NamedPFRule{}, fmt.Errorf("bad port forwarding rule, expected name,proto,hostip,hostport,guestip,guestport: %q", s)
+	}
+	hostPort, err := strconv.ParseUint(fields[3], 10, 16)
+	if err != nil {
+		return NamedPFRule{}, fmt.Errorf("bad host port in port forwarding rule %q: %w", s, err)
+	}
+	guestPort, err := strconv.ParseUint(fields[5], 10, 16)
+	if err != nil {
+		return NamedPFRule{}, fmt.Errorf("bad guest port in port forwarding rule %q: %w", s, err)
+	}
+	return NamedPFRule{
+		Name: fields[0],
+		PFRule: PFRule{
+			Proto:     PFProto(fields[1]),
+			HostIP:    net.ParseIP(fields[2]),
+			HostPort:  uint16(hostPort),
+			GuestIP:   net.ParseIP(fields[4]),
+			GuestPort: uint16(guestPort),
+		},
+	}, nil
+}
@@ -0,0 +1,91 @@
+package virtualbox
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// OperationOption configures a long running operation such as CloneHD or ImportOV.
+type OperationOption func(*operationConfig)
+
+type operationConfig struct {
+	inhibitSleep bool
+}
+
+// WithSleepInhibited asks the host not to go to sleep while the operation runs,
+// as an interrupted disk operation may leave a corrupted output behind.
+//
+// Supported on linux (systemd-inhibit), macOS (caffeinate) and Windows (SetThreadExecutionState);
+// the option has no effect on other operating systems.
+func WithSleepInhibited() OperationOption {
+	return func(cfg *operationConfig) {
+		cfg.inhibitSleep = true
+	}
+}
+
+// sleepInhibitor inhibits the host sleep until the returned release function is called,
+// replaced by fakes in tests.
+var sleepInhibitor = inhibitSleep
+
+// inhibitorStartGrace is how long a sleep inhibitor process is watched after its start,
+// so that e.g. systemd-inhibit failing to reach logind is reported instead of being ignored.
+var inhibitorStartGrace = 200 * time.Millisecond
+
+// runOperation runs the long running operation op as configured by the given options.
+func runOperation(name string, op func() error, opts ...OperationOption) error {
+	cfg := operationConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if !cfg.inhibitSleep {
+		return op()
+	}
+	release, err := sleepInhibitor("go-virtualbox: " + name)
+	if err != nil {
+		return errors.Wrapf(err, "fail to inhibit host sleep for operation: %s", name)
+	}
+	defer release()
+	return op()
+}
+
+// startInhibitorProcess starts a process holding a sleep inhibition as long as it runs.
+// An error is returned if the process exits within inhibitorStartGrace, e.g. because the inhibition was refused.
+// If it exits later on, before being released, the host may sleep again: this is logged with Debug.
+func startInhibitorProcess(program string, args ...string) (func(), error) {
+	cmd := exec.Command(program, args...) // #nosec
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrapf(err, "fail to start sleep inhibitor: %s", cmd.String())
+	}
+	exited := make(chan error, 1)
+	released := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		select {
+		case <-released:
+		default:
+			Debug("sleep inhibitor exited before being released, host sleep is not inhibited anymore: cmd=%s, err=%v, stderr=%s",
+				cmd.String(), err, strings.TrimSpace(stderr.String()))
+		}
+		exited <- err
+	}()
+	select {
+	case err := <-exited:
+		return nil, errors.Errorf("sleep inhibitor exited on start: cmd=%s, err=%v, stderr=%s",
+			cmd.String(), err, strings.TrimSpace(stderr.String()))
+	case <-time.After(inhibitorStartGrace):
+	}
+	Trace("sleep inhibitor started: %s", cmd.String())
+	return func() {
+		close(released)
+		if err := cmd.Process.Kill(); err != nil {
+			Debug("fail to stop sleep inhibitor: cmd=%s, err=%v", cmd.String(), err)
+		}
+		<-exited
+	}, nil
+}
@@ -0,0 +1,6 @@
+package virtualbox
+
+func inhibitSleep(reason string) (func(), error) {
+	// -i: prevent idle sleep, -s: prevent system sleep while on AC power
+	return startInhibitorProcess("caffeinate", "-i", "-s")
+}
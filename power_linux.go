@@ -0,0 +1,7 @@
+package virtualbox
+
+func inhibitSleep(reason string) (func(), error) {
+	return startInhibitorProcess("systemd-inhibit",
+		"--what=sleep:idle", "--who=go-virtualbox", "--why="+reason, "--mode=block",
+		"sleep", "infinity")
+}
@@ -0,0 +1,8 @@
+//go:build !linux && !darwin && !windows
+
+package virtualbox
+
+func inhibitSleep(reason string) (func(), error) {
+	Debug("sleep inhibition not supported on this OS, ignoring it: reason=%s", reason)
+	return func() {}, nil
+}
@@ -0,0 +1,71 @@
+package virtualbox
+
+import (
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunOperationWithSleepInhibited(t *testing.T) {
+	defer func(inhibitor func(string) (func(), error)) { sleepInhibitor = inhibitor }(sleepInhibitor)
+	var reason string
+	inhibited := false
+	sleepInhibitor = func(r string) (func(), error) {
+		reason = r
+		inhibited = true
+		return func() { inhibited = false }, nil
+	}
+
+	err := runOperation("clonehd", func() error {
+		require.True(t, inhibited, "sleep should be inhibited while the operation runs")
+		return nil
+	}, WithSleepInhibited())
+	require.NoError(t, err)
+	require.Equal(t, "go-virtualbox: clonehd", reason)
+	require.False(t, inhibited, "sleep inhibition should be released once the operation is done")
+
+	opErr := errors.New("fail")
+	err = runOperation("clonehd", func() error { return opErr }, WithSleepInhibited())
+	require.ErrorIs(t, err, opErr)
+	require.False(t, inhibited, "sleep inhibition should be released when the operation fails")
+}
+
+func TestRunOperationSleepInhibitorFailure(t *testing.T) {
+	defer func(inhibitor func(string) (func(), error)) { sleepInhibitor = inhibitor }(sleepInhibitor)
+	inhibitErr := errors.New("inhibition refused")
+	sleepInhibitor = func(string) (func(), error) { return nil, inhibitErr }
+
+	ran := false
+	err := runOperation("export", func() error {
+		ran = true
+		return nil
+	}, WithSleepInhibited())
+	require.ErrorIs(t, err, inhibitErr)
+	require.False(t, ran, "operation should not run without the requested sleep inhibition")
+
+	// the inhibitor is only used when asked for
+	require.NoError(t, runOperation("export", func() error {
+		ran = true
+		return nil
+	}))
+	require.True(t, ran)
+}
+
+func TestStartInhibitorProcess(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+
+	_, err := startInhibitorProcess("sh", "-c", "echo inhibition refused >&2; exit 1")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "inhibition refused")
+
+	release, err := startInhibitorProcess("sleep", "60")
+	require.NoError(t, err)
+	start := time.Now()
+	release()
+	require.Less(t, time.Since(start), 10*time.Second, "release should stop the inhibitor process")
+}
@@ -0,0 +1,37 @@
+package virtualbox
+
+import (
+	"runtime"
+	"syscall"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	esContinuous     = 0x80000000
+	esSystemRequired = 0x00000001
+)
+
+var procSetThreadExecutionState = syscall.NewLazyDLL("kernel32.dll").NewProc("SetThreadExecutionState")
+
+func inhibitSleep(reason string) (func(), error) {
+	// The execution state is bound to the calling thread, so it is set and reset
+	// from a goroutine locked to its OS thread for the duration of the operation.
+	errC := make(chan error)
+	done := make(chan struct{})
+	go func() {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		if r, _, err := procSetThreadExecutionState.Call(esContinuous | esSystemRequired); r == 0 {
+			errC <- errors.Wrapf(err, "SetThreadExecutionState failed: reason=%s", reason)
+			return
+		}
+		errC <- nil
+		<-done
+		_, _, _ = procSetThreadExecutionState.Call(esContinuous)
+	}()
+	if err := <-errC; err != nil {
+		return nil, err
+	}
+	return func() { close(done) }, nil
+}
@@ -0,0 +1,76 @@
+package virtualbox
+
+import (
+	"strings"
+)
+
+// Runner runs VirtualBox management commands given their arguments (without the program name).
+//
+// Setting a Runner with SetRunner replaces VBoxManage for all operations of this package,
+// e.g. to unit-test code built on top of it against recorded VBoxManage outputs
+// (see package virtualboxtest).
+type Runner interface {
+	Run(args ...string) (stdout string, stderr string, err error)
+}
+
+// SetRunner makes all subsequent operations run their commands with r instead of VBoxManage.
+// The returned function restores the command used before.
+// The guest OS types, which GetMachine lists once to read the OS type of a machine, are listed again with r.
+func SetRunner(r Runner) (restore func()) {
+	previous := manage
+	manage = &runnerCommand{runner: r}
+	setOSTypes(nil)
+	return func() {
+		manage = previous
+		setOSTypes(nil)
+	}
+}
+
+// runnerCommand adapts a Runner to the Command interface.
+type runnerCommand struct {
+	runner   Runner
+	settings command // holds the options set with setOpts
+}
+
+func (rc runnerCommand) setOpts(opts ...option) Command {
+	for _, opt := range opts {
+		opt(&rc.settings)
+	}
+	return &rc
+}
+
+func (rc runnerCommand) isGuest() bool {
+	return false
+}
+
+func (rc runnerCommand) path() string {
+	return "VBoxManage"
+}
+
+func (rc runnerCommand) run(args ...string) error {
+	_, _, err := rc.runOutErr(args...)
+	return err
+}
+
+func (rc runnerCommand) runOut(args ...string) (string, error) {
+	stdout, _, err := rc.runOutErr(args...)
+	return stdout, err
+}
+
+// runOutErr runs the command with the runner, honouring the options set with setOpts: the command is not run
+// once the context is done, and its outputs are also written to the stdout and stderr writers.
+func (rc runnerCommand) runOutErr(args ...string) (string, string, error) {
+	defer timed(args)()
+	if rc.settings.ctx != nil && rc.settings.ctx.Err() != nil {
+		return "", "", rc.settings.ctx.Err()
+	}
+	stdout, stderr, err := rc.runner.Run(args...)
+	if rc.settings.stdout != nil {
+		_, _ = rc.settings.stdout.Write([]byte(stdout))
+	}
+	if rc.settings.stderr != nil {
+		_, _ = rc.settings.stderr.Write([]byte(stderr))
+	}
+	Trace("runner: args=%s, stdout=%s, stderr=%s, err=%v", strings.Join(args, " "), stdout, stderr, err)
+	return stdout, stderr, err
+}
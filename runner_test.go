@@ -0,0 +1,48 @@
+package virtualbox
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type runnerFunc func(args ...string) (string, string, error)
+
+func (f runnerFunc) Run(args ...string) (string, string, error) {
+	return f(args...)
+}
+
+func TestRunnerCommandHonoursOptions(t *testing.T) {
+	runs := 0
+	restore := SetRunner(runnerFunc(func(args ...string) (string, string, error) {
+		runs++
+		return "out", "err", nil
+	}))
+	defer func() {
+		restore()
+		useTestOSTypes() // cleared by SetRunner
+	}()
+
+	var stdout, stderr bytes.Buffer
+	cmd := Manage().setOpts(outWriter(&stdout), errWriter(&stderr))
+	out, err := cmd.runOut("list", "vms")
+	require.NoError(t, err)
+	require.Equal(t, "out", out)
+	_, _, err = cmd.runOutErr("list", "vms")
+	require.NoError(t, err)
+	require.NoError(t, cmd.run("list", "vms"))
+	require.Equal(t, "outoutout", stdout.String())
+	require.Equal(t, "errerrerr", stderr.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	cmd = Manage().setOpts(withContext(ctx))
+	_, err = cmd.runOut("list", "vms")
+	require.ErrorIs(t, err, context.Canceled)
+	_, _, err = cmd.runOutErr("list", "vms")
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, cmd.run("list", "vms"), context.Canceled)
+	require.Equal(t, 3, runs, "commands should not run once the context is done")
+}
@@ -0,0 +1,47 @@
+package virtualbox
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// DiscardSavedState discards the saved state of the machine, which is then powered off.
+// The disks are kept, only the state of the running guest is lost. This is the way out of
+// a saved state which cannot be restored anymore, e.g. after a VirtualBox upgrade.
+// Nothing is done if the machine is not saved.
+func (m *Machine) DiscardSavedState() error {
+	if m.State != Saved {
+		return nil
+	}
+	stdout, stderr, err := Manage().runOutErr("discardstate", m.Name)
+	if err != nil {
+		return errors.Wrapf(err, "fail to discard saved state: vm=%s, stdout=%s, stderr=%s", m.Name, stdout, stderr)
+	}
+	return m.Refresh()
+}
+
+// AdoptSavedState makes the given saved state file (.sav) the saved state of the machine,
+// e.g. a saved state produced on another host. The machine must be powered off (or aborted),
+// otherwise ErrMachineNotPoweredOff is returned. The machine is saved afterwards.
+func (m *Machine) AdoptSavedState(savPath string) error {
+	if m.State != Poweroff && m.State != Aborted {
+		return errors.Wrapf(ErrMachineNotPoweredOff, "fail to adopt saved state: vm=%s, state=%s", m.Name, m.State)
+	}
+	savPath, err := filepath.Abs(savPath)
+	if err != nil {
+		return errors.Wrapf(err, "fail to adopt saved state: vm=%s, file=%s", m.Name, savPath)
+	}
+	if fi, err := os.Stat(savPath); err != nil {
+		return errors.Wrapf(err, "fail to adopt saved state: vm=%s", m.Name)
+	} else if !fi.Mode().IsRegular() {
+		return errors.Errorf("fail to adopt saved state, not a regular file: vm=%s, file=%s", m.Name, savPath)
+	}
+	stdout, stderr, err := Manage().runOutErr("adoptstate", m.Name, savPath)
+	if err != nil {
+		return errors.Wrapf(err, "fail to adopt saved state: vm=%s, file=%s, stdout=%s, stderr=%s",
+			m.Name, savPath, stdout, stderr)
+	}
+	return m.Refresh()
+}
@@ -0,0 +1,78 @@
+package virtualbox
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// testSavedMachine saves the running real machine, which is started again once the test is done.
+func testSavedMachine(t *testing.T) *Machine {
+	t.Helper()
+	m := testMachine(t, "", Running)
+	t.Cleanup(func() {
+		require.NoError(t, m.Refresh())
+		require.NoError(t, m.Start())
+	})
+	require.NoError(t, m.Save())
+	require.NoError(t, m.Refresh())
+	require.Equal(t, Saved, m.State)
+	return m
+}
+
+func TestDiscardSavedState(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", State: Saved}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("discardstate", "go-virtualbox").Return("", "", nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Poweroff, "0")),
+		)
+	} else {
+		m = testSavedMachine(t)
+	}
+	require.NoError(t, m.DiscardSavedState())
+	require.Equal(t, Poweroff, m.State)
+
+	// nothing to discard
+	require.NoError(t, m.DiscardSavedState())
+}
+
+func TestAdoptSavedState(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	dir := t.TempDir()
+	savPath := filepath.Join(dir, "2023-10-16T09-44-26-178Z.sav")
+	m := &Machine{Name: "go-virtualbox", State: Poweroff}
+	if ManageMock != nil {
+		require.NoError(t, os.WriteFile(savPath, []byte("state"), 0600))
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("adoptstate", "go-virtualbox", savPath).Return("", "", nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Saved, "0")),
+		)
+	} else {
+		// the saved state file of the machine, kept aside before discarding it
+		m = testSavedMachine(t)
+		savFiles, err := filepath.Glob(filepath.Join(m.SnapshotFolder, "*.sav"))
+		require.NoError(t, err)
+		require.NotEmpty(t, savFiles)
+		sort.Strings(savFiles)
+		data, err := os.ReadFile(savFiles[len(savFiles)-1])
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(savPath, data, 0600))
+		require.NoError(t, m.DiscardSavedState())
+	}
+	require.Error(t, m.AdoptSavedState(filepath.Join(dir, "missing.sav")), "missing file")
+
+	require.NoError(t, m.AdoptSavedState(savPath))
+	require.Equal(t, Saved, m.State)
+
+	require.ErrorIs(t, m.AdoptSavedState(savPath), ErrMachineNotPoweredOff)
+}
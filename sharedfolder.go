@@ -0,0 +1,83 @@
+package virtualbox
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// SharedFolder is a host folder shared with the guest.
+type SharedFolder struct {
+	Name       string
+	HostPath   string
+	ReadOnly   bool   // not part of the VM info: false for parsed shared folders
+	AutoMount  bool   // not part of the VM info: false for parsed shared folders
+	MountPoint string // auto-mount point in the guest, not part of the VM info
+}
+
+// sharedFoldersFromPropMap reads the permanent (machine mapping) shared folders from a VM info map.
+func sharedFoldersFromPropMap(propMap map[string]string) []SharedFolder {
+	folders := []SharedFolder{}
+	for i := 1; ; i++ {
+		// SharedFolderNameMachineMapping1="vagrant"
+		// SharedFolderPathMachineMapping1="/Users/fix/src/go-virtualbox"
+		name, ok := propMap[fmt.Sprintf("SharedFolderNameMachineMapping%d", i)]
+		if !ok {
+			return folders
+		}
+		folders = append(folders, SharedFolder{
+			Name:     name,
+			HostPath: propMap[fmt.Sprintf("SharedFolderPathMachineMapping%d", i)],
+		})
+	}
+}
+
+// AddSharedFolder adds a permanent shared folder to the machine.
+func (m *Machine) AddSharedFolder(sf SharedFolder) error {
+	args := []string{"sharedfolder", "add", m.Name, "--name", sf.Name, "--hostpath", sf.HostPath}
+	if sf.ReadOnly {
+		args = append(args, "--readonly")
+	}
+	if sf.AutoMount {
+		args = append(args, "--automount")
+	}
+	if sf.MountPoint != "" {
+		args = append(args, "--auto-mount-point", sf.MountPoint)
+	}
+	if stdout, stderr, err := Manage().runOutErr(args...); err != nil {
+		return errors.Wrapf(err, "fail to add shared folder: vm=%s, name=%s, stdout=%s, stderr=%s",
+			m.Name, sf.Name, stdout, stderr)
+	}
+	return nil
+}
+
+// RemoveSharedFolder removes the permanent shared folder with the given name from the machine.
+func (m *Machine) RemoveSharedFolder(name string) error {
+	if stdout, stderr, err := Manage().runOutErr("sharedfolder", "remove", m.Name, "--name", name); err != nil {
+		return errors.Wrapf(err, "fail to remove shared folder: vm=%s, name=%s, stdout=%s, stderr=%s",
+			m.Name, name, stdout, stderr)
+	}
+	return nil
+}
+
+// EnsureSharedFolder adds the shared folder unless the machine already shares the same host path
+// under the same name. A shared folder with the same name but another host path is replaced.
+// It returns whether a change was made, in which case the machine is refreshed.
+func (m *Machine) EnsureSharedFolder(sf SharedFolder) (bool, error) {
+	for _, current := range m.SharedFolders {
+		if current.Name != sf.Name {
+			continue
+		}
+		if current.HostPath == sf.HostPath {
+			return false, nil
+		}
+		if err := m.RemoveSharedFolder(sf.Name); err != nil {
+			return false, err
+		}
+		break
+	}
+	if err := m.AddSharedFolder(sf); err != nil {
+		return true, err
+	}
+	return true, m.Refresh()
+}
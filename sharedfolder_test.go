@@ -0,0 +1,46 @@
+package virtualbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureSharedFolder(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out") +
+		"SharedFolderNameMachineMapping1=\"vagrant\"\nSharedFolderPathMachineMapping1=\"/Users/fix/src/go-virtualbox\"\n"
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	sf := SharedFolder{Name: "vagrant", HostPath: "/Users/fix/src/go-virtualbox"}
+	other := SharedFolder{Name: "vagrant", HostPath: "/Users/fix/src/other", ReadOnly: true, AutoMount: true}
+	if ManageMock != nil {
+		require.Equal(t, []SharedFolder{sf}, m.SharedFolders)
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("sharedfolder", "remove", "go-virtualbox", "--name", "vagrant").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("sharedfolder", "add", "go-virtualbox", "--name", "vagrant",
+				"--hostpath", "/Users/fix/src/other", "--readonly", "--automount").Return("", "", nil).Times(1),
+			expectShowVMInfo("go-virtualbox", strings.Replace(vmInfoOut, sf.HostPath, other.HostPath, 1)),
+		)
+	} else {
+		sf = SharedFolder{Name: "go-virtualbox-test", HostPath: t.TempDir()}
+		other = SharedFolder{Name: sf.Name, HostPath: t.TempDir(), ReadOnly: true, AutoMount: true}
+		t.Cleanup(func() { require.NoError(t, m.RemoveSharedFolder(sf.Name)) })
+		changed, err := m.EnsureSharedFolder(sf)
+		require.NoError(t, err)
+		require.True(t, changed)
+		require.Contains(t, m.SharedFolders, sf)
+	}
+
+	changed, err := m.EnsureSharedFolder(sf)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	changed, err = m.EnsureSharedFolder(other)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.Contains(t, m.SharedFolders, SharedFolder{Name: other.Name, HostPath: other.HostPath}, "machine should have been refreshed")
+}
@@ -0,0 +1,254 @@
+package virtualbox
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slices"
+)
+
+var reNoSnapshots = regexp.MustCompile(`does not have any snapshots`)
+
+var (
+	// ErrSnapshotNotExist is returned when the machine has no snapshot with the given name or UUID.
+	ErrSnapshotNotExist = errors.New("snapshot does not exist")
+	// ErrSnapshotNotRestored is returned when the machine is not in the state of a snapshot after restoring it.
+	ErrSnapshotNotRestored = errors.New("snapshot not restored")
+)
+
+// cleanStatePoweroffTimeout is how long EnsureCleanState waits for the machine to be powered off.
+const cleanStatePoweroffTimeout = 30 * time.Second
+
+// Snapshot is a node of the snapshot tree of a machine.
+type Snapshot struct {
+	Name        string
+	UUID        string
+	Description string
+	Current     bool // true for the snapshot the current state is based on
+	Children    []*Snapshot
+}
+
+// Snapshots returns the root snapshots of the machine (VirtualBox only ever has one), nil if it has no snapshots.
+func (m *Machine) Snapshots() ([]*Snapshot, error) {
+	stdout, stderr, err := Manage().runOutErr("snapshot", m.Name, "list", "--machinereadable")
+	if err != nil {
+		if reNoSnapshots.MatchString(stdout + stderr) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "fail to list snapshots: vm=%s, stdout=%s, stderr=%s", m.Name, stdout, stderr)
+	}
+	propMap, err := vminfoAsPropMap(strings.NewReader(stdout))
+	if err != nil {
+		return nil, err
+	}
+	return snapshotsFromPropMap(propMap), nil
+}
+
+// snapshotsFromPropMap builds the snapshot tree from the SnapshotName[-i[-j...]] keys.
+func snapshotsFromPropMap(propMap map[string]string) []*Snapshot {
+	if _, ok := propMap["SnapshotName"]; !ok {
+		return nil
+	}
+	return []*Snapshot{snapshotFromPropMap(propMap, "", propMap["CurrentSnapshotUUID"])}
+}
+
+func snapshotFromPropMap(propMap map[string]string, node, currentUUID string) *Snapshot {
+	s := &Snapshot{
+		Name:        propMap["SnapshotName"+node],
+		UUID:        propMap["SnapshotUUID"+node],
+		Description: propMap["SnapshotDescription"+node],
+	}
+	s.Current = s.UUID != "" && s.UUID == currentUUID
+	for i := 1; ; i++ {
+		child := fmt.Sprintf("%s-%d", node, i)
+		if _, ok := propMap["SnapshotName"+child]; !ok {
+			break
+		}
+		s.Children = append(s.Children, snapshotFromPropMap(propMap, child, currentUUID))
+	}
+	return s
+}
+
+// DeleteAllSnapshots deletes all the snapshots of the machine, leaves first so that VirtualBox never
+// has to merge a snapshot having several children. See DeleteAllSnapshotsProgress.
+func (m *Machine) DeleteAllSnapshots() error {
+	return m.DeleteAllSnapshotsProgress(nil)
+}
+
+// DeleteAllSnapshotsProgress deletes all the snapshots of the machine like DeleteAllSnapshots,
+// streaming the VBoxManage output, including the progress of the disk merges, to progress if not nil.
+func (m *Machine) DeleteAllSnapshotsProgress(progress io.Writer) error {
+	roots, err := m.Snapshots()
+	if err != nil {
+		return err
+	}
+	for _, root := range roots {
+		if err := m.deleteSnapshotTree(root, progress); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Machine) deleteSnapshotTree(s *Snapshot, progress io.Writer) error {
+	for _, child := range s.Children {
+		if err := m.deleteSnapshotTree(child, progress); err != nil {
+			return err
+		}
+	}
+	vbm := Manage()
+	if progress != nil {
+		vbm = vbm.setOpts(outWriter(progress), errWriter(progress))
+	}
+	if err := vbm.run("snapshot", m.Name, "delete", s.UUID); err != nil {
+		return errors.Wrapf(err, "fail to delete snapshot: vm=%s, snapshot=%s, uuid=%s", m.Name, s.Name, s.UUID)
+	}
+	return nil
+}
+
+// RestoreSnapshot restores the given snapshot (name or UUID) of the machine, which must not be running.
+// See EnsureCleanState to also power off the machine and verify the restore.
+func (m *Machine) RestoreSnapshot(snapshot string) error {
+	stdout, stderr, err := Manage().runOutErr("snapshot", m.Name, "restore", snapshot)
+	if err != nil {
+		return errors.Wrapf(err, "fail to restore snapshot: vm=%s, snapshot=%s, stdout=%s, stderr=%s",
+			m.Name, snapshot, stdout, stderr)
+	}
+	return m.Refresh()
+}
+
+// EnsureCleanState resets the machine to the given snapshot (name or UUID), e.g. to get a pristine VM
+// before each test of a CI suite: the machine is powered off if needed, the snapshot is restored, then
+// the machine is read again to verify that its current snapshot is the expected one.
+//
+// ErrSnapshotNotExist is returned if the machine has no such snapshot and ErrSnapshotNotRestored if
+// the machine is not in the state of the snapshot afterwards.
+func (m *Machine) EnsureCleanState(snapshot string) error {
+	if err := m.Refresh(); err != nil {
+		return err
+	}
+	roots, err := m.Snapshots()
+	if err != nil {
+		return err
+	}
+	s := findSnapshot(roots, snapshot)
+	if s == nil {
+		return errors.Wrapf(ErrSnapshotNotExist, "fail to ensure clean state: vm=%s, snapshot=%s", m.Name, snapshot)
+	}
+	switch m.State {
+	case Running, Paused, GuruMeditation:
+		if err := m.Poweroff(); err != nil {
+			return err
+		}
+		if err := m.WaitForState(Poweroff, cleanStatePoweroffTimeout); err != nil {
+			return err
+		}
+	}
+	if err := m.RestoreSnapshot(s.UUID); err != nil {
+		return err
+	}
+	switch {
+	case m.CurrentSnapshot != s.UUID:
+		return errors.Wrapf(ErrSnapshotNotRestored, "vm=%s, snapshot=%s, expected=%s, current=%s",
+			m.Name, snapshot, s.UUID, m.CurrentSnapshot)
+	case m.State != Poweroff && m.State != Saved:
+		return errors.Wrapf(ErrSnapshotNotRestored, "vm=%s, snapshot=%s, state=%s", m.Name, snapshot, m.State)
+	}
+	return nil
+}
+
+// findSnapshot returns the snapshot with the given name or UUID in the given trees, nil if none.
+func findSnapshot(snapshots []*Snapshot, nameOrUUID string) *Snapshot {
+	for _, s := range snapshots {
+		if s.UUID == nameOrUUID || s.Name == nameOrUUID {
+			return s
+		}
+		if found := findSnapshot(s.Children, nameOrUUID); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// SnapshotDiskChain returns the disks of the given snapshot (name or UUID) with their differencing image chains:
+// for each disk attached to the machine, its base medium followed by the differencing images, up to the
+// image the snapshot is attached to. The SizeMB of each medium tells how much disk space the chain uses.
+//
+// The disks are searched from the media currently attached to the machine, which must then have been read
+// with GetMachine. ErrSnapshotNotExist is returned if the machine has no such snapshot.
+func (m *Machine) SnapshotDiskChain(snapshot string) ([]Medium, error) {
+	roots, err := m.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	s := findSnapshot(roots, snapshot)
+	if s == nil {
+		return nil, errors.Wrapf(ErrSnapshotNotExist, "vm=%s, snapshot=%s", m.Name, snapshot)
+	}
+	var chains []Medium
+	for _, medium := range m.StorageControllers.DeviceMedia() {
+		if !isAttachedMedium(medium) {
+			continue
+		}
+		md, err := diskMediumInfo(medium)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to get snapshot disk chain: vm=%s, snapshot=%s, medium=%s",
+				m.Name, snapshot, medium)
+		}
+		if md == nil {
+			continue
+		}
+		chain, err := snapshotMediumChain(md, s.UUID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fail to get snapshot disk chain: vm=%s, snapshot=%s, medium=%s",
+				m.Name, snapshot, medium)
+		}
+		chains = append(chains, chain...)
+	}
+	return chains, nil
+}
+
+// snapshotMediumChain returns the chain, base first, of the medium in the tree of the given medium
+// which is attached to the given snapshot, nil if none is.
+func snapshotMediumChain(md *Medium, snapshotUUID string) ([]Medium, error) {
+	chain, err := mediumChainFrom(md)
+	if err != nil {
+		return nil, err
+	}
+	// base first
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	// the snapshot is usually an ancestor of the current state
+	for i, md := range chain {
+		if slices.Contains(md.SnapshotUUIDs, snapshotUUID) {
+			return chain[:i+1], nil
+		}
+	}
+	// otherwise it is on another branch of the snapshot tree
+	return searchSnapshotMedium(chain[:1], snapshotUUID)
+}
+
+// searchSnapshotMedium searches the descendants of the last medium of the given chain for the medium
+// attached to the given snapshot, and returns its chain.
+func searchSnapshotMedium(chain []Medium, snapshotUUID string) ([]Medium, error) {
+	last := chain[len(chain)-1]
+	if slices.Contains(last.SnapshotUUIDs, snapshotUUID) {
+		return chain, nil
+	}
+	for _, childUUID := range last.ChildUUIDs {
+		child, err := MediumInfo(childUUID)
+		if err != nil {
+			return nil, err
+		}
+		found, err := searchSnapshotMedium(append(chain[:len(chain):len(chain)], *child), snapshotUUID)
+		if found != nil || err != nil {
+			return found, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,284 @@
+package virtualbox
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+// testMachineWithoutSnapshots returns the powered off real machine, whose snapshots are all deleted once
+// the test is done. The test is skipped if the machine already has snapshots.
+func testMachineWithoutSnapshots(t *testing.T) *Machine {
+	t.Helper()
+	m := testMachine(t, "", Poweroff, Aborted)
+	roots, err := m.Snapshots()
+	require.NoError(t, err)
+	if len(roots) > 0 {
+		t.Skipf("requires %s without snapshots", m.Name)
+	}
+	t.Cleanup(func() { require.NoError(t, m.DeleteAllSnapshots()) })
+	return m
+}
+
+// takeSnapshot takes a snapshot of the real machine and returns its UUID.
+func takeSnapshot(t *testing.T, m *Machine, name, description string) string {
+	t.Helper()
+	require.NoError(t, Manage().run("snapshot", m.Name, "take", name, "--description", description))
+	require.NoError(t, m.Refresh())
+	return m.CurrentSnapshot
+}
+
+// takeSnapshotTree takes the snapshots of vboxmanage-snapshot-list-1.out of the real machine: base with
+// the children provisioned, which has the current child test-run, and hotfix. It returns their UUIDs by name.
+func takeSnapshotTree(t *testing.T, m *Machine, provisionedDescription string) map[string]string {
+	t.Helper()
+	uuids := map[string]string{}
+	uuids["base"] = takeSnapshot(t, m, "base", "")
+	uuids["provisioned"] = takeSnapshot(t, m, "provisioned", provisionedDescription)
+	uuids["test-run"] = takeSnapshot(t, m, "test-run", "")
+	require.NoError(t, m.RestoreSnapshot(uuids["base"]))
+	uuids["hotfix"] = takeSnapshot(t, m, "hotfix", "")
+	require.NoError(t, m.RestoreSnapshot(uuids["test-run"]))
+	return uuids
+}
+
+func TestSnapshots(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	testRunUUID := "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a03"
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("snapshot", "go-virtualbox", "list", "--machinereadable").
+			Return(ReadTestData("vboxmanage-snapshot-list-1.out"), "", nil).Times(1)
+	} else {
+		m = testMachineWithoutSnapshots(t)
+		testRunUUID = takeSnapshotTree(t, m, "after provisioning")["test-run"]
+	}
+
+	roots, err := m.Snapshots()
+	require.NoError(t, err)
+	require.Len(t, roots, 1)
+	base := roots[0]
+	require.Equal(t, "base", base.Name)
+	require.Len(t, base.Children, 2)
+	require.Equal(t, "provisioned", base.Children[0].Name)
+	require.Equal(t, "after provisioning", base.Children[0].Description)
+	require.Equal(t, "hotfix", base.Children[1].Name)
+	require.Len(t, base.Children[0].Children, 1)
+	testRun := base.Children[0].Children[0]
+	require.Equal(t, testRunUUID, testRun.UUID)
+	require.True(t, testRun.Current)
+	require.False(t, base.Current)
+}
+
+func TestSnapshotsNone(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("snapshot", "go-virtualbox", "list", "--machinereadable").
+			Return("This machine does not have any snapshots\n", "", errors.New("exit status 1")).Times(1)
+	} else {
+		m = testMachineWithoutSnapshots(t)
+	}
+
+	roots, err := m.Snapshots()
+	require.NoError(t, err)
+	require.Empty(t, roots)
+}
+
+func TestDeleteAllSnapshotsProgress(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	var progress bytes.Buffer
+	if ManageMock != nil {
+		calls := []*gomock.Call{
+			ManageMock.EXPECT().runOutErr("snapshot", "go-virtualbox", "list", "--machinereadable").
+				Return(ReadTestData("vboxmanage-snapshot-list-1.out"), "", nil).Times(1),
+		}
+		// leaves first: test-run, provisioned, hotfix, then base
+		for _, uuid := range []string{"0a03", "0a02", "0a04", "0a01"} {
+			calls = append(calls,
+				ManageMock.EXPECT().setOpts(gomock.Any(), gomock.Any()).Return(ManageMock).Times(1),
+				ManageMock.EXPECT().run("snapshot", "go-virtualbox", "delete", "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c"+uuid).
+					Return(nil).Times(1))
+		}
+		calls = append(calls,
+			ManageMock.EXPECT().runOutErr("snapshot", "go-virtualbox", "list", "--machinereadable").
+				Return("This machine does not have any snapshots\n", "", errors.New("exit status 1")).Times(1))
+		gomock.InOrder(calls...)
+	} else {
+		m = testMachineWithoutSnapshots(t)
+		takeSnapshotTree(t, m, "")
+	}
+
+	require.NoError(t, m.DeleteAllSnapshotsProgress(&progress))
+	roots, err := m.Snapshots()
+	require.NoError(t, err)
+	require.Empty(t, roots)
+}
+
+func TestSnapshotDiskChain(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "worker2", StorageControllers: StorageControllers{
+		{Name: "SATA", Devices: []StorageMedium{{Medium: "/media/bigstorage/Snapshots/{3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf}.vdi"}}},
+	}}
+	baseUUID := "8c80c269-8569-4c90-b745-bac723810dab"
+	if ManageMock != nil {
+		snapshots := `SnapshotName="base"
+SnapshotUUID="1bd0f0a6-a0ab-4e36-b7a5-5ea0a3a2d58f"
+CurrentSnapshotName="base"
+CurrentSnapshotUUID="1bd0f0a6-a0ab-4e36-b7a5-5ea0a3a2d58f"
+CurrentSnapshotNode="SnapshotName"
+`
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("snapshot", "worker2", "list", "--machinereadable").
+				Return(snapshots, "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/media/bigstorage/Snapshots/{3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf}.vdi").
+				Return(ReadTestData("vboxmanage-showmediuminfo-diff-1.out"), "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "8c80c269-8569-4c90-b745-bac723810dab").
+				Return(ReadTestData("vboxmanage-showmediuminfo-base-1.out"), "", nil).Times(1),
+		)
+	} else {
+		m = testMachineWithoutSnapshots(t)
+		baseUUID = testSnapshotBaseDisk(t, m)
+		takeSnapshot(t, m, "base", "")
+	}
+
+	chain, err := m.SnapshotDiskChain("base")
+	require.NoError(t, err)
+	require.Len(t, chain, 1)
+	require.Equal(t, baseUUID, chain[0].UUID)
+	if ManageMock != nil {
+		require.Equal(t, uint64(2379), chain[0].SizeMB)
+	}
+}
+
+// testSnapshotBaseDisk returns the UUID of the first disk of the real machine, skipping the test if it has none.
+func testSnapshotBaseDisk(t *testing.T, m *Machine) string {
+	t.Helper()
+	disk := attachedDisk(t, m)
+	if disk == "" {
+		t.Skipf("requires %s to have a disk", m.Name)
+	}
+	md, err := diskMediumInfo(disk)
+	require.NoError(t, err)
+	return md.UUID
+}
+
+func TestSnapshotDiskChainOtherBranch(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "worker2", StorageControllers: StorageControllers{
+		{Name: "SATA", Devices: []StorageMedium{{Medium: "/media/bigstorage/worker2.vdi"}}},
+	}}
+	baseUUID, experimentUUID := "8c80c269-8569-4c90-b745-bac723810dab", "77f1a0c2-6d0e-4b5f-9a54-1c2f4a6b8e90"
+	snapshots := `SnapshotName="base"
+SnapshotUUID="1bd0f0a6-a0ab-4e36-b7a5-5ea0a3a2d58f"
+SnapshotName-1="experiment"
+SnapshotUUID-1="77f1a0c2-6d0e-4b5f-9a54-1c2f4a6b8e90"
+`
+	if ManageMock != nil {
+		diff := strings.Replace(ReadTestData("vboxmanage-showmediuminfo-diff-1.out"),
+			"(UUID: 6ad3c1f2-5b0b-4d66-a3b0-0f56b8e3d4c1)",
+			"(UUID: 6ad3c1f2-5b0b-4d66-a3b0-0f56b8e3d4c1) [experiment (UUID: 77f1a0c2-6d0e-4b5f-9a54-1c2f4a6b8e90)]", 1)
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("snapshot", "worker2", "list", "--machinereadable").
+				Return(snapshots, "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "/media/bigstorage/worker2.vdi").
+				Return(ReadTestData("vboxmanage-showmediuminfo-base-1.out"), "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf").
+				Return(diff, "", nil).Times(1),
+		)
+	} else {
+		// the current state is based on base, experiment is on another branch
+		m = testMachineWithoutSnapshots(t)
+		baseUUID = testSnapshotBaseDisk(t, m)
+		base := takeSnapshot(t, m, "base", "")
+		experimentUUID = takeSnapshot(t, m, "experiment", "")
+		require.NoError(t, m.RestoreSnapshot(base))
+	}
+
+	chain, err := m.SnapshotDiskChain(experimentUUID)
+	require.NoError(t, err)
+	require.Len(t, chain, 2)
+	require.Equal(t, baseUUID, chain[0].UUID)
+	require.Contains(t, chain[1].SnapshotUUIDs, experimentUUID)
+	if ManageMock != nil {
+		require.Equal(t, "3a9e3f4b-0f1e-4b9a-8ad4-a1f4ff33b6cf", chain[1].UUID)
+		ManageMock.EXPECT().runOutErr("snapshot", "worker2", "list", "--machinereadable").
+			Return(snapshots, "", nil).Times(1)
+	}
+	_, err = m.SnapshotDiskChain("missing")
+	require.ErrorIs(t, err, ErrSnapshotNotExist)
+}
+
+func TestEnsureCleanState(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox"}
+	provisionedUUID := "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a02"
+	vmInfo := func(state MachineState, currentSnapshot string) string {
+		return vmInfoWithState(state, "0") + `CurrentSnapshotUUID="` + currentSnapshot + `"` + "\n"
+	}
+	expectSnapshotList := func() *gomock.Call {
+		return ManageMock.EXPECT().runOutErr("snapshot", "go-virtualbox", "list", "--machinereadable").
+			Return(ReadTestData("vboxmanage-snapshot-list-1.out"), "", nil).Times(1)
+	}
+	expectRestore := func() *gomock.Call {
+		return ManageMock.EXPECT().runOutErr("snapshot", "go-virtualbox", "restore", "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a02").
+			Return("", "", nil).Times(1)
+	}
+
+	if ManageMock != nil {
+		gomock.InOrder(
+			expectShowVMInfo("go-virtualbox", vmInfo(Running, "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a03")),
+			expectSnapshotList(),
+			ManageMock.EXPECT().run("controlvm", "go-virtualbox", "poweroff").Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfo(Running, "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a03")),
+			expectShowVMInfo("go-virtualbox", vmInfo(Poweroff, "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a03")),
+			expectRestore(),
+			expectShowVMInfo("go-virtualbox", vmInfo(Poweroff, "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a02")),
+		)
+	} else {
+		m = testMachineWithoutSnapshots(t)
+		provisionedUUID = takeSnapshot(t, m, "provisioned", "")
+		takeSnapshot(t, m, "test-run", "")
+		require.NoError(t, m.Start())
+		poweroffOnCleanup(t, m)
+	}
+	require.NoError(t, m.EnsureCleanState("provisioned"))
+	require.Equal(t, provisionedUUID, m.CurrentSnapshot)
+	require.Equal(t, Poweroff, m.State)
+
+	if ManageMock != nil {
+		gomock.InOrder(
+			expectShowVMInfo("go-virtualbox", vmInfo(Poweroff, "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a03")),
+			expectSnapshotList(),
+			expectRestore(),
+			expectShowVMInfo("go-virtualbox", vmInfo(Poweroff, "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a03")),
+		)
+		require.ErrorIs(t, m.EnsureCleanState("provisioned"), ErrSnapshotNotRestored)
+
+		gomock.InOrder(
+			expectShowVMInfo("go-virtualbox", vmInfo(Poweroff, "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a03")),
+			expectSnapshotList(),
+		)
+	}
+	require.ErrorIs(t, m.EnsureCleanState("pristine"), ErrSnapshotNotExist)
+}
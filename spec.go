@@ -0,0 +1,115 @@
+package virtualbox
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// VMSpec is a declarative description of a machine, which can be used to recreate an equivalent machine.
+type VMSpec struct {
+	Name               string             `json:"name"`
+	OSType             string             `json:"ostype,omitempty"`
+	CPUs               uint               `json:"cpus"`
+	Memory             uint               `json:"memory"` // main memory (in MB)
+	VRAM               uint               `json:"vram"`   // video memory (in MB)
+	Flag               Flag               `json:"flag"`
+	BootOrder          []string           `json:"bootOrder,omitempty"`
+	NICs               []NIC              `json:"nics,omitempty"`
+	UARTs              UARTs              `json:"uarts,omitempty"`
+	StorageControllers StorageControllers `json:"storageControllers,omitempty"`
+	ProcessPriority    string             `json:"processPriority,omitempty"`
+}
+
+// ToSpec returns the spec of this machine.
+// Media are referenced by location only, as their UUIDs are specific to the host they are registered with.
+func (m *Machine) ToSpec() VMSpec {
+	spec := VMSpec{
+		Name:            m.Name,
+		OSType:          m.OSType,
+		CPUs:            m.CPUs,
+		Memory:          m.Memory,
+		VRAM:            m.VRAM,
+		Flag:            m.Flag,
+		BootOrder:       append([]string(nil), m.BootOrder...),
+		NICs:            append([]NIC(nil), m.NICs...),
+		UARTs:           append(UARTs(nil), m.UARTs...),
+		ProcessPriority: m.ProcessPriority,
+	}
+	for _, sc := range m.StorageControllers {
+		sc.Devices = append([]StorageMedium(nil), sc.Devices...)
+		for i := range sc.Devices {
+			sc.Devices[i].UUID = ""
+		}
+		spec.StorageControllers = append(spec.StorageControllers, sc)
+	}
+	return spec
+}
+
+// ToJSON returns the JSON representation of this spec.
+func (spec VMSpec) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(spec, "", "  ")
+}
+
+// FromJSON reads a spec from its JSON representation.
+func FromJSON(data []byte) (*VMSpec, error) {
+	spec := VMSpec{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, errors.Wrap(err, "fail to read vm spec from json")
+	}
+	return &spec, nil
+}
+
+// FromSpec creates and registers a new machine as described by the given spec:
+// the machine settings are applied with Modify, then its storage controllers are added
+// and their media attached. The machine is unregistered and deleted if any of these steps fails.
+func FromSpec(spec VMSpec) (*Machine, error) {
+	uuid, err := newUUID()
+	if err != nil {
+		return nil, errors.Wrap(err, "fail to generate machine uuid")
+	}
+	m, err := CreateMachine(uuid, spec.Name, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to create machine from spec: name=%s", spec.Name)
+	}
+	m.OSType = spec.OSType
+	m.CPUs = spec.CPUs
+	m.Memory = spec.Memory
+	m.VRAM = spec.VRAM
+	m.Flag = spec.Flag
+	m.BootOrder = spec.BootOrder
+	m.NICs = spec.NICs
+	if len(spec.UARTs) > 0 {
+		m.UARTs = spec.UARTs
+	}
+	m.ProcessPriority = spec.ProcessPriority
+	if err := m.Modify(); err != nil {
+		return nil, rollbackCreation(m, errors.Wrapf(err, "fail to apply spec to machine: name=%s", spec.Name))
+	}
+	for _, sc := range spec.StorageControllers {
+		if err := m.AddStorageCtl(sc.Name, sc); err != nil {
+			return nil, rollbackCreation(m, errors.Wrapf(err, "fail to add storage controller: vm=%s, controller=%s",
+				m.Name, sc.Name))
+		}
+		for _, medium := range sc.Devices {
+			if medium.DriveType == "" {
+				medium.DriveType = guessDriveType(medium.Medium)
+			}
+			if err := m.AttachStorage(sc.Name, medium); err != nil {
+				return nil, rollbackCreation(m, errors.Wrapf(err, "fail to attach storage: vm=%s, controller=%s, medium=%s",
+					m.Name, sc.Name, medium.Medium))
+			}
+		}
+	}
+	return m, m.Refresh()
+}
+
+// guessDriveType returns the drive type of the given medium, as it is not part of the VM info.
+func guessDriveType(medium string) DriveType {
+	if medium == "emptydrive" || strings.EqualFold(filepath.Ext(medium), ".iso") {
+		return DriveDVD
+	}
+	return DriveHDD
+}
@@ -0,0 +1,142 @@
+package virtualbox
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVMSpecJSONRoundTrip(t *testing.T) {
+	propMap, err := vminfoAsPropMap(strings.NewReader(ReadTestData("vboxmanage-showvminfo-1.out")))
+	require.NoError(t, err)
+	m, err := newMachineFromPropMap(propMap)
+	require.NoError(t, err)
+
+	spec := m.ToSpec()
+	data, err := spec.ToJSON()
+	require.NoError(t, err)
+	readSpec, err := FromJSON(data)
+	require.NoError(t, err)
+
+	require.Equal(t, spec, *readSpec)
+	require.Equal(t, "Ubuntu_64", readSpec.OSType, "os type id of ostype=\"Ubuntu (64-bit)\"")
+	require.Equal(t, []string{"disk", "dvd", "none", "none"}, readSpec.BootOrder)
+	require.Equal(t, "/Users/fix/VirtualBox VMs/go-virtualbox/ubuntu-16.04-amd64-disk001.vmdk",
+		readSpec.StorageControllers[1].Devices[0].Medium)
+	require.Empty(t, readSpec.StorageControllers[1].Devices[0].UUID, "medium UUIDs are host specific")
+	require.NotEmpty(t, m.StorageControllers[1].Devices[0].UUID, "machine should not be altered")
+}
+
+func TestFromSpec(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	spec := VMSpec{
+		Name:      "go-virtualbox",
+		OSType:    "Ubuntu_64",
+		CPUs:      2,
+		Memory:    2048,
+		BootOrder: []string{"disk", "dvd", "none", "none"},
+		StorageControllers: StorageControllers{{
+			Name: "SATA Controller", SysBus: SysBusSATA, Ports: 1, Chipset: CtrlIntelAHCI, Bootable: true,
+			Devices: []StorageMedium{{Medium: "/vms/disk001.vmdk"}, {Port: 1, Medium: "/isos/ubuntu.iso"}},
+		}},
+	}
+	var modifyArgs []string
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOut("list", "vms").Return("", nil).Times(1),
+			ManageMock.EXPECT().run("createvm", "--uuid", gomock.Any(), "--name", "go-virtualbox", "--register").
+				Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoOut),
+			ManageMock.EXPECT().runOutErr(gomock.Any()).DoAndReturn(func(args ...string) (string, string, error) {
+				modifyArgs = args
+				return "", "", nil
+			}).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoOut),
+			ManageMock.EXPECT().run("storagectl", "go-virtualbox", "--name", "SATA Controller", "--add", "sata",
+				"--portcount", "1", "--controller", "IntelAHCI", "--hostiocache", "off", "--bootable", "on").
+				Return(nil).Times(1),
+			ManageMock.EXPECT().run("storageattach", "go-virtualbox", "--storagectl", "SATA Controller",
+				"--port", "0", "--device", "0", "--type", "hdd", "--medium", "/vms/disk001.vmdk").
+				Return(nil).Times(1),
+			ManageMock.EXPECT().run("storageattach", "go-virtualbox", "--storagectl", "SATA Controller",
+				"--port", "1", "--device", "0", "--type", "dvddrive", "--medium", "/isos/ubuntu.iso").
+				Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", strings.NewReplacer("memory=1024", "memory=2048", "cpus=1", "cpus=2").Replace(vmInfoOut)),
+		)
+	} else {
+		// no installation medium at hand, only a blank disk
+		spec.Name = "go-virtualbox-test-spec"
+		spec.StorageControllers[0].Devices = []StorageMedium{{Medium: testDiskImage(t)}}
+		t.Cleanup(func() {
+			if _, err := GetMachine(spec.Name); err == nil {
+				require.NoError(t, Manage().run("unregistervm", spec.Name, "--delete"))
+			}
+		})
+	}
+
+	m, err := FromSpec(spec)
+
+	require.NoError(t, err)
+	require.Equal(t, spec.Name, m.Name)
+	require.Equal(t, []uint{2, 2048}, []uint{m.CPUs, m.Memory})
+	require.Equal(t, spec.OSType, m.OSType)
+	require.Equal(t, spec.BootOrder, m.BootOrder)
+	if ManageMock != nil {
+		cpus, _ := argValue(modifyArgs, "--cpus")
+		memory, _ := argValue(modifyArgs, "--memory")
+		require.Equal(t, []string{"2", "2048"}, []string{cpus, memory})
+		osType, _ := argValue(modifyArgs, "--ostype")
+		require.Equal(t, "Ubuntu_64", osType)
+	} else {
+		require.Equal(t, spec.StorageControllers[0].Devices[0].Medium, attachedDisk(t, m))
+	}
+}
+
+func TestFromSpecRollback(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	spec := VMSpec{
+		Name: "go-virtualbox",
+		StorageControllers: StorageControllers{{
+			Name: "SATA Controller", SysBus: SysBusSATA, Ports: 1, Chipset: CtrlIntelAHCI, Bootable: true,
+			Devices: []StorageMedium{{Medium: "/vms/missing.vdi"}},
+		}},
+	}
+	if ManageMock != nil {
+		vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+		gomock.InOrder(
+			ManageMock.EXPECT().runOut("list", "vms").Return("", nil).Times(1),
+			ManageMock.EXPECT().run("createvm", "--uuid", gomock.Any(), "--name", "go-virtualbox", "--register").
+				Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoOut),
+			ManageMock.EXPECT().runOutErr(gomock.Any()).Return("", "", nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoOut),
+			ManageMock.EXPECT().run("storagectl", "go-virtualbox", "--name", "SATA Controller", "--add", "sata",
+				"--portcount", "1", "--controller", "IntelAHCI", "--hostiocache", "off", "--bootable", "on").
+				Return(nil).Times(1),
+			ManageMock.EXPECT().run("storageattach", "go-virtualbox", "--storagectl", "SATA Controller",
+				"--port", "0", "--device", "0", "--type", "hdd", "--medium", "/vms/missing.vdi").
+				Return(errors.New("exit status 1")).Times(1),
+			ManageMock.EXPECT().run("unregistervm", "go-virtualbox", "--delete").Return(nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+				Return("", "VBoxManage: error: Could not find a registered machine named 'go-virtualbox'", errors.New("exit status 1")).Times(1),
+		)
+	} else {
+		spec.Name = "go-virtualbox-test-spec-rollback"
+		spec.StorageControllers[0].Devices[0].Medium = filepath.Join(t.TempDir(), "missing.vdi")
+	}
+
+	m, err := FromSpec(spec)
+	require.Error(t, err)
+	require.Nil(t, m)
+	_, err = GetMachine(spec.Name)
+	require.ErrorIs(t, err, ErrMachineNotExist, "the partial machine should have been deleted")
+}
@@ -1,6 +1,7 @@
 package virtualbox
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 
@@ -107,8 +108,55 @@ func (sm StorageMedium) IsNone() bool {
 }
 
 // CloneHD virtual harddrive
-func CloneHD(input, output string) error {
-	return Manage().run("clonehd", input, output)
+func CloneHD(input, output string, opts ...OperationOption) error {
+	return runOperation("clonehd", func() error {
+		return Manage().run("clonehd", input, output)
+	}, opts...)
+}
+
+// CreateDisk creates a new empty (dynamically allocated) VDI disk image with the given capacity in MB.
+func CreateDisk(filename string, sizeMB uint64) error {
+	stdout, stderr, err := Manage().runOutErr("createmedium", "disk", "--filename", filename,
+		"--size", strconv.FormatUint(sizeMB, 10), "--format", string(DiskFormatVDI))
+	if err != nil {
+		return errors.Wrapf(err, "fail to create disk: filename=%q, size=%dMB, stdout=%s, stderr=%s",
+			filename, sizeMB, stdout, stderr)
+	}
+	return nil
+}
+
+// CloneHDCtx clones a virtual harddrive like CloneHD, cancelling the clone when ctx is done.
+// The partially written output is then removed and the returned error wraps ctx.Err().
+func CloneHDCtx(ctx context.Context, input, output string, opts ...OperationOption) error {
+	outputExisted := fileExists(output)
+	return runOperation("clonehd", func() error {
+		err := Manage().setOpts(withContext(ctx)).run("clonehd", input, output)
+		if errCancel := cancelled(ctx, "clonehd", output, outputExisted); errCancel != nil {
+			return errCancel
+		}
+		return err
+	}, opts...)
+}
+
+// CloneHDNewUUID clones a virtual harddrive like CloneHD and makes sure the clone got a UUID of its own,
+// returning it. VBoxManage assigns a new UUID to clones; should the clone still carry the UUID of
+// its source, e.g. as when disk files are copied around, a fresh one is set on the clone.
+func CloneHDNewUUID(input, output string, opts ...OperationOption) (string, error) {
+	src, err := MediumInfo(input)
+	if err != nil {
+		return "", err
+	}
+	if err := CloneHD(input, output, opts...); err != nil {
+		return "", errors.Wrapf(err, "fail to clone disk: input=%q, output=%q", input, output)
+	}
+	clone, err := MediumInfo(output)
+	if err != nil {
+		return "", err
+	}
+	if clone.UUID != src.UUID {
+		return clone.UUID, nil
+	}
+	return SetMediumUUID(output, "")
 }
 
 func findStorageControllerByIndex(
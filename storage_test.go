@@ -3,8 +3,14 @@ package virtualbox
 import (
 	"bytes"
 	"io/ioutil"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
 )
 
 func TestNewStorageControllersFromProps(t *testing.T) {
@@ -91,3 +97,100 @@ func vmPropMapFromLocalRes(t *testing.T, resRelPath string) (vmPropMap map[strin
 	}
 	return vmPropMap
 }
+
+func TestEnsureStorageCtl(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	sata := StorageController{SysBus: SysBusSATA, Ports: 1, Chipset: CtrlIntelAHCI, Bootable: true}
+	sataName, nvmeName := "SATA Controller", "NVMe"
+	if ManageMock != nil {
+		vmInfoOut = strings.Replace(vmInfoOut, `storagecontrollerportcount1="1"`, `storagecontrollerportcount1="4"`, 1)
+		gomock.InOrder(
+			ManageMock.EXPECT().run("storagectl", "go-virtualbox", "--name", "SATA Controller", "--portcount", "4").
+				Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoOut),
+			ManageMock.EXPECT().run("storagectl", "go-virtualbox", "--name", "NVMe", "--add", "pcie",
+				"--portcount", "1", "--controller", "NVMe", "--hostiocache", "off", "--bootable", "off").
+				Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoOut+`storagecontrollername2="NVMe"
+storagecontrollertype2="NVMe"
+storagecontrollerinstance2="0"
+storagecontrollermaxportcount2="255"
+storagecontrollerportcount2="1"
+storagecontrollerbootable2="off"
+`),
+		)
+	} else {
+		sataName, nvmeName = "go-virtualbox-test-sata", "go-virtualbox-test-nvme"
+		t.Cleanup(func() {
+			require.NoError(t, m.Refresh())
+			for _, ctl := range m.StorageControllers {
+				if ctl.Name == sataName || ctl.Name == nvmeName {
+					require.NoError(t, m.DelStorageCtl(ctl.Name))
+				}
+			}
+		})
+		require.NoError(t, m.EnsureStorageCtl(sataName, sata))
+	}
+	ctlIndex := func(name string) int {
+		return slices.IndexFunc(m.StorageControllers, func(ctl StorageController) bool { return ctl.Name == name })
+	}
+
+	// already as desired
+	require.NoError(t, m.EnsureStorageCtl(sataName, sata))
+
+	// differing port count
+	sata.Ports = 4
+	require.NoError(t, m.EnsureStorageCtl(sataName, sata))
+	i := ctlIndex(sataName)
+	require.NotEqual(t, -1, i)
+	require.Equal(t, uint(4), m.StorageControllers[i].Ports)
+
+	// missing
+	require.NoError(t, m.EnsureStorageCtl(nvmeName, StorageController{SysBus: SysBusPCI, Ports: 1, Chipset: CtlrNVMe}))
+	require.NotEqual(t, -1, ctlIndex(nvmeName))
+}
+
+func TestCloneHDNewUUID(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	src, dst := "worker2.vdi", "worker3.vdi"
+	srcUUID := "8c80c269-8569-4c90-b745-bac723810dab"
+	var newUUID string
+	if ManageMock != nil {
+		// clone carrying the UUID of its source, e.g. a copied disk file
+		info := ReadTestData("vboxmanage-showmediuminfo-base-1.out")
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "worker2.vdi").Return(info, "", nil).Times(1),
+			ManageMock.EXPECT().run("clonehd", "worker2.vdi", "worker3.vdi").Return(nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showmediuminfo", "disk", "worker3.vdi").Return(info, "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("modifymedium", "disk", "worker3.vdi", "--setuuid", gomock.Any()).
+				DoAndReturn(func(args ...string) (string, string, error) {
+					newUUID = args[4]
+					return "", "", nil
+				}).Times(1),
+		)
+	} else {
+		src, dst = testDiskImage(t), filepath.Join(t.TempDir(), "clone.vdi")
+		t.Cleanup(func() { _ = UnregisterDisk(dst) })
+		md, err := MediumInfo(src)
+		require.NoError(t, err)
+		srcUUID = md.UUID
+	}
+
+	uuid, err := CloneHDNewUUID(src, dst)
+	require.NoError(t, err)
+	if ManageMock != nil {
+		require.Equal(t, newUUID, uuid)
+	} else {
+		md, err := MediumInfo(dst)
+		require.NoError(t, err)
+		require.Equal(t, md.UUID, uuid)
+	}
+	require.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, uuid)
+	require.NotEqual(t, srcUUID, uuid)
+}
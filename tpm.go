@@ -0,0 +1,71 @@
+package virtualbox
+
+import (
+	"errors"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrInvalidTPM is returned when the TPM settings of a machine are not valid.
+var ErrInvalidTPM = errors.New("invalid tpm settings")
+
+// TPMType is the type of the Trusted Platform Module of a machine (VirtualBox 7+).
+type TPMType string
+
+const (
+	// TPMNone when the machine has no TPM.
+	TPMNone = TPMType("none")
+	// TPM12 when the machine has an emulated TPM 1.2.
+	TPM12 = TPMType("1.2")
+	// TPM20 when the machine has an emulated TPM 2.0, e.g. as required by Windows 11.
+	TPM20 = TPMType("2.0")
+	// TPMHost when the machine uses the TPM of the host.
+	TPMHost = TPMType("host")
+	// TPMSwtpm when the machine uses an external swtpm process listening at TPM.Location.
+	TPMSwtpm = TPMType("swtpm")
+)
+
+// TPM holds the Trusted Platform Module settings of a machine.
+type TPM struct {
+	Type     TPMType // empty to keep the current one
+	Location string  // e.g. the address of the swtpm process, empty to keep the current one
+}
+
+// validate checks the TPM type, and that a location is only given with a TPM type.
+func (t TPM) validate() error {
+	switch t.Type {
+	case "", TPMNone, TPM12, TPM20, TPMHost, TPMSwtpm:
+	default:
+		return pkgerrors.Wrapf(ErrInvalidTPM, "tpm type must be none|1.2|2.0|host|swtpm: %+v", t)
+	}
+	if t.Type == "" && t.Location != "" {
+		return pkgerrors.Wrapf(ErrInvalidTPM, "tpm location requires a tpm type: %+v", t)
+	}
+	return nil
+}
+
+// cmdArgs returns the modifyvm args applying the TPM settings, none if the type is empty,
+// so that machines are still modifiable with VirtualBox 6.1 which has no TPM support.
+func (t TPM) cmdArgs() []CmdArg {
+	if t.Type == "" {
+		return nil
+	}
+	args := []CmdArg{NewCmdArg("--tpm-type", string(t.Type))}
+	if t.Location != "" {
+		args = append(args, NewCmdArg("--tpm-location", t.Location))
+	}
+	return args
+}
+
+// tpmFromPropMap reads the TPM settings from the machine readable VM info, e.g. TPM_Type="tpm-2.0".
+func tpmFromPropMap(propMap map[string]string) TPM {
+	tpmType := strings.TrimPrefix(propMap["TPM_Type"], "tpm-")
+	if tpmType == "" {
+		return TPM{}
+	}
+	return TPM{
+		Type:     TPMType(strings.NewReplacer("v", "", "_", ".").Replace(tpmType)), // v2_0 in some versions
+		Location: propMap["TPM_Location"],
+	}
+}
@@ -0,0 +1,51 @@
+package virtualbox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMachineTPM(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vmInfoOut := ReadTestData("vboxmanage-showvminfo-full-1.out")
+	m := testMachine(t, vmInfoOut, Poweroff, Aborted)
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		require.Equal(t, TPM{Type: TPM20}, m.TPM)
+		modifyArgs = expectModifyVM("go-virtualbox", strings.NewReplacer(`TPM_Type="tpm-2.0"`, `TPM_Type="swtpm"`,
+			`TPM_Location=""`, `TPM_Location="127.0.0.1:2321"`).Replace(vmInfoOut))
+	} else {
+		skipBeforeVirtualBox(t, 7, 0, 0)
+		saved := m.TPM
+		if saved.Type == "" {
+			saved.Type = TPMNone
+		}
+		restoreOnCleanup(t, m, saved.cmdArgs()...)
+	}
+
+	m.TPM = TPM{Type: TPMSwtpm, Location: "127.0.0.1:2321"}
+	require.NoError(t, m.Modify())
+	require.Equal(t, TPM{Type: TPMSwtpm, Location: "127.0.0.1:2321"}, m.TPM)
+	if ManageMock != nil {
+		tpmType, _ := argValue(*modifyArgs, "--tpm-type")
+		require.Equal(t, "swtpm", tpmType)
+		location, _ := argValue(*modifyArgs, "--tpm-location")
+		require.Equal(t, "127.0.0.1:2321", location)
+	}
+
+	// invalid settings are rejected before running modifyvm
+	m.TPM = TPM{Type: "3.0"}
+	require.ErrorIs(t, m.Modify(), ErrInvalidTPM)
+	m.TPM = TPM{Location: "127.0.0.1:2321"}
+	require.ErrorIs(t, m.Modify(), ErrInvalidTPM)
+}
+
+func TestTPMFromPropMap(t *testing.T) {
+	require.Equal(t, TPM{Type: TPM12}, tpmFromPropMap(map[string]string{"TPM_Type": "v1_2"}))
+	require.Equal(t, TPM{}, tpmFromPropMap(map[string]string{}))
+	require.Empty(t, TPM{}.cmdArgs(), "no tpm args for VirtualBox 6.1")
+}
@@ -0,0 +1,50 @@
+package virtualbox
+
+import (
+	"errors"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// ErrInvalidTracing is returned when the tracing settings of a machine are not consistent.
+var ErrInvalidTracing = errors.New("invalid tracing settings")
+
+// Tracing holds the settings of the VM execution tracing facility.
+type Tracing struct {
+	Enabled       bool
+	Config        string // tracepoint configuration, e.g. all
+	AllowVMAccess bool   // allows the guest to access the tracing facility
+}
+
+// validate checks that the tracing settings are only given when tracing is enabled.
+func (t Tracing) validate() error {
+	if !t.Enabled && (t.Config != "" || t.AllowVMAccess) {
+		return pkgerrors.Wrapf(ErrInvalidTracing, "tracing config and vm access require tracing to be enabled: %+v", t)
+	}
+	return nil
+}
+
+// cmdArgs returns the modifyvm args applying the tracing settings.
+func (t Tracing) cmdArgs() []CmdArg {
+	if !t.Enabled {
+		return []CmdArg{NewCmdArg("--tracing-enabled", "off")}
+	}
+	return []CmdArg{
+		NewCmdArg("--tracing-enabled", "on"),
+		NewCmdArg("--tracing-config", t.Config),
+		NewCmdArg("--tracing-allow-vm-access", bool2string(t.AllowVMAccess)),
+	}
+}
+
+// tracingFromPropMap reads the tracing settings from the machine readable VM info.
+// The tracing config and vm access are only read when tracing is enabled, as they do not apply otherwise.
+func tracingFromPropMap(propMap map[string]string) Tracing {
+	if propMap["tracing-enabled"] != "on" {
+		return Tracing{}
+	}
+	return Tracing{
+		Enabled:       true,
+		Config:        propMap["tracing-config"],
+		AllowVMAccess: propMap["tracing-allow-vm-access"] == "on",
+	}
+}
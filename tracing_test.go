@@ -0,0 +1,43 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMachineTracing(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	var modifyArgs *[]string
+	if ManageMock != nil {
+		modifyArgs = expectModifyVM("go-virtualbox", ReadTestData("vboxmanage-showvminfo-full-1.out"))
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		restoreOnCleanup(t, m, NewCmdArg("--tracing-enabled", bool2string(m.Tracing.Enabled)),
+			NewCmdArg("--tracing-config", m.Tracing.Config), NewCmdArg("--tracing-allow-vm-access", bool2string(m.Tracing.AllowVMAccess)))
+	}
+	m.Tracing = Tracing{Enabled: true, Config: "all", AllowVMAccess: true}
+	require.NoError(t, m.Modify())
+	if ManageMock != nil {
+		for key, want := range map[string]string{
+			"--tracing-enabled": "on", "--tracing-config": "all", "--tracing-allow-vm-access": "on",
+		} {
+			got, _ := argValue(*modifyArgs, key)
+			require.Equalf(t, want, got, "value of %s", key)
+		}
+	}
+	require.Equal(t, Tracing{Enabled: true, Config: "all", AllowVMAccess: true}, m.Tracing)
+
+	// inconsistent settings are rejected before running modifyvm
+	m.Tracing = Tracing{Config: "all"}
+	require.ErrorIs(t, m.Modify(), ErrInvalidTracing)
+}
+
+func TestTracingFromPropMap(t *testing.T) {
+	require.Equal(t, Tracing{},
+		tracingFromPropMap(map[string]string{"tracing-enabled": "off", "tracing-config": "all", "tracing-allow-vm-access": "on"}))
+	require.Equal(t, []CmdArg{NewCmdArg("--tracing-enabled", "off")}, Tracing{}.cmdArgs())
+}
@@ -351,31 +351,22 @@ func (uart UART) validate() error {
 }
 
 func (uart UART) commandParameters() ([]string, error) {
-	if err := uart.validate(); err != nil {
+	args, err := uart.cmdArgs()
+	if err != nil {
 		return nil, err
 	}
-
-	commandFuncs := []func() (cmdName string, cmdValue string){
-		uart.commandParameterUartN,
-		uart.commandParameterUARTModeN,
-		uart.commandParameterUARTTypeN,
-	}
-	commands := make([]string, 0, len(commandFuncs))
-	for _, commandFunc := range commandFuncs {
-		if cmdName, cmdValue := commandFunc(); cmdName != "" {
-			cmdValueSplitsBySpace := strings.Split(cmdValue, " ")
-			commands = append(commands, cmdName)
-			commands = append(commands, cmdValueSplitsBySpace...)
-		}
-	}
-	return commands, nil
+	// rendering through CmdArgs so that the parameters are the same as the one used by Modify
+	cmdArgs := CmdArgs{}
+	cmdArgs.AppendCmdArgs(args...)
+	return cmdArgs.Args(), nil
 }
 
+// ToCmdArgsPartsUart renders an uart arg whose value holds space separated tokens (e.g. "0x02f8 3" or "file /tmp/com1.log").
+// The value is only split at its first space, so that the last token (e.g. a file path or pipe name) may contain spaces.
 func ToCmdArgsPartsUart(key, value string) []string {
-	parts := make([]string, 0, 4)
-	vsplits := strings.Split(value, " ")
+	parts := make([]string, 0, 3)
 	parts = append(parts, key)
-	parts = append(parts, vsplits...)
+	parts = append(parts, strings.SplitN(value, " ", 2)...)
 	return parts
 }
 
@@ -384,18 +375,23 @@ func (uart UART) cmdArgs() ([]CmdArg, error) {
 		return nil, err
 	}
 
-	commandFuncs := []func() (cmdName string, cmdValue string){
-		uart.commandParameterUartN,
-		uart.commandParameterUARTModeN,
-		uart.commandParameterUARTTypeN,
-	}
-	args := make([]CmdArg, 0, len(commandFuncs))
-	for _, commandFunc := range commandFuncs {
-		if cmdName, cmdValue := commandFunc(); cmdName != "" {
-			cmdArg := CmdArg{K: cmdName, V: &cmdValue, ToCmdArgParts: ToCmdArgsPartsUart}
-			args = append(args, cmdArg)
+	args := make([]CmdArg, 0, 3)
+	appendArg := func(cmdName, cmdValue string, toCmdArgParts func(K, V string) []string) {
+		if cmdName != "" {
+			args = append(args, CmdArg{K: cmdName, V: &cmdValue, ToCmdArgParts: toCmdArgParts})
 		}
 	}
+	uartName, uartValue := uart.commandParameterUartN()
+	appendArg(uartName, uartValue, ToCmdArgsPartsUart)
+	modeName, modeValue := uart.commandParameterUARTModeN()
+	if uart.Mode == UARTModeHostDevice {
+		// the device name is the whole value
+		appendArg(modeName, modeValue, nil)
+	} else {
+		appendArg(modeName, modeValue, ToCmdArgsPartsUart)
+	}
+	typeName, typeValue := uart.commandParameterUARTTypeN()
+	appendArg(typeName, typeValue, nil)
 	return args, nil
 }
 
@@ -427,7 +423,7 @@ func (uart UART) commandParameterUARTModeN() (cmdName string, cmdValue string) {
 	case UARTModeDisconnected:
 		return fmt.Sprintf("--uartmode%d", uart.Key.ToRank()), string(UARTModeDisconnected)
 	case UARTModeHostDevice:
-		return fmt.Sprintf("--uartmode%d ", uart.Key.ToRank()), uart.ModeData
+		return fmt.Sprintf("--uartmode%d", uart.Key.ToRank()), uart.ModeData
 	default:
 		return fmt.Sprintf("--uartmode%d", uart.Key.ToRank()), string(uart.Mode) + " " + uart.ModeData
 
@@ -286,3 +286,58 @@ func TestUARTsWithoutUARTHavingStateOff(t *testing.T) {
 		})
 	}
 }
+
+func TestModifyVMCommandParametersMatchesModifyVMCmdArgs(t *testing.T) {
+	uart2, err := NewUART("uart2", "16550A", "0x2f8", "3", "file", "/tmp/uart2")
+	assert.NoErrorf(t, err, "Fail to create uart2")
+	uart3, err := NewUART("uart3", "16750", "0x3E8", "4", "tcpserver", "6666")
+	assert.NoErrorf(t, err, "Fail to create uart3")
+	uarts, err := NewUARTsFromUARTMap(map[UARTKey]UART{UART2: *uart2, UART3: *uart3})
+	assert.NoError(t, err, "NewUARTsFromUARTMap failed")
+
+	params, err := uarts.ModifyVMCommandParameters()
+	assert.NoError(t, err)
+	args, err := uarts.ModifyVMCmdArgs()
+	assert.NoError(t, err)
+	cmdArgs := CmdArgs{}
+	cmdArgs.AppendCmdArgs(args...)
+
+	assert.Equal(t, params, cmdArgs.Args(), "both uart parameter renderings should be identical")
+	assert.Equal(t,
+		[]string{
+			"--uart1", "off",
+			"--uart2", "0x02f8", "3", "--uartmode2", "file", "/tmp/uart2", "--uarttype2", "16550A",
+			"--uart3", "0x03e8", "4", "--uartmode3", "tcpserver", "6666", "--uarttype3", "16750",
+			"--uart4", "off",
+		},
+		params)
+}
+
+func TestUartCmdParamsKeepSpacesInModeData(t *testing.T) {
+	fileUART := UART{Key: UART1, ComConfig: COM1(), Type: UARTTDefault, Mode: UARTModeFile, ModeData: "/tmp/my vm/com1.log"}
+	commands, err := fileUART.commandParameters()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]string{"--uart1", "0x03f8", "4", "--uartmode1", "file", "/tmp/my vm/com1.log", "--uarttype1", "16550A"},
+		commands)
+
+	deviceUART := UART{Key: UART2, ComConfig: COM2(), Type: UARTTDefault, Mode: UARTModeHostDevice, ModeData: "/dev/serial/by-id/usb FTDI"}
+	commands, err = deviceUART.commandParameters()
+	assert.NoError(t, err)
+	assert.Equal(t,
+		[]string{"--uart2", "0x02f8", "3", "--uartmode2", "/dev/serial/by-id/usb FTDI", "--uarttype2", "16550A"},
+		commands)
+}
+
+func TestUartModeFileWithSpaceInPathIsOneArg(t *testing.T) {
+	uart2 := UART{Key: UART2, ComConfig: COM2(), Type: UARTT16750, Mode: UARTModeFile, ModeData: "/tmp/my uart.log"}
+
+	args, err := UARTs{uart2}.ModifyVMCmdArgs()
+	assert.NoError(t, err)
+	cmdArgs := CmdArgs{}
+	cmdArgs.AppendCmdArgs(args...)
+
+	assert.Equal(t,
+		[]string{"--uart2", "0x02f8", "3", "--uartmode2", "file", "/tmp/my uart.log", "--uarttype2", "16750"},
+		cmdArgs.Args())
+}
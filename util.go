@@ -1,6 +1,12 @@
 package virtualbox
 
-import "net"
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
 
 // ParseIPv4Mask parses IPv4 netmask written in IP form (e.g. 255.255.255.0).
 // This function should really belong to the net package.
@@ -11,3 +17,36 @@ func ParseIPv4Mask(s string) net.IPMask {
 	}
 	return net.IPv4Mask(mask[12], mask[13], mask[14], mask[15])
 }
+
+// parseMemoryMB parses a memory size in MB, with an optional KB|MB|GB unit suffix (e.g. 2048, 2048MB, 2 GB).
+// KB sizes are rounded down to the MB.
+func parseMemoryMB(s string) (uint, error) {
+	value := strings.TrimSpace(s)
+	factor, divisor := uint64(1), uint64(1)
+	switch upper := strings.ToUpper(value); {
+	case strings.HasSuffix(upper, "KB"):
+		divisor = 1024
+	case strings.HasSuffix(upper, "GB"):
+		factor = 1024
+	case strings.HasSuffix(upper, "MB"):
+	default:
+		value += "MB"
+	}
+	value = strings.TrimSpace(value[:len(value)-2])
+	n, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("bad memory size (expected <number>[KB|MB|GB]): %q: %w", s, err)
+	}
+	return uint(n * factor / divisor), nil
+}
+
+// newUUID returns a random (version 4) UUID, e.g. for <VBoxManage createvm --uuid>.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,36 @@
+package virtualbox
+
+import (
+	"testing"
+)
+
+func TestParseMemoryMB(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    uint
+		wantErr bool
+	}{
+		{value: "1024", want: 1024},
+		{value: "2048MB", want: 2048},
+		{value: "2048 MB", want: 2048},
+		{value: "16mb", want: 16},
+		{value: "2GB", want: 2048},
+		{value: "4096KB", want: 4},
+		{value: " 128 ", want: 128},
+		{value: "MB", wantErr: true},
+		{value: "-1", wantErr: true},
+		{value: "2TB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseMemoryMB(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseMemoryMB(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseMemoryMB(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
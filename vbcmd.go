@@ -2,9 +2,14 @@ package virtualbox
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"io"
+	"os"
 	"os/exec"
 	"runtime"
+	"strings"
+	"time"
 
 	pkgerrors "github.com/pkg/errors"
 )
@@ -25,10 +30,20 @@ type Command interface {
 var (
 	// Verbose toggles the library in verbose execution mode.
 	Verbose bool
+	// ForceCLocale runs commands with LC_ALL=C.UTF-8 and LANG=C.UTF-8, so that their output is parsed in English
+	// whatever the host locale, while keeping non-ASCII names (e.g. of machines or paths) intact.
+	// Set it to false to run commands with the inherited locale.
+	ForceCLocale = true
 	// ErrMachineExist holds the error message when the machine already exists.
 	ErrMachineExist = errors.New("machine already exists")
 	// ErrMachineNotExist holds the error message when the machine does not exist.
 	ErrMachineNotExist = errors.New("machine does not exist")
+	// ErrMachineNotPaused holds the error message when the machine is expected to be paused but is not.
+	ErrMachineNotPaused = errors.New("machine is not paused")
+	// ErrMachineNotRunning holds the error message when the machine is expected to be running but is not.
+	ErrMachineNotRunning = errors.New("machine is not running")
+	// ErrMachineNotPoweredOff holds the error message when the machine is expected to be powered off but is not.
+	ErrMachineNotPoweredOff = errors.New("machine is not powered off")
 	// ErrCommandNotFound holds the error message when the VBoxManage commands was not found.
 	ErrCommandNotFound = errors.New("command not found")
 )
@@ -38,6 +53,10 @@ type command struct {
 	sudoer  bool // Is current user a sudoer?
 	sudo    bool // Is current command expected to be run under sudo?
 	guest   bool
+	stdout  io.Writer       // if set, run streams the command stdout to it instead of buffering it
+	stderr  io.Writer       // if set, run streams the command stderr to it instead of buffering it
+	ctx     context.Context // if set, run and runOutErr interrupt the command when it is done
+	env     []string        // environment variables (key=value) set on top of the inherited environment
 }
 
 func (vbcmd command) setOpts(opts ...option) Command {
@@ -56,6 +75,68 @@ func sudo(sudo bool) option {
 	}
 }
 
+func outWriter(w io.Writer) option {
+	return func(cmd Command) {
+		vbcmd := cmd.(*command)
+		vbcmd.stdout = w
+	}
+}
+
+func errWriter(w io.Writer) option {
+	return func(cmd Command) {
+		vbcmd := cmd.(*command)
+		vbcmd.stderr = w
+	}
+}
+
+func withContext(ctx context.Context) option {
+	return func(cmd Command) {
+		vbcmd := cmd.(*command)
+		vbcmd.ctx = ctx
+	}
+}
+
+func env(vars ...string) option {
+	return func(cmd Command) {
+		vbcmd := cmd.(*command)
+		vbcmd.env = mergeEnv(vbcmd.env, vars)
+	}
+}
+
+// mergeEnv returns the environment base with the given key=value vars set, replacing
+// the variables of base with the same key.
+func mergeEnv(base, vars []string) []string {
+	merged := make([]string, 0, len(base)+len(vars))
+	for _, kv := range base {
+		if !containsEnvKey(vars, envKey(kv)) {
+			merged = append(merged, kv)
+		}
+	}
+	for i, kv := range vars {
+		// last occurrence wins
+		if !containsEnvKey(vars[i+1:], envKey(kv)) {
+			merged = append(merged, kv)
+		}
+	}
+	return merged
+}
+
+func envKey(kv string) string {
+	if i := strings.Index(kv, "="); i >= 0 {
+		return kv[:i]
+	}
+	return kv
+}
+
+func containsEnvKey(vars []string, key string) bool {
+	for _, kv := range vars {
+		if envKey(kv) == key || (runtime.GOOS == osWindows && strings.EqualFold(envKey(kv), key)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (vbcmd command) isGuest() bool {
 	return vbcmd.guest
 }
@@ -68,21 +149,40 @@ func (vbcmd command) prepare(args []string) *exec.Cmd {
 	program := vbcmd.program
 	argv := []string{}
 	Trace("Command: '%+v', runtime.GOOS: '%s'", vbcmd, runtime.GOOS)
+	vars := vbcmd.env
+	if ForceCLocale {
+		vars = mergeEnv([]string{"LC_ALL=C.UTF-8", "LANG=C.UTF-8"}, vars)
+	}
 	if vbcmd.sudoer && vbcmd.sudo && runtime.GOOS != osWindows {
 		program = "sudo"
+		if len(vars) > 0 {
+			// sudo resets the environment, e.g. VBOX_USER_HOME, so the vars are set with env
+			argv = append(append(argv, "env"), vars...)
+		}
 		argv = append(argv, vbcmd.program)
 	}
 	argv = append(argv, args...)
 	Trace("executing: %v %v", program, argv)
-	return exec.Command(program, argv...) // #nosec
+	cmd := exec.Command(program, argv...) // #nosec
+	if len(vars) > 0 {
+		cmd.Env = mergeEnv(os.Environ(), vars)
+	}
+	return cmd
 }
 
 func (vbcmd command) run(args ...string) error {
+	defer timed(args)()
 	defer vbcmd.setOpts(sudo(false))
 	cmd := vbcmd.prepare(args)
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
+	if vbcmd.stdout != nil {
+		cmd.Stdout = vbcmd.stdout
+	}
+	if vbcmd.stderr != nil {
+		cmd.Stderr = vbcmd.stderr
+	}
 	if Verbose {
 		defer func() {
 			stdoutStr := stdout.String()
@@ -96,7 +196,7 @@ func (vbcmd command) run(args ...string) error {
 			}
 		}()
 	}
-	if err := cmd.Run(); err != nil {
+	if err := vbcmd.execute(cmd); err != nil {
 		if ee, ok := err.(*exec.Error); ok && ee == exec.ErrNotFound {
 			return ErrCommandNotFound
 		}
@@ -108,6 +208,7 @@ func (vbcmd command) run(args ...string) error {
 }
 
 func (vbcmd command) runOut(args ...string) (string, error) {
+	defer timed(args)()
 	defer vbcmd.setOpts(sudo(false))
 	cmd := vbcmd.prepare(args)
 	if Verbose {
@@ -135,13 +236,14 @@ func (vbcmd command) runOut(args ...string) (string, error) {
 }
 
 func (vbcmd command) runOutErr(args ...string) (string, string, error) {
+	defer timed(args)()
 	defer vbcmd.setOpts(sudo(false))
 	cmd := vbcmd.prepare(args)
 	var stdout bytes.Buffer
 	var stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-	err := cmd.Run()
+	err := vbcmd.execute(cmd)
 	if err != nil {
 		if ee, ok := err.(*exec.Error); ok && ee == exec.ErrNotFound {
 			err = ErrCommandNotFound
@@ -151,6 +253,44 @@ func (vbcmd command) runOutErr(args ...string) (string, string, error) {
 }
 
 // RunVBoxManageCmd run VBoxManage with the given arguments.
-func RunVBoxManageCmd(args ...string) (sdterr string, stdout string, err error) {
+//
+// Deprecated: use RunVBoxManage, whose result cannot be mistaken. Despite the former naming of its
+// results, RunVBoxManageCmd returns the stdout first and then the stderr.
+func RunVBoxManageCmd(args ...string) (stdout string, stderr string, err error) {
 	return Manage().runOutErr(args...)
 }
+
+// CmdResult holds the outcome of a VBoxManage invocation.
+type CmdResult struct {
+	Args     []string
+	Stdout   string
+	Stderr   string
+	ExitCode int // -1 if the command could not be run or did not exit normally
+	Duration time.Duration
+}
+
+// RunVBoxManage runs VBoxManage with the given arguments.
+// The result is returned as long as the command could be started, even if it exits with a non-zero code;
+// err is then not nil as well.
+func RunVBoxManage(args ...string) (*CmdResult, error) {
+	start := time.Now()
+	stdout, stderr, err := Manage().runOutErr(args...)
+	res := &CmdResult{
+		Args:     append([]string(nil), args...),
+		Stdout:   stdout,
+		Stderr:   stderr,
+		Duration: time.Since(start),
+	}
+	if err == nil {
+		return res, nil
+	}
+	if errors.Is(err, ErrCommandNotFound) {
+		return nil, err
+	}
+	res.ExitCode = -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.ExitCode = exitErr.ExitCode()
+	}
+	return res, pkgerrors.Wrapf(err, "VBoxManage failed: args=%v, exitCode=%d, stderr=%s", args, res.ExitCode, stderr)
+}
@@ -47,6 +47,26 @@ func Manage() Command {
 	return manage
 }
 
+// SetVBoxUserHome makes all subsequent operations run VBoxManage with the given VBOX_USER_HOME,
+// i.e. against the VirtualBox configuration and machine registry kept in that directory
+// instead of the one of the current user (e.g. ~/.config/VirtualBox).
+// The returned function restores the command used before.
+func SetVBoxUserHome(dir string) (restore func()) {
+	return SetEnv("VBOX_USER_HOME=" + dir)
+}
+
+// SetEnv makes all subsequent operations run VBoxManage with the given environment variables (key=value)
+// set on top of the inherited environment, e.g. VBOX_USER_HOME or LC_ALL. They are passed with env
+// to the commands run under sudo, as sudo does not keep the environment.
+// The returned function restores the command used before.
+func SetEnv(vars ...string) (restore func()) {
+	previous := Manage()
+	manage = previous.setOpts(env(vars...))
+	return func() {
+		manage = previous
+	}
+}
+
 // LookupVBoxProgram searches for an executable with the given name.
 //
 // On Windows: If environment variable VBOX_INSTALL_PATH exists will return ${VBOX_INSTALL_PATH}/vbprogName.exe,
@@ -1,12 +1,16 @@
 package virtualbox
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -33,6 +37,7 @@ func Setup(t *testing.T) {
 		t.Logf("Using ManageMock=%v (type=%T)", ManageMock, ManageMock)
 	} else {
 		t.Logf("Using real VM='%s'\n", VM)
+		setOSTypes(nil) // list the ones of the real VirtualBox
 	}
 	t.Logf("Using Manage='%+v' (type: '%T')", Manage(), Manage())
 }
@@ -57,3 +62,96 @@ func TestVBMOut(t *testing.T) {
 
 	Teardown()
 }
+
+func TestSetVBoxUserHome(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+	previous := manage
+	defer func() { manage = previous }()
+	manage = command{program: "sh"}
+
+	restore := SetVBoxUserHome("/srv/tenant1/vbox")
+	var stdout bytes.Buffer
+	require.NoError(t, Manage().setOpts(outWriter(&stdout)).run("-c", "echo $VBOX_USER_HOME"))
+	require.Equal(t, "/srv/tenant1/vbox\n", stdout.String())
+
+	restore()
+	require.Equal(t, command{program: "sh"}, Manage())
+}
+
+func TestSudoKeepsEnv(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("no sudo on windows")
+	}
+	cmd := command{program: "VBoxManage", sudoer: true, sudo: true, env: []string{"VBOX_USER_HOME=/srv/tenant1/vbox"}}
+	require.Equal(t,
+		[]string{"sudo", "env", "LC_ALL=C.UTF-8", "LANG=C.UTF-8", "VBOX_USER_HOME=/srv/tenant1/vbox", "VBoxManage", "list", "vms"},
+		cmd.prepare([]string{"list", "vms"}).Args)
+
+	ForceCLocale = false
+	defer func() { ForceCLocale = true }()
+	cmd.env = nil
+	require.Equal(t, []string{"sudo", "VBoxManage", "list", "vms"}, cmd.prepare([]string{"list", "vms"}).Args)
+}
+
+func TestMergeEnv(t *testing.T) {
+	require.Equal(t,
+		[]string{"PATH=/usr/bin", "LANG=C", "VBOX_USER_HOME=/srv/tenant2/vbox"},
+		mergeEnv(
+			[]string{"PATH=/usr/bin", "VBOX_USER_HOME=/home/me/.config/VirtualBox"},
+			[]string{"VBOX_USER_HOME=/srv/tenant1/vbox", "LANG=C", "VBOX_USER_HOME=/srv/tenant2/vbox"}))
+}
+
+func TestForceCLocale(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+	cmd := command{program: "sh"}
+
+	var stdout bytes.Buffer
+	require.NoError(t, cmd.setOpts(outWriter(&stdout)).run("-c", "echo $LC_ALL $LANG"))
+	require.Equal(t, "C.UTF-8 C.UTF-8\n", stdout.String())
+
+	ForceCLocale = false
+	defer func() { ForceCLocale = true }()
+	stdout.Reset()
+	require.NoError(t, cmd.setOpts(outWriter(&stdout)).run("-c", "echo $LC_ALL"))
+	require.Equal(t, "de_DE.UTF-8\n", stdout.String())
+}
+
+func TestRunVBoxManage(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+	previous := manage
+	defer func() { manage = previous }()
+	manage = command{program: "sh"}
+
+	res, err := RunVBoxManage("-c", "echo out; echo err >&2; exit 3")
+	require.Error(t, err)
+	require.Equal(t, []string{"-c", "echo out; echo err >&2; exit 3"}, res.Args)
+	require.Equal(t, "out\n", res.Stdout)
+	require.Equal(t, "err\n", res.Stderr)
+	require.Equal(t, 3, res.ExitCode)
+	require.Greater(t, res.Duration, time.Duration(0))
+
+	res, err = RunVBoxManage("-c", "echo ok")
+	require.NoError(t, err)
+	require.Equal(t, "ok\n", res.Stdout)
+	require.Equal(t, 0, res.ExitCode)
+}
+
+func TestCommandKeepsArgsVerbatim(t *testing.T) {
+	if runtime.GOOS == osWindows {
+		t.Skip("requires a posix shell")
+	}
+	cmd := command{program: "sh"}
+
+	// args are passed as is to the program, without any shell splitting or quoting
+	out, err := cmd.runOut("-c", `printf '[%s]\n' "$@"`, "sh",
+		"file /tmp/my vm/com1.log", "ssh,tcp,,2222,,22", `C:\VirtualBox VMs\"quoted"`)
+	require.NoError(t, err)
+	require.Equal(t, "[file /tmp/my vm/com1.log]\n[ssh,tcp,,2222,,22]\n[C:\\VirtualBox VMs\\\"quoted\"]\n", out)
+}
@@ -0,0 +1,187 @@
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+// vboxInternalPrefix prefixes the extradata keys of the VirtualBox internal settings.
+const vboxInternalPrefix = "VBoxInternal/"
+
+// Common VirtualBox internal settings, relative to VBoxInternal/ (see SetVBoxInternal).
+// Those with verbs are formatted with fmt.Sprintf before use.
+const (
+	// VBoxInternalTSCMode is the TSC mode, see TSCMode.
+	VBoxInternalTSCMode = "TM/TSCMode"
+	// VBoxInternalTSCTiedToExecution (0|1) stops the guest TSC while the machine does not execute.
+	VBoxInternalTSCTiedToExecution = "TM/TSCTiedToExecution"
+	// VBoxInternalIgnoreFlush (0|1), formatted with the storage device name, its instance and the port (LUN),
+	// makes the storage device ignore the flush requests of the guest.
+	VBoxInternalIgnoreFlush = "Devices/%s/%d/LUN#%d/Config/IgnoreFlush"
+	// VBoxInternalFlushInterval (bytes), formatted like VBoxInternalIgnoreFlush,
+	// flushes the disk every given amount of written data.
+	VBoxInternalFlushInterval = "Devices/%s/%d/LUN#%d/Config/FlushInterval"
+	// VBoxInternalNICSegmentationOffload (0|1), formatted with the network device name (e1000 only) and its instance,
+	// toggles the segmentation offload of the NIC.
+	VBoxInternalNICSegmentationOffload = "Devices/%s/%d/Config/GSOEnabled"
+)
+
+// ErrInvalidVBoxInternalPath is returned when setting a malformed VirtualBox internal setting path.
+var ErrInvalidVBoxInternalPath = errors.New("invalid VBoxInternal path")
+
+// SetVBoxInternal sets the VirtualBox internal setting at the given path of the machine,
+// i.e. the extradata VBoxInternal/<path>, effective from the next machine start.
+// The path is relative to VBoxInternal/, e.g. one of the VBoxInternal* constants.
+// An empty value removes the setting.
+func (m *Machine) SetVBoxInternal(path, value string) error {
+	path = strings.TrimPrefix(path, vboxInternalPrefix)
+	if err := validateVBoxInternalPath(path); err != nil {
+		return pkgerrors.Wrapf(err, "vm=%s", m.Name)
+	}
+	if value == "" {
+		return m.DeleteExtraData(vboxInternalPrefix + path)
+	}
+	return m.SetExtraData(vboxInternalPrefix+path, value)
+}
+
+// validateVBoxInternalPath checks that path is made of non-empty segments, with no verb left unformatted.
+func validateVBoxInternalPath(path string) error {
+	if strings.Contains(path, "%") {
+		return pkgerrors.Wrapf(ErrInvalidVBoxInternalPath, "path not formatted: %q", path)
+	}
+	for _, segment := range strings.Split(path, "/") {
+		if segment == "" || strings.TrimSpace(segment) != segment {
+			return pkgerrors.Wrapf(ErrInvalidVBoxInternalPath, "empty or padded segment: %q", path)
+		}
+	}
+	return nil
+}
+
+// TSCMode is the way the time stamp counter (TSC) is presented to the guest.
+type TSCMode string
+
+const (
+	// TSCModeRealTSCOffset passes the host TSC through with an offset.
+	TSCModeRealTSCOffset = TSCMode("RealTSCOffset")
+	// TSCModeVirtTSCEmulated emulates the TSC, trapping each guest read.
+	TSCModeVirtTSCEmulated = TSCMode("VirtTSCEmulated")
+	// TSCModeDynamic lets VirtualBox switch between the offset and emulated modes (default).
+	TSCModeDynamic = TSCMode("Dynamic")
+	// TSCModeNativeAPI leaves the TSC to the native hypervisor API (e.g. Hyper-V).
+	TSCModeNativeAPI = TSCMode("NativeApi")
+)
+
+// ErrInvalidTSCMode is returned when setting an unknown TSC mode.
+var ErrInvalidTSCMode = errors.New("invalid TSC mode")
+
+// SetTSCMode sets the TSC mode of the machine, effective from the next machine start.
+// An empty mode restores the default behavior.
+func (m *Machine) SetTSCMode(mode TSCMode) error {
+	switch mode {
+	case "", TSCModeRealTSCOffset, TSCModeVirtTSCEmulated, TSCModeDynamic, TSCModeNativeAPI:
+	default:
+		return pkgerrors.Wrapf(ErrInvalidTSCMode, "vm=%s, mode=%q", m.Name, mode)
+	}
+	return m.SetVBoxInternal(VBoxInternalTSCMode, string(mode))
+}
+
+// TSCMode returns the TSC mode set with SetTSCMode, empty if none has been set.
+func (m *Machine) TSCMode() (TSCMode, error) {
+	value, err := m.GetExtraData(vboxInternalPrefix + VBoxInternalTSCMode)
+	if err != nil || value == nil {
+		return "", err
+	}
+	return TSCMode(*value), nil
+}
+
+// storageDeviceNames maps the storage controller chipsets to the name of their VirtualBox device.
+var storageDeviceNames = map[StorageControllerChipset]string{
+	CtrlPIIX3:       "piix3ide",
+	CtrlPIIX4:       "piix3ide",
+	CtrlICH6:        "piix3ide",
+	CtrlIntelAHCI:   "ahci",
+	CtrlLSILogic:    "lsilogicscsi",
+	CtrlLSILogicSAS: "lsilogicsas",
+	CtrlBusLogic:    "buslogic",
+	CtlrNVMe:        "nvme",
+	CtrlVirtioSCSI:  "virtio-scsi",
+}
+
+// SetIgnoreFlush sets whether the disk attached to the given port of the given storage controller
+// ignores the flush requests of the guest, effective from the next machine start. Ignoring them
+// speeds up disk writes, at the risk of losing data if the host crashes.
+//
+// The internal device path is derived from the controller chipset as last read (e.g. with Refresh).
+// On IDE controllers, the master device of the port is addressed.
+// The device instance is the rank of the controller among the ones of the same device type.
+func (m *Machine) SetIgnoreFlush(ctlName string, port uint, ignore bool) error {
+	device, instance, err := m.storageDevice(ctlName)
+	if err != nil {
+		return err
+	}
+	lun := port
+	if device == "piix3ide" {
+		lun = port * 2 // primary master 0, primary slave 1, secondary master 2, ...
+	}
+	value := "0"
+	if ignore {
+		value = "1"
+	}
+	return m.SetVBoxInternal(fmt.Sprintf(VBoxInternalIgnoreFlush, device, instance, lun), value)
+}
+
+// storageDevice returns the VirtualBox device name and instance of the storage controller with the given name.
+func (m *Machine) storageDevice(ctlName string) (string, int, error) {
+	instances := map[string]int{}
+	for _, ctl := range m.StorageControllers {
+		device, ok := storageDeviceNames[ctl.Chipset]
+		if ctl.Name != ctlName {
+			if ok {
+				instances[device]++
+			}
+			continue
+		}
+		if !ok {
+			return "", 0, pkgerrors.Errorf("no internal device known for storage controller: vm=%s, controller=%s, chipset=%s",
+				m.Name, ctlName, ctl.Chipset)
+		}
+		return device, instances[device], nil
+	}
+	return "", 0, pkgerrors.Errorf("storage controller not found: vm=%s, controller=%s", m.Name, ctlName)
+}
+
+// nicDeviceNames maps the NIC hardware to the name of their VirtualBox device.
+var nicDeviceNames = map[NICHardware]string{
+	AMDPCNetPCIII:         "pcnet",
+	AMDPCNetFASTIII:       "pcnet",
+	IntelPro1000MTDesktop: "e1000",
+	IntelPro1000TServer:   "e1000",
+	IntelPro1000MTServer:  "e1000",
+	VirtIO:                "virtio-net",
+}
+
+// SetNICOffload toggles the segmentation offload of the n-th NIC (from 1), effective from the next
+// machine start, e.g. to debug guest networking throughput or corruption issues.
+//
+// The internal device path is derived from the NIC hardware as last read (e.g. with Refresh), the device
+// instance being the adapter slot, whatever the hardware of the other NICs.
+// Only the Intel PRO/1000 NICs (e1000) support it: the other devices refuse to start with
+// the setting, so an error is returned for them.
+func (m *Machine) SetNICOffload(n int, enabled bool) error {
+	if n < 1 || n > len(m.NICs) {
+		return pkgerrors.Errorf("no such NIC: vm=%s, nic=%d", m.Name, n)
+	}
+	device := nicDeviceNames[m.NICs[n-1].Hardware]
+	if device != "e1000" {
+		return pkgerrors.Errorf("segmentation offload not supported by NIC hardware: vm=%s, nic=%d, hardware=%s",
+			m.Name, n, m.NICs[n-1].Hardware)
+	}
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+	return m.SetVBoxInternal(fmt.Sprintf(VBoxInternalNICSegmentationOffload, device, n-1), value)
+}
@@ -0,0 +1,147 @@
+package virtualbox
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slices"
+)
+
+func TestTSCMode(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/TM/TSCMode", "RealTSCOffset").Return(nil).Times(1),
+			ManageMock.EXPECT().runOut("getextradata", "go-virtualbox", "VBoxInternal/TM/TSCMode").Return("Value: RealTSCOffset\n", nil).Times(1),
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/TM/TSCMode").Return(nil).Times(1),
+			ManageMock.EXPECT().runOut("getextradata", "go-virtualbox", "VBoxInternal/TM/TSCMode").Return("No value set!\n", nil).Times(1),
+		)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		t.Cleanup(func() { _ = m.SetTSCMode("") })
+	}
+	require.NoError(t, m.SetTSCMode(TSCModeRealTSCOffset))
+
+	mode, err := m.TSCMode()
+	require.NoError(t, err)
+	require.Equal(t, TSCModeRealTSCOffset, mode)
+
+	require.ErrorIs(t, m.SetTSCMode("Realtime"), ErrInvalidTSCMode)
+
+	require.NoError(t, m.SetTSCMode(""))
+	mode, err = m.TSCMode()
+	require.NoError(t, err)
+	require.Empty(t, mode)
+}
+
+func TestSetVBoxInternal(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	flushInterval := "VBoxInternal/" + fmt.Sprintf(VBoxInternalFlushInterval, "ahci", 0, 1)
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/Devices/ahci/0/LUN#1/Config/FlushInterval", "1000000").
+				Return(nil).Times(1),
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/TM/TSCTiedToExecution").Return(nil).Times(1),
+		)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		t.Cleanup(func() { _ = m.DeleteExtraData(flushInterval) })
+	}
+	require.NoError(t, m.SetVBoxInternal(fmt.Sprintf(VBoxInternalFlushInterval, "ahci", 0, 1), "1000000"))
+	if ManageMock == nil {
+		actual, err := m.GetExtraData(flushInterval)
+		require.NoError(t, err)
+		require.NotNilf(t, actual, "%s should be set", flushInterval)
+		require.Equal(t, "1000000", *actual)
+	}
+
+	require.NoError(t, m.SetVBoxInternal("VBoxInternal/TM/TSCTiedToExecution", ""))
+
+	require.ErrorIs(t, m.SetVBoxInternal(VBoxInternalIgnoreFlush, "1"), ErrInvalidVBoxInternalPath)
+	require.ErrorIs(t, m.SetVBoxInternal("Devices//Config", "1"), ErrInvalidVBoxInternalPath)
+}
+
+func TestSetIgnoreFlush(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", StorageControllers: StorageControllers{
+		{Name: "IDE", Chipset: CtrlPIIX4},
+		{Name: "SATA", Chipset: CtrlIntelAHCI},
+		{Name: "SATA2", Chipset: CtrlIntelAHCI},
+	}}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/Devices/ahci/1/LUN#3/Config/IgnoreFlush", "1").
+				Return(nil).Times(1),
+			ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/Devices/piix3ide/0/LUN#2/Config/IgnoreFlush", "0").
+				Return(nil).Times(1),
+		)
+		require.NoError(t, m.SetIgnoreFlush("SATA2", 3, true))
+		require.NoError(t, m.SetIgnoreFlush("IDE", 1, false))
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		i := slices.IndexFunc(m.StorageControllers, func(ctl StorageController) bool {
+			_, ok := storageDeviceNames[ctl.Chipset]
+			return ok
+		})
+		if i == -1 {
+			t.Skipf("requires %s to have a storage controller", m.Name)
+		}
+		device, instance, err := m.storageDevice(m.StorageControllers[i].Name)
+		require.NoError(t, err)
+		key := "VBoxInternal/" + fmt.Sprintf(VBoxInternalIgnoreFlush, device, instance, 0)
+		t.Cleanup(func() { _ = m.DeleteExtraData(key) })
+
+		require.NoError(t, m.SetIgnoreFlush(m.StorageControllers[i].Name, 0, true))
+		actual, err := m.GetExtraData(key)
+		require.NoError(t, err)
+		require.NotNilf(t, actual, "%s should be set", key)
+		require.Equal(t, "1", *actual)
+	}
+	require.Error(t, m.SetIgnoreFlush("go-virtualbox-missing", 0, true))
+}
+
+func TestSetNICOffload(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox", NICs: []NIC{
+		{Network: NICNetNAT, Hardware: IntelPro1000MTDesktop},
+		{Network: NICNetHostonly, Hardware: VirtIO},
+		{Network: NICNetBridged, Hardware: IntelPro1000MTServer},
+	}}
+	n := 3
+	if ManageMock != nil {
+		ManageMock.EXPECT().run("setextradata", "go-virtualbox", "VBoxInternal/Devices/e1000/2/Config/GSOEnabled", "0").
+			Return(nil).Times(1)
+		require.Error(t, m.SetNICOffload(2, false))
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		n = slices.IndexFunc(m.NICs, func(nic NIC) bool { return nicDeviceNames[nic.Hardware] == "e1000" }) + 1
+		if n == 0 {
+			t.Skipf("requires %s to have an Intel PRO/1000 NIC", m.Name)
+		}
+	}
+	key := "VBoxInternal/" + fmt.Sprintf(VBoxInternalNICSegmentationOffload, "e1000", n-1)
+	if ManageMock == nil {
+		t.Cleanup(func() { _ = m.DeleteExtraData(key) })
+	}
+	require.NoError(t, m.SetNICOffload(n, false))
+	if ManageMock == nil {
+		actual, err := m.GetExtraData(key)
+		require.NoError(t, err)
+		require.NotNilf(t, actual, "%s should be set", key)
+		require.Equal(t, "0", *actual)
+	}
+
+	require.Error(t, m.SetNICOffload(len(m.NICs)+1, false))
+}
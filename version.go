@@ -1,6 +1,16 @@
 package virtualbox
 
-import "github.com/pkg/errors"
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/pkg/errors"
+)
+
+// matches e.g. 7.0.10r158379, 6.1.34_Ubuntur150636 or 7.0.0_BETA1r153351,
+// possibly after warnings such as the one about the vboxdrv kernel module not being loaded
+var reVersion = regexp.MustCompile(`(?m)^(\d+)\.(\d+)\.(\d+)(?:\S*?r(\d+))?`)
 
 // Version return the version. E.g. 6.1.34r150636.
 // format: <major>.<minor>.<patch>r<revision>
@@ -14,3 +24,51 @@ func Version() (string, error) {
 	}
 	return stdout, nil
 }
+
+// VBoxVersion is a VirtualBox version, as parsed by VersionInfo.
+type VBoxVersion struct {
+	Major    int
+	Minor    int
+	Patch    int
+	Revision int // build revision, 0 if unknown
+}
+
+// String returns the version as <major>.<minor>.<patch>.
+func (v VBoxVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast returns true if the version is the given one or a later one.
+func (v VBoxVersion) AtLeast(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// VersionInfo returns the parsed VirtualBox version, e.g. to check whether a feature is available.
+func VersionInfo() (*VBoxVersion, error) {
+	out, err := Version()
+	if err != nil {
+		return nil, err
+	}
+	return parseVersion(out)
+}
+
+func parseVersion(out string) (*VBoxVersion, error) {
+	res := reVersion.FindStringSubmatch(out)
+	if res == nil {
+		return nil, errors.Errorf("unexpected virtualbox version format: %q", out)
+	}
+	v := VBoxVersion{}
+	v.Major, _ = strconv.Atoi(res[1])
+	v.Minor, _ = strconv.Atoi(res[2])
+	v.Patch, _ = strconv.Atoi(res[3])
+	if res[4] != "" {
+		v.Revision, _ = strconv.Atoi(res[4])
+	}
+	return &v, nil
+}
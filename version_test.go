@@ -0,0 +1,29 @@
+package virtualbox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	for out, want := range map[string]VBoxVersion{
+		"7.0.10r158379\n":        {Major: 7, Minor: 0, Patch: 10, Revision: 158379},
+		"6.1.34_Ubuntur150636\n": {Major: 6, Minor: 1, Patch: 34, Revision: 150636},
+		"7.0.0_BETA1r153351":     {Major: 7, Minor: 0, Patch: 0, Revision: 153351},
+		"6.1.38":                 {Major: 6, Minor: 1, Patch: 38},
+		"WARNING: The vboxdrv kernel module is not loaded.\n7.0.12r159484\n": {Major: 7, Minor: 0, Patch: 12, Revision: 159484},
+	} {
+		v, err := parseVersion(out)
+		require.NoError(t, err)
+		require.Equalf(t, want, *v, "version %q", out)
+	}
+	_, err := parseVersion("WARNING: The vboxdrv kernel module is not loaded.")
+	require.Error(t, err)
+
+	v := VBoxVersion{Major: 6, Minor: 1, Patch: 34}
+	require.True(t, v.AtLeast(6, 1, 34))
+	require.True(t, v.AtLeast(6, 0, 99))
+	require.False(t, v.AtLeast(6, 1, 35))
+	require.False(t, v.AtLeast(7, 0, 0))
+}
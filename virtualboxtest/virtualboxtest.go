@@ -0,0 +1,129 @@
+/*
+Package virtualboxtest provides helpers to unit-test code built on package virtualbox
+without a VirtualBox installation.
+
+A FakeRunner answers VBoxManage invocations with canned outputs (usually recorded from
+a real VBoxManage into testdata files) and records the invocations, so that tests can
+assert on the commands which would have been run:
+
+	fake := virtualboxtest.NewFakeRunner()
+	fake.On("showvminfo", "my-vm", "--machinereadable").
+		Return(virtualboxtest.ReadTestData(t, "showvminfo-my-vm.out"), "", nil)
+	virtualboxtest.Install(t, fake)
+
+	m, err := virtualbox.GetMachine("my-vm")
+*/
+package virtualboxtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	virtualbox "github.com/terra-farm/go-virtualbox"
+)
+
+// Response is the canned output of a command.
+type Response struct {
+	args   []string
+	stdout string
+	stderr string
+	err    error
+	times  int // number of times the response can still be used, < 0 for unlimited
+}
+
+// Return sets the outputs returned for the command.
+func (r *Response) Return(stdout, stderr string, err error) *Response {
+	r.stdout, r.stderr, r.err = stdout, stderr, err
+	return r
+}
+
+// Times limits the number of times the response is returned. Afterwards the next matching response is used.
+func (r *Response) Times(n int) *Response {
+	r.times = n
+	return r
+}
+
+// FakeRunner is a virtualbox.Runner answering with canned outputs and recording the commands it runs.
+// It is safe for concurrent use.
+type FakeRunner struct {
+	mu        sync.Mutex
+	responses []*Response
+	commands  [][]string
+}
+
+// NewFakeRunner returns a FakeRunner without any canned output.
+func NewFakeRunner() *FakeRunner {
+	return &FakeRunner{}
+}
+
+// On registers a response for the command with exactly the given args.
+// Responses are matched in registration order; by default a response can be used any number of times.
+func (f *FakeRunner) On(args ...string) *Response {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	r := &Response{args: args, times: -1}
+	f.responses = append(f.responses, r)
+	return r
+}
+
+// Run implements virtualbox.Runner.
+// Commands without response fail with an error naming the command.
+func (f *FakeRunner) Run(args ...string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands = append(f.commands, append([]string{}, args...))
+	for _, r := range f.responses {
+		if r.times == 0 || !equal(r.args, args) {
+			continue
+		}
+		if r.times > 0 {
+			r.times--
+		}
+		return r.stdout, r.stderr, r.err
+	}
+	return "", "", fmt.Errorf("virtualboxtest: no canned output for: VBoxManage %s", strings.Join(args, " "))
+}
+
+// Commands returns the args of all commands run so far, in order.
+func (f *FakeRunner) Commands() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	commands := make([][]string, 0, len(f.commands))
+	for _, args := range f.commands {
+		commands = append(commands, append([]string{}, args...))
+	}
+	return commands
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Install makes package virtualbox run its commands with r for the duration of the test.
+func Install(t testing.TB, r virtualbox.Runner) {
+	t.Helper()
+	restore := virtualbox.SetRunner(r)
+	t.Cleanup(restore)
+}
+
+// ReadTestData returns the content of the given file of the testdata folder of the package under test.
+func ReadTestData(t testing.TB, file string) string {
+	t.Helper()
+	out, err := os.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("could not read test data: file=testdata/%s, err=%v", file, err)
+	}
+	return string(out)
+}
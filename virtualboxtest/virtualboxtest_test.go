@@ -0,0 +1,46 @@
+package virtualboxtest_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	virtualbox "github.com/terra-farm/go-virtualbox"
+	"github.com/terra-farm/go-virtualbox/virtualboxtest"
+)
+
+func TestFakeRunnerAnswersGetMachine(t *testing.T) {
+	fake := virtualboxtest.NewFakeRunner()
+	fake.On("showvminfo", "go-virtualbox", "--machinereadable").
+		Return(virtualboxtest.ReadTestData(t, "vboxmanage-showvminfo-go-virtualbox.out"), "", nil)
+	fake.On("list", "ostypes").Return(virtualboxtest.ReadTestData(t, "vboxmanage-list-ostypes.out"), "", nil)
+	virtualboxtest.Install(t, fake)
+
+	m, err := virtualbox.GetMachine("go-virtualbox")
+
+	require.NoError(t, err)
+	require.Equal(t, "37f5d336-bf07-48dd-947c-37e6a56420a7", m.UUID)
+	require.Equal(t, uint(1024), m.Memory)
+	require.Equal(t, "Ubuntu_64", m.OSType)
+	require.Equal(t, [][]string{{"showvminfo", "go-virtualbox", "--machinereadable"}, {"list", "ostypes"}},
+		fake.Commands(), "the os types are listed once to map the os type description to its id")
+}
+
+func TestFakeRunnerRecordsCommands(t *testing.T) {
+	fake := virtualboxtest.NewFakeRunner()
+	fake.On("controlvm", "go-virtualbox", "pause").Return("", "", nil).Times(1)
+	fake.On("controlvm", "go-virtualbox", "pause").Return("", "VBoxManage: error: Machine is not running", errors.New("exit status 1"))
+	virtualboxtest.Install(t, fake)
+	m := &virtualbox.Machine{Name: "go-virtualbox", State: virtualbox.Running}
+
+	require.NoError(t, m.Pause())
+	require.Error(t, m.Pause())
+	require.Error(t, m.Poweroff(), "commands without canned output should fail")
+	require.Equal(t,
+		[][]string{
+			{"controlvm", "go-virtualbox", "pause"},
+			{"controlvm", "go-virtualbox", "pause"},
+			{"controlvm", "go-virtualbox", "poweroff"},
+		},
+		fake.Commands())
+}
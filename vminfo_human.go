@@ -0,0 +1,138 @@
+package virtualbox
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// matches the fields, e.g. <Memory size:                 2048MB>, and the section headers, e.g. <Snapshots:>
+	reVMInfoHumanField = regexp.MustCompile(`^([A-Za-z][^:]*):( *)(.*)$`)
+	// matches e.g. <   Name: base (UUID: 4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a01) *>, indented by 3 spaces per level
+	reVMInfoHumanSnapshot = regexp.MustCompile(`^(\s+)Name: (.*) \(UUID: ([0-9a-fA-F-]+)\)( \*)?$`)
+	// matches the description header of a snapshot, e.g. <      Description:>
+	reVMInfoHumanSnapshotDescription = regexp.MustCompile(`^\s+Description:\s*$`)
+)
+
+const (
+	// vminfoHumanSnapshotIndent is the indentation of each level of the snapshot tree in <VBoxManage showvminfo>.
+	vminfoHumanSnapshotIndent = 3
+	// vminfoHumanLabelWidth is the width the labels (with their colon) are padded to in <VBoxManage showvminfo>.
+	vminfoHumanLabelWidth = 28
+)
+
+// VMInfoHuman holds the info of the human readable <VBoxManage showvminfo>, for the few fields the
+// machine readable output (see GetMachine) does not represent well, e.g. multi-line descriptions.
+type VMInfoHuman struct {
+	Fields      map[string]string // values of the colon-aligned fields by label, e.g. "Memory size": "2048MB"
+	Description string            // description of the machine, which may span multiple lines
+	Snapshots   []*Snapshot       // root snapshots, with their multi-line descriptions
+}
+
+// ShowVMInfo reads the human readable info of the machine with the given id (name or UUID).
+// ErrMachineNotExist is returned if there is no such machine.
+//
+// A line of a description which looks like a colon-aligned field (e.g. <Note:  ...>) ends the description.
+func ShowVMInfo(id string) (*VMInfoHuman, error) {
+	// see GetMachine about the serialization
+	mutex.Lock()
+	stdout, stderr, err := Manage().runOutErr("showvminfo", id)
+	mutex.Unlock()
+	if err != nil {
+		if reMachineNotFound.MatchString(stderr) || reMachineNotFoundByUuid.MatchString(stderr) {
+			return nil, ErrMachineNotExist
+		}
+		return nil, errors.Wrapf(err, "fail to show vm info: id=%s, stderr=%s", id, stderr)
+	}
+	return parseVMInfoHuman(strings.NewReader(stdout))
+}
+
+// parseVMInfoHuman parses the output of <VBoxManage showvminfo>.
+func parseVMInfoHuman(vmInfo io.Reader) (*VMInfoHuman, error) {
+	info := &VMInfoHuman{Fields: map[string]string{}}
+	var description *[]string // lines of the description being read, if any
+	var levels []*Snapshot    // snapshot of each level of the tree on the current branch
+	inSnapshots := false
+	endDescription := func() {
+		if description == nil {
+			return
+		}
+		text := strings.TrimRight(strings.Join(*description, "\n"), "\n")
+		if len(levels) > 0 {
+			levels[len(levels)-1].Description = text
+		} else {
+			info.Description = text
+		}
+		description = nil
+	}
+
+	s := bufio.NewScanner(vmInfo)
+	for s.Scan() {
+		line := s.Text()
+		if inSnapshots {
+			if res := reVMInfoHumanSnapshot.FindStringSubmatch(line); res != nil {
+				endDescription()
+				level := len(res[1])/vminfoHumanSnapshotIndent - 1
+				if level < 0 || level > len(levels) {
+					return nil, errors.Errorf("bad snapshot tree indentation: %q", line)
+				}
+				snapshot := &Snapshot{Name: res[2], UUID: res[3], Current: res[4] != ""}
+				levels = append(levels[:level], snapshot)
+				if level == 0 {
+					info.Snapshots = append(info.Snapshots, snapshot)
+				} else {
+					levels[level-1].Children = append(levels[level-1].Children, snapshot)
+				}
+				continue
+			}
+			if reVMInfoHumanSnapshotDescription.MatchString(line) && len(levels) > 0 {
+				endDescription()
+				description = &[]string{}
+				continue
+			}
+		}
+		label, val, ok := vminfoHumanField(line)
+		if !ok {
+			if description != nil {
+				*description = append(*description, line)
+			}
+			continue
+		}
+		endDescription()
+		inSnapshots = false
+		levels = nil
+		switch {
+		case label == "Description" && val == "":
+			description = &[]string{}
+		case label == "Snapshots" && val == "":
+			inSnapshots = true
+		default:
+			if _, exists := info.Fields[label]; !exists {
+				info.Fields[label] = val
+			}
+		}
+	}
+	endDescription()
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "error parsing human vminfo")
+	}
+	return info, nil
+}
+
+// vminfoHumanField returns the label and value of a field line, telling it from a description line by
+// the alignment of the value: a field value is padded with at least two spaces, or one after a long label.
+func vminfoHumanField(line string) (label string, val string, ok bool) {
+	res := reVMInfoHumanField.FindStringSubmatch(line)
+	if res == nil {
+		return "", "", false
+	}
+	label, padding, val := res[1], res[2], strings.TrimSpace(res[3])
+	if val != "" && len(padding) < 2 && (len(padding) == 0 || len(label)+1 < vminfoHumanLabelWidth) {
+		return "", "", false
+	}
+	return label, val, true
+}
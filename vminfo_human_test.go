@@ -0,0 +1,60 @@
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestShowVMInfo(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	vm, memory := "go-virtualbox", "2048MB"
+	description := "Build agent for the integration tests.\nOwner: team-ci\n\nDo not start manually."
+	provisionedUUID := "4c9d5a3e-2f5b-4d89-9a0e-6f3b2d1c0a02"
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox").
+			Return(ReadTestData("vboxmanage-showvminfo-human-1.out"), "", nil).Times(1)
+	} else {
+		m := testMachineWithoutSnapshots(t)
+		vm, memory = m.Name, fmt.Sprintf("%dMB", m.Memory)
+		original, err := ShowVMInfo(vm)
+		require.NoError(t, err)
+		require.NoError(t, Manage().run("modifyvm", vm, "--description", description))
+		t.Cleanup(func() { require.NoError(t, Manage().run("modifyvm", vm, "--description", original.Description)) })
+		provisionedUUID = takeSnapshotTree(t, m, "after provisioning\nwith ansible")["provisioned"]
+	}
+	info, err := ShowVMInfo(vm)
+	require.NoError(t, err)
+
+	require.Equal(t, vm, info.Fields["Name"])
+	require.Equal(t, memory, info.Fields["Memory size"])
+	if ManageMock != nil {
+		require.Equal(t, "disabled", info.Fields["Nested VT-x/AMD-V"])
+		require.Equal(t, "0MB", info.Fields["Configured memory balloon size"])
+	}
+	require.Equal(t, description, info.Description)
+
+	require.Len(t, info.Snapshots, 1)
+	base := info.Snapshots[0]
+	require.Equal(t, "base", base.Name)
+	require.Len(t, base.Children, 2)
+	provisioned := base.Children[0]
+	require.Equal(t, provisionedUUID, provisioned.UUID)
+	require.Equal(t, "after provisioning\nwith ansible", provisioned.Description)
+	require.Len(t, provisioned.Children, 1)
+	require.Equal(t, "test-run", provisioned.Children[0].Name)
+	require.True(t, provisioned.Children[0].Current)
+	require.Equal(t, "hotfix", base.Children[1].Name)
+	require.Empty(t, base.Children[1].Description)
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox-gone").
+			Return("", "VBoxManage: error: Could not find a registered machine named 'go-virtualbox-gone'", errors.New("exit status 1")).Times(1)
+	}
+	_, err = ShowVMInfo("go-virtualbox-gone")
+	require.ErrorIs(t, err, ErrMachineNotExist)
+}
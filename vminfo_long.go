@@ -0,0 +1,148 @@
+package virtualbox
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	reVMInfoLongName      = regexp.MustCompile(`^Name:\s+([^'\s].*)$`)
+	reVMInfoLongLine      = regexp.MustCompile(`^([^:]+):\s+(.*)$`)
+	reVMInfoLongNIC       = regexp.MustCompile(`^NIC (\d+)$`)
+	reVMInfoLongUART      = regexp.MustCompile(`^UART (\d+)$`)
+	reVMInfoLongState     = regexp.MustCompile(`^(.*?)\s*(?:\(since .*\))?$`)
+	reVMInfoLongNICMac    = regexp.MustCompile(`MAC: ([0-9A-Fa-f]+)`)
+	reVMInfoLongNICType   = regexp.MustCompile(`Type: ([^,]+)`)
+	reVMInfoLongNICAttach = regexp.MustCompile(`Attachment: ([^,']+?)(?: '([^']*)')?(?:,|$)`)
+)
+
+// vminfoLongKeys maps the <VBoxManage list vms --long> labels to the <VBoxManage showvminfo --machinereadable> keys.
+var vminfoLongKeys = map[string]string{
+	"Name":            "name",
+	"UUID":            "UUID",
+	"Hardware UUID":   "hardwareuuid",
+	"Config file":     "CfgFile",
+	"Snapshot folder": "SnapFldr",
+	"Memory size":     "memory",
+	"VRAM size":       "vram",
+	"Number of CPUs":  "cpus",
+}
+
+// vminfoLongNICNetworks maps the NIC attachments of <VBoxManage list vms --long> to NIC networks.
+var vminfoLongNICNetworks = map[string]NICNetwork{
+	"NAT":                 NICNetNAT,
+	"NAT Network":         NICNetNATNetwork,
+	"Bridged Interface":   NICNetBridged,
+	"Internal Network":    NICNetInternal,
+	"Host-only Interface": NICNetHostonly,
+	"Generic":             NICNetGeneric,
+	"none":                NICNetDisconnected,
+}
+
+// splitVMInfoLongBlocks splits the output of <VBoxManage list vms --long> into one block per VM.
+func splitVMInfoLongBlocks(out string) []string {
+	blocks := []string{}
+	var cur *strings.Builder
+	s := bufio.NewScanner(strings.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if reVMInfoLongName.MatchString(line) {
+			if cur != nil {
+				blocks = append(blocks, cur.String())
+			}
+			cur = &strings.Builder{}
+		}
+		if cur != nil {
+			cur.WriteString(line)
+			cur.WriteString("\n")
+		}
+	}
+	if cur != nil {
+		blocks = append(blocks, cur.String())
+	}
+	return blocks
+}
+
+// vminfoLongAsPropMap reads the human readable info of one VM, as given by <VBoxManage list vms --long>,
+// into a map using the keys of <VBoxManage showvminfo --machinereadable>.
+// Only the info needed for the Machine basic fields and NICs is read.
+func vminfoLongAsPropMap(vmInfo io.Reader) (map[string]string, error) {
+	propMap := make(map[string]string)
+	s := bufio.NewScanner(vmInfo)
+	for s.Scan() {
+		res := reVMInfoLongLine.FindStringSubmatch(s.Text())
+		if res == nil {
+			continue
+		}
+		label, val := res[1], strings.TrimSpace(res[2])
+		if key, ok := vminfoLongKeys[label]; ok {
+			if _, exists := propMap[key]; !exists {
+				propMap[key] = val
+			}
+			continue
+		}
+		switch {
+		case label == "State":
+			propMap["VMState"] = vminfoLongState(val)
+		case reVMInfoLongNIC.MatchString(label):
+			i := reVMInfoLongNIC.FindStringSubmatch(label)[1]
+			vminfoLongNIC(propMap, i, val)
+		case reVMInfoLongUART.MatchString(label):
+			if val == "disabled" {
+				propMap["uart"+reVMInfoLongUART.FindStringSubmatch(label)[1]] = "off"
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, errors.Wrap(err, "error parsing long vminfo into map")
+	}
+	return propMap, nil
+}
+
+// vminfoLongState converts a state such as <powered off (since 2022-10-20T08:12:44.000000000)> to a MachineState value.
+func vminfoLongState(val string) string {
+	state := strings.ToLower(reVMInfoLongState.FindStringSubmatch(val)[1])
+	if state == "powered off" {
+		return string(Poweroff)
+	}
+	return strings.ReplaceAll(state, " ", "")
+}
+
+// vminfoLongNIC reads a NIC description such as
+// <MAC: 080027D4E5F6, Attachment: Host-only Interface 'vboxnet0', Cable connected: on, ..., Type: virtio, ...>.
+func vminfoLongNIC(propMap map[string]string, i string, val string) {
+	if val == "disabled" {
+		propMap["nic"+i] = string(NICNetAbsent)
+		return
+	}
+	if res := reVMInfoLongNICMac.FindStringSubmatch(val); res != nil {
+		propMap["macaddress"+i] = res[1]
+	}
+	if res := reVMInfoLongNICType.FindStringSubmatch(val); res != nil {
+		propMap["nictype"+i] = strings.TrimSpace(res[1])
+	}
+	res := reVMInfoLongNICAttach.FindStringSubmatch(val)
+	if res == nil {
+		return
+	}
+	network, ok := vminfoLongNICNetworks[res[1]]
+	if !ok {
+		network = NICNetwork(strings.ToLower(res[1]))
+	}
+	propMap["nic"+i] = string(network)
+	switch network {
+	case NICNetHostonly:
+		propMap[fmt.Sprintf("hostonlyadapter%s", i)] = res[2]
+	case NICNetBridged:
+		propMap[fmt.Sprintf("bridgeadapter%s", i)] = res[2]
+	case NICNetNATNetwork:
+		propMap[fmt.Sprintf("nat-network%s", i)] = res[2]
+	case NICNetInternal:
+		propMap[fmt.Sprintf("intnet%s", i)] = res[2]
+	}
+}
@@ -0,0 +1,129 @@
+package virtualbox
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// logHeaderLines is the number of lines at the beginning of VBox.log searched for the log opening time.
+const logHeaderLines = 20
+
+var (
+	// ErrUptimeNotAvailable holds the error message when the start time of a running machine cannot be
+	// read from its log, e.g. when the log folder is not accessible from this host.
+	ErrUptimeNotAvailable = errors.New("uptime not available")
+
+	// matches e.g. 00:00:00.000000 Log opened 2023-10-16T09:44:26.178830000Z
+	reLogOpened = regexp.MustCompile(`Log opened (\S+)`)
+)
+
+// LogPath returns the folder of the VirtualBox logs of the machine (e.g. VBox.log).
+func (m *Machine) LogPath() string {
+	return filepath.Join(m.BaseFolder, "Logs")
+}
+
+// logFile returns the path of the index-th log file: VBox.log for 0, VBox.log.<index> for older ones.
+func (m *Machine) logFile(index int) string {
+	name := "VBox.log"
+	if index > 0 {
+		name = fmt.Sprintf("%s.%d", name, index)
+	}
+	return filepath.Join(m.LogPath(), name)
+}
+
+// ReadLog opens the index-th VirtualBox log of the machine: 0 for the current VBox.log,
+// 1 for VBox.log.1 (previous run) and so on. The caller must close the returned reader.
+func (m *Machine) ReadLog(index int) (io.ReadCloser, error) {
+	if index < 0 {
+		return nil, errors.Errorf("bad log index: vm=%s, index=%d", m.Name, index)
+	}
+	if m.BaseFolder == "" {
+		return nil, errors.Errorf("fail to read log of vm=%s: base folder is unknown", m.Name)
+	}
+	f, err := os.Open(m.logFile(index))
+	if err != nil {
+		return nil, errors.Wrapf(err, "fail to open log: vm=%s, index=%d", m.Name, index)
+	}
+	return f, nil
+}
+
+// TailLog follows the current VirtualBox log of the machine (VBox.log), e.g. to watch the boot
+// of a machine just started, sending its lines, from the first one, to the returned channel.
+// The log is waited for if it does not exist yet.
+//
+// The channel is closed when ctx is done or the log cannot be read anymore.
+// VirtualBox rotates the logs on each start: after a restart, the log of the previous run is still the one followed.
+func (m *Machine) TailLog(ctx context.Context) (<-chan string, error) {
+	if m.BaseFolder == "" {
+		return nil, errors.Errorf("fail to tail log of vm=%s: base folder is unknown", m.Name)
+	}
+	path := m.logFile(0)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(tailFile(path, pw, ctx.Done()))
+	}()
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		defer pr.Close()
+		s := bufio.NewScanner(pr)
+		for s.Scan() {
+			select {
+			case lines <- s.Text():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return lines, nil
+}
+
+// Uptime returns for how long the machine has been running (or paused), as of the start time of its
+// VM process. VBoxManage does not report it, nor do the guest additions: it is derived from the
+// "Log opened" timestamp of the current VBox.log, which is written when the machine starts.
+//
+// ErrMachineNotRunning is returned when the machine is neither running nor paused and
+// ErrUptimeNotAvailable when the start time cannot be read from the log.
+func (m *Machine) Uptime() (time.Duration, error) {
+	if err := m.Refresh(); err != nil {
+		return 0, err
+	}
+	if m.State != Running && m.State != Paused {
+		return 0, errors.Wrapf(ErrMachineNotRunning, "fail to get uptime: vm=%s, state=%s", m.Name, m.State)
+	}
+	started, err := m.logOpenedTime()
+	if err != nil {
+		return 0, err
+	}
+	return time.Since(started), nil
+}
+
+// logOpenedTime returns the time the current VBox.log has been opened at, i.e. the start time of the machine.
+func (m *Machine) logOpenedTime() (time.Time, error) {
+	f, err := os.Open(m.logFile(0))
+	if err != nil {
+		return time.Time{}, errors.Wrapf(ErrUptimeNotAvailable, "fail to open log: vm=%s, err=%v", m.Name, err)
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for i := 0; i < logHeaderLines && s.Scan(); i++ {
+		match := reLogOpened.FindStringSubmatch(s.Text())
+		if match == nil {
+			continue
+		}
+		started, err := time.Parse(time.RFC3339Nano, match[1])
+		if err != nil {
+			return time.Time{}, errors.Wrapf(ErrUptimeNotAvailable, "bad log opening time: vm=%s, time=%s", m.Name, match[1])
+		}
+		return started, nil
+	}
+	return time.Time{}, errors.Wrapf(ErrUptimeNotAvailable, "no log opening time: vm=%s", m.Name)
+}
@@ -0,0 +1,102 @@
+package virtualbox
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLog(t *testing.T) {
+	m := &Machine{Name: "go-virtualbox", BaseFolder: t.TempDir()}
+	require.NoError(t, os.MkdirAll(m.LogPath(), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(m.LogPath(), "VBox.log"), []byte("current"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(m.LogPath(), "VBox.log.1"), []byte("previous"), 0o644))
+
+	for index, expected := range []string{"current", "previous"} {
+		r, err := m.ReadLog(index)
+		require.NoError(t, err)
+		content, err := io.ReadAll(r)
+		require.NoError(t, r.Close())
+		require.NoError(t, err)
+		require.Equal(t, expected, string(content))
+	}
+
+	_, err := m.ReadLog(2)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestTailLog(t *testing.T) {
+	defer func(interval time.Duration) { consolePollInterval = interval }(consolePollInterval)
+	consolePollInterval = time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox", BaseFolder: t.TempDir()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := m.TailLog(ctx)
+	require.NoError(t, err)
+
+	// the log does not exist before the machine is started
+	require.NoError(t, os.MkdirAll(m.LogPath(), 0o755))
+	log := filepath.Join(m.LogPath(), "VBox.log")
+	require.NoError(t, os.WriteFile(log, []byte("00:00:00.000000 VirtualBox VM starting\n00:00:00.001000 Log opened\n"), 0o644))
+	require.Equal(t, "00:00:00.000000 VirtualBox VM starting", <-lines)
+	require.Equal(t, "00:00:00.001000 Log opened", <-lines)
+
+	f, err := os.OpenFile(log, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString("00:00:02.500000 Guest Additions loaded\n")
+	require.NoError(t, f.Close())
+	require.NoError(t, err)
+	require.Equal(t, "00:00:02.500000 Guest Additions loaded", <-lines)
+
+	cancel()
+	for range lines {
+	}
+}
+
+func TestUptime(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	if ManageMock == nil {
+		m := testMachine(t, "", Running)
+		uptime, err := m.Uptime()
+		require.NoError(t, err)
+		require.Positive(t, uptime)
+		return
+	}
+
+	// BaseFolder is derived from CfgFile on refresh
+	baseFolder := t.TempDir()
+	vmInfo := func(state MachineState) string {
+		return strings.Replace(vmInfoWithState(state, "0"),
+			`CfgFile="/Users/fix/VirtualBox VMs/go-virtualbox/go-virtualbox.vbox"`,
+			`CfgFile="`+filepath.Join(baseFolder, "go-virtualbox.vbox")+`"`, 1)
+	}
+	m := &Machine{Name: "go-virtualbox"}
+
+	expectShowVMInfo("go-virtualbox", vmInfo(Poweroff))
+	_, err := m.Uptime()
+	require.ErrorIs(t, err, ErrMachineNotRunning)
+
+	expectShowVMInfo("go-virtualbox", vmInfo(Running))
+	_, err = m.Uptime()
+	require.ErrorIs(t, err, ErrUptimeNotAvailable)
+
+	started := time.Now().Add(-90 * time.Minute).UTC()
+	require.NoError(t, os.MkdirAll(m.LogPath(), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(m.LogPath(), "VBox.log"),
+		[]byte("00:00:00.000000 VirtualBox VM 7.0.12 r159484 linux.amd64 (Oct 12 2023 14:47:30) release log\n"+
+			"00:00:00.000002 Log opened "+started.Format(time.RFC3339Nano)+"\n"+
+			"00:00:00.000003 Build Type: release\n"), 0o644))
+	expectShowVMInfo("go-virtualbox", vmInfo(Running))
+	uptime, err := m.Uptime()
+	require.NoError(t, err)
+	require.InDelta(t, 90*time.Minute, uptime, float64(time.Minute))
+}
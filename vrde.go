@@ -0,0 +1,49 @@
+package virtualbox
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	pkgerrors "github.com/pkg/errors"
+)
+
+var (
+	// matches e.g. VBoxManage: error: The VRDE server is not available, install the extension pack
+	// or ... Failed to load the VRDE library (VERR_FILE_NOT_FOUND)
+	reVRDENotAvailable = regexp.MustCompile(`(?i)(vrde.*(not (available|installed|found)|VERR_FILE_NOT_FOUND|library))|(extension pack.*vrde)`)
+)
+
+var (
+	// ErrVRDENotAvailable holds the error message when the remote display server is not available,
+	// which is the case when no extension pack providing it is installed.
+	ErrVRDENotAvailable = errors.New("VRDE not available")
+)
+
+// SetVRDE enables or disables the remote display server of the running machine, without changing its settings.
+func (m *Machine) SetVRDE(enabled bool) error {
+	return m.controlVRDE("vrde", bool2string(enabled))
+}
+
+// SetVRDEPort sets the port of the remote display server of the running machine.
+func (m *Machine) SetVRDEPort(port uint) error {
+	return m.controlVRDE("vrdeport", fmt.Sprintf("%d", port))
+}
+
+func (m *Machine) controlVRDE(setting string, value string) error {
+	stdout, stderr, err := Manage().runOutErr("controlvm", m.Name, setting, value)
+	if err != nil {
+		if reVRDENotAvailable.MatchString(stderr) {
+			if errExtPack := requireExtensionPack("VRDE"); errExtPack != nil {
+				return pkgerrors.Wrapf(ErrVRDENotAvailable,
+					"fail to set %s: vm=%s, %v", setting, m.Name, errExtPack)
+			}
+			return pkgerrors.Wrapf(ErrVRDENotAvailable,
+				"fail to set %s: vm=%s, stderr=%s", setting, m.Name, stderr)
+		}
+		return pkgerrors.Wrapf(err,
+			"fail to set %s:\n\tvm=%s \n\tvalue=%s \n\tstdout=%s \n\tstderr=%s",
+			setting, m.Name, value, stdout, stderr)
+	}
+	return nil
+}
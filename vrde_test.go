@@ -0,0 +1,60 @@
+package virtualbox
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetVRDE(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+
+	m := &Machine{Name: "go-virtualbox"}
+	if ManageMock != nil {
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("controlvm", "go-virtualbox", "vrdeport", "5001").Return("", "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("controlvm", "go-virtualbox", "vrde", "on").Return("", "", nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "0")),
+		)
+	} else {
+		m = testMachine(t, "", Running)
+		vmInfoOut, _, err := Manage().runOutErr("showvminfo", m.Name, "--machinereadable")
+		require.NoError(t, err)
+		propMap, err := vminfoAsPropMap(strings.NewReader(vmInfoOut))
+		require.NoError(t, err)
+		port, enabled := "default", m.VRDE
+		if _, err := strconv.Atoi(propMap["vrdeproperty[TCP/Ports]"]); err == nil {
+			port = propMap["vrdeproperty[TCP/Ports]"]
+		}
+		t.Cleanup(func() {
+			require.NoError(t, Manage().run("controlvm", m.Name, "vrdeport", port))
+			require.NoError(t, m.SetVRDE(enabled))
+		})
+	}
+
+	err := m.SetVRDEPort(5001)
+	if err == nil {
+		err = m.SetVRDE(true)
+	}
+	if ManageMock == nil && errors.Is(err, ErrVRDENotAvailable) {
+		require.Contains(t, err.Error(), OracleExtensionPack, "error should tell the extension pack is missing")
+		t.Skip("requires the VRDE server")
+	}
+	require.NoError(t, err)
+	require.NoError(t, m.Refresh())
+	require.True(t, m.VRDE)
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("controlvm", "go-virtualbox", "vrde", "on").
+			Return("", "VBoxManage: error: Failed to load the VRDE library (VERR_FILE_NOT_FOUND)", errors.New("exit status 1")).Times(1)
+		ManageMock.EXPECT().runOutErr("list", "extpacks").Return("Extension Packs: 0\n", "", nil).Times(1)
+		err := m.SetVRDE(true)
+		require.ErrorIs(t, err, ErrVRDENotAvailable)
+		require.Contains(t, err.Error(), OracleExtensionPack, "error should tell the extension pack is missing")
+	}
+}
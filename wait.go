@@ -0,0 +1,218 @@
+package virtualbox
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrStartFailed holds the error message when the machine could not be started.
+	ErrStartFailed = errors.New("machine failed to start")
+	// ErrStateTimeout holds the error message when the machine did not reach the expected state in time.
+	ErrStateTimeout = errors.New("machine did not reach the expected state in time")
+	// ErrGuestNotReady holds the error message when the guest additions did not come up in time.
+	ErrGuestNotReady = errors.New("guest additions did not come up in time")
+	// ErrGuestPortNotOpen holds the error message when a guest port did not accept connections in time.
+	ErrGuestPortNotOpen = errors.New("guest port did not open in time")
+	// ErrGuestFileTimeout holds the error message when a guest file did not appear in time.
+	ErrGuestFileTimeout = errors.New("guest file did not appear in time")
+)
+
+var waitPollInterval = 1 * time.Second
+
+// waitMaxBackoff is the maximum number of poll intervals between two attempts of the waits which back off.
+const waitMaxBackoff = 8
+
+// WaitForState refreshes the machine until it reaches the given state.
+// ErrStateTimeout is returned if the state is not reached within the timeout.
+func (m *Machine) WaitForState(state MachineState, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := m.Refresh(); err != nil {
+			return err
+		}
+		if m.State == state {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrapf(ErrStateTimeout, "vm=%s, expected=%s, actual=%s, timeout=%s",
+				m.Name, state, m.State, timeout)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// WaitForShutdown refreshes the machine until it is powered off, typically after Stop or an
+// ACPI shutdown. clean is true when it reached Poweroff, false when it ended up Aborted
+// (e.g. crashed or was killed). ErrStateTimeout is returned if neither happens within the timeout.
+func (m *Machine) WaitForShutdown(timeout time.Duration) (clean bool, err error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := m.Refresh(); err != nil {
+			return false, err
+		}
+		switch m.State {
+		case Poweroff:
+			return true, nil
+		case Aborted:
+			return false, nil
+		}
+		if time.Now().After(deadline) {
+			return false, errors.Wrapf(ErrStateTimeout, "vm=%s, expected=%s, actual=%s, timeout=%s",
+				m.Name, Poweroff, m.State, timeout)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// WaitGuestReady refreshes the machine until its guest additions are running,
+// which means the guest has booted and can e.g. be used with guest control.
+// ErrGuestNotReady is returned if the guest additions do not come up within the timeout.
+func (m *Machine) WaitGuestReady(timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		if err := m.Refresh(); err != nil {
+			return err
+		}
+		if m.GuestAdditionsRunLevel > 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrapf(ErrGuestNotReady, "vm=%s, state=%s, timeout=%s", m.Name, m.State, timeout)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// startError is returned by StartAndWaitBooted when the machine could not be started.
+// It matches ErrStartFailed with errors.Is and unwraps to its cause, e.g. an ErrDanglingHostonlyAdapter.
+type startError struct {
+	vm  string
+	err error
+}
+
+func (e *startError) Error() string {
+	return fmt.Sprintf("%v: vm=%s: %v", ErrStartFailed, e.vm, e.err)
+}
+
+// Is reports whether target is ErrStartFailed.
+func (e *startError) Is(target error) bool {
+	return target == ErrStartFailed
+}
+
+// Unwrap returns the cause of the start failure.
+func (e *startError) Unwrap() error {
+	return e.err
+}
+
+// StartAndWaitBooted starts the machine and waits until it is running and its guest additions are up.
+// The timeout applies to the whole operation.
+//
+// The returned error wraps ErrStartFailed, ErrStateTimeout or ErrGuestNotReady depending on where it stalled.
+// ErrStartFailed is returned as soon as the machine is aborted while booting, its cause being reachable
+// with errors.Is and errors.As, e.g. ErrHardwareVirtUnavailable.
+func (m *Machine) StartAndWaitBooted(timeout time.Duration, startVmParamOverrides ...CmdArg) error {
+	deadline := time.Now().Add(timeout)
+	if err := m.Start(startVmParamOverrides...); err != nil {
+		return &startError{vm: m.Name, err: err}
+	}
+	for {
+		if err := m.Refresh(); err != nil {
+			return err
+		}
+		switch {
+		case m.State == Aborted:
+			return &startError{vm: m.Name, err: errors.Errorf("machine aborted while booting, see its VBox.log")}
+		case m.State == Running && m.GuestAdditionsRunLevel > 0:
+			return nil
+		}
+		if time.Now().After(deadline) {
+			if m.State != Running {
+				return errors.Wrapf(ErrStateTimeout, "vm=%s, expected=%s, actual=%s, timeout=%s",
+					m.Name, Running, m.State, timeout)
+			}
+			return errors.Wrapf(ErrGuestNotReady, "vm=%s, state=%s, timeout=%s", m.Name, m.State, timeout)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// WaitPort waits until the given guest TCP port accepts connections.
+//
+// The port is reached through the host port forwarded to it by a NAT NIC of the machine if any,
+// otherwise directly at guestIP, which must then be the address of the guest on a host-only or
+// bridged network. ErrGuestPortNotOpen is returned if the port does not open within the timeout.
+func (m *Machine) WaitPort(guestIP string, port int, timeout time.Duration) error {
+	addr, err := m.guestPortAddr(guestIP, port)
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", addr, waitPollInterval)
+		if err == nil {
+			_ = conn.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrapf(ErrGuestPortNotOpen, "vm=%s, port=%d, addr=%s, timeout=%s, err=%v",
+				m.Name, port, addr, timeout, err)
+		}
+		time.Sleep(waitPollInterval)
+	}
+}
+
+// WaitGuestFile waits until the given guest path exists, e.g. /var/lib/cloud/instance/boot-finished
+// written by cloud-init once the guest is provisioned. The path is checked with GuestStat, backing off
+// up to waitMaxBackoff poll intervals between attempts; guest control errors, e.g. while the guest
+// additions are still starting, do not stop the wait.
+//
+// ErrGuestFileTimeout is returned, with the last error, if the path does not exist within the timeout.
+func (m *Machine) WaitGuestFile(path string, cred GuestCredentials, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	interval := waitPollInterval
+	for {
+		_, err := m.GuestStat(path, cred)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return errors.Wrapf(ErrGuestFileTimeout, "vm=%s, path=%s, timeout=%s, err=%v", m.Name, path, timeout, err)
+		}
+		time.Sleep(interval)
+		if interval < waitMaxBackoff*waitPollInterval {
+			interval *= 2
+		}
+	}
+}
+
+// guestPortAddr returns the address to dial from the host to reach the given guest TCP port.
+func (m *Machine) guestPortAddr(guestIP string, port int) (string, error) {
+	for i, nic := range m.NICs {
+		if nic.Network != NICNetNAT {
+			continue
+		}
+		rules, err := m.ListNATPF(i + 1)
+		if err != nil {
+			return "", err
+		}
+		for _, rule := range rules {
+			if rule.Proto != PFTCP || int(rule.GuestPort) != port {
+				continue
+			}
+			host := "127.0.0.1"
+			if rule.HostIP != nil && !rule.HostIP.IsUnspecified() {
+				host = rule.HostIP.String()
+			}
+			return net.JoinHostPort(host, strconv.Itoa(int(rule.HostPort))), nil
+		}
+	}
+	if guestIP == "" {
+		return "", errors.Errorf("no port forwarding to guest port and no guest IP given: vm=%s, port=%d", m.Name, port)
+	}
+	return net.JoinHostPort(guestIP, strconv.Itoa(port)), nil
+}
@@ -0,0 +1,322 @@
+package virtualbox
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// vmInfoWithState returns the go-virtualbox VM info with the given state and guest additions run level.
+func vmInfoWithState(state MachineState, runLevel string) string {
+	vmInfo := strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"),
+		`VMState="saved"`, `VMState="`+string(state)+`"`, 1)
+	return vmInfo + "GuestAdditionsRunLevel=" + runLevel + "\n"
+}
+
+// poweroffOnCleanup powers off the real machine once the test is done, should it still be running.
+func poweroffOnCleanup(t *testing.T, m *Machine) {
+	t.Helper()
+	t.Cleanup(func() {
+		require.NoError(t, m.Refresh())
+		if m.State == Running || m.State == Paused {
+			require.NoError(t, m.Poweroff())
+			require.NoError(t, m.WaitForState(Poweroff, time.Minute))
+		}
+	})
+}
+
+func TestStartAndWaitBooted(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+
+	m := &Machine{Name: "go-virtualbox", State: Poweroff}
+	if ManageMock != nil {
+		waitPollInterval = time.Millisecond
+		gomock.InOrder(
+			ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState("starting", "0")),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "0")),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "0")),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Running, "2")),
+		)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		poweroffOnCleanup(t, m)
+	}
+
+	err := m.StartAndWaitBooted(5 * time.Minute)
+	if ManageMock == nil && errors.Is(err, ErrGuestNotReady) {
+		t.Skipf("requires %s to run the guest additions", m.Name)
+	}
+	require.NoError(t, err)
+	require.Equal(t, Running, m.State)
+	if ManageMock != nil {
+		require.Equal(t, uint(2), m.GuestAdditionsRunLevel)
+	} else {
+		require.NotZero(t, m.GuestAdditionsRunLevel)
+	}
+}
+
+func TestStartAndWaitBootedErrors(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox", State: Poweroff}
+	adapter := "vboxnet1"
+	if ManageMock != nil {
+		ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(errors.New("exit status 1")).Times(1)
+	} else {
+		m.Name = "go-virtualbox-gone"
+	}
+	require.ErrorIs(t, m.StartAndWaitBooted(time.Minute), ErrStartFailed)
+
+	if ManageMock != nil {
+		m = &Machine{Name: "go-virtualbox", State: Poweroff}
+		ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(errors.New(
+			"VBoxManage: error: Nonexistent host networking interface, name 'vboxnet1' (VERR_INTERNAL_ERROR)")).Times(1)
+	} else {
+		adapter = "vboxnet-go-virtualbox-missing"
+		m, _ = setDanglingNIC(t, adapter)
+		poweroffOnCleanup(t, m)
+	}
+	err := m.StartAndWaitBooted(time.Minute)
+	require.ErrorIs(t, err, ErrStartFailed)
+	var dangling *ErrDanglingHostonlyAdapter
+	require.Truef(t, errors.As(err, &dangling), "dangling adapter should be reachable from the error: %v", err)
+	require.Equal(t, adapter, dangling.Adapter)
+
+	if ManageMock != nil {
+		// fails as soon as the machine is aborted
+		m = &Machine{Name: "go-virtualbox", State: Poweroff}
+		gomock.InOrder(
+			ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(nil).Times(1),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState("starting", "0")),
+			expectShowVMInfo("go-virtualbox", vmInfoWithState(Aborted, "0")),
+		)
+		require.ErrorIs(t, m.StartAndWaitBooted(time.Minute), ErrStartFailed)
+	}
+}
+
+func TestStartAndWaitBootedTimeout(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox", State: Poweroff}
+	if ManageMock != nil {
+		ManageMock.EXPECT().run("startvm", "go-virtualbox", "--type", "headless").Return(nil).Times(1)
+		ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+			Return(vmInfoWithState("starting", "0"), "", nil).MinTimes(1)
+	} else {
+		m = testMachine(t, "", Poweroff, Aborted)
+		poweroffOnCleanup(t, m)
+	}
+
+	err := m.StartAndWaitBooted(10 * time.Millisecond)
+	if ManageMock != nil {
+		require.ErrorIs(t, err, ErrStateTimeout)
+	} else {
+		// the machine is usually running by the time startvm returns, the guest is not booted yet
+		require.Truef(t, errors.Is(err, ErrStateTimeout) || errors.Is(err, ErrGuestNotReady), "unexpected error: %v", err)
+	}
+}
+
+func TestWaitGuestReadyTimeout(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox", State: Running}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+			Return(vmInfoWithState(Running, "0"), "", nil).MinTimes(1)
+	} else {
+		// the guest additions of a powered off machine are not running
+		m = testMachine(t, "", Poweroff, Aborted)
+	}
+	require.ErrorIs(t, m.WaitGuestReady(10*time.Millisecond), ErrGuestNotReady)
+}
+
+func TestWaitPortForwarded(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = 10 * time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox", NICs: []NIC{{Network: NICNetNAT}}}
+	guestPort := 22
+	if ManageMock != nil {
+		// the guest ssh port is forwarded to the listener port
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer l.Close()
+		hostPort := strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+		vmInfo := strings.Replace(ReadTestData("vboxmanage-showvminfo-1.out"),
+			`Forwarding(0)="ssh,tcp,127.0.0.1,2222,,22"`, `Forwarding(0)="ssh,tcp,127.0.0.1,`+hostPort+`,,22"`, 1)
+		expectShowVMInfo("go-virtualbox", vmInfo)
+	} else {
+		// VirtualBox listens on the forwarded host port as long as the machine runs
+		m = testMachine(t, "", Running)
+		n := testNATNIC(t, m)
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		hostPort := l.Addr().(*net.TCPAddr).Port
+		require.NoError(t, l.Close())
+		guestPort = 52022
+		require.NoError(t, m.AddNATPF(n, "go-virtualbox-test", PFRule{
+			Proto: PFTCP, HostIP: net.ParseIP("127.0.0.1"), HostPort: uint16(hostPort), GuestPort: uint16(guestPort),
+		}))
+		t.Cleanup(func() { require.NoError(t, m.DelNATPF(n, "go-virtualbox-test")) })
+	}
+
+	require.NoError(t, m.WaitPort("", guestPort, time.Second))
+}
+
+func TestWaitPortTimeout(t *testing.T) {
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = 10 * time.Millisecond
+
+	// nothing listens on the guest IP port: the listener is closed right away
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	require.NoError(t, l.Close())
+	m := &Machine{Name: "go-virtualbox", NICs: []NIC{{Network: NICNetHostonly}}}
+
+	err = m.WaitPort("127.0.0.1", port, 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrGuestPortNotOpen)
+}
+
+func TestWaitForShutdown(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+
+	m := &Machine{Name: "go-virtualbox", State: Running}
+	if ManageMock != nil {
+		waitPollInterval = time.Millisecond
+		gomock.InOrder(
+			ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+				Return(vmInfoWithState(MachineState("stopping"), "0"), "", nil).Times(1),
+			ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+				Return(vmInfoWithState(Poweroff, "0"), "", nil).Times(1),
+		)
+	} else {
+		m = testMachine(t, "", Running)
+		t.Cleanup(func() {
+			require.NoError(t, m.Refresh())
+			require.NoError(t, m.Start())
+		})
+		require.NoError(t, Manage().run("controlvm", m.Name, "acpipowerbutton"))
+	}
+	clean, err := m.WaitForShutdown(5 * time.Minute)
+	require.NoError(t, err)
+	require.True(t, clean)
+
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+			Return(vmInfoWithState(Aborted, "0"), "", nil).Times(1)
+	} else {
+		// crashed: the VM process is killed
+		require.NoError(t, m.Start())
+		require.NoError(t, m.WaitForState(Running, time.Minute))
+		pids, err := vmProcessIDs(m.UUID)
+		require.NoError(t, err)
+		require.NotEmpty(t, pids)
+		for _, pid := range pids {
+			require.NoError(t, killProcess(pid))
+		}
+	}
+	clean, err = m.WaitForShutdown(time.Minute)
+	require.NoError(t, err)
+	require.False(t, clean)
+}
+
+func TestWaitForShutdownTimeout(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox", State: Running}
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr("showvminfo", "go-virtualbox", "--machinereadable").
+			Return(vmInfoWithState(Running, "2"), "", nil).MinTimes(1)
+	} else {
+		m = testMachine(t, "", Running)
+	}
+	_, err := m.WaitForShutdown(10 * time.Millisecond)
+	require.ErrorIs(t, err, ErrStateTimeout)
+}
+
+func TestWaitGuestFile(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+
+	m := &Machine{Name: "go-virtualbox"}
+	cred := GuestCredentials{Username: "vagrant", PasswordFile: "/etc/vbox/pw"}
+	path := "/var/lib/cloud/instance/boot-finished"
+	if ManageMock != nil {
+		waitPollInterval = time.Millisecond
+		stat := func() *gomock.Call {
+			return ManageMock.EXPECT().runOutErr("guestcontrol", "go-virtualbox", "stat", "--username", "vagrant",
+				"--passwordfile", "/etc/vbox/pw", "/var/lib/cloud/instance/boot-finished")
+		}
+		gomock.InOrder(
+			stat().Return("", "VBoxManage: error: The guest execution service is not ready (yet)", errors.New("exit status 1")).Times(1),
+			stat().Return("", "VBoxManage: error: VERR_FILE_NOT_FOUND", errors.New("exit status 1")).Times(2),
+			stat().Return(`Element "/var/lib/cloud/instance/boot-finished" found: Is a file`+"\n", "", nil).Times(1),
+		)
+	} else {
+		// the guest is provisioned while waiting
+		m = testMachineWithGuestAdditions(t, "")
+		cred = testGuestCredentials(t)
+		path = guestTempDir(t, cred) + "/boot-finished"
+		go func() {
+			time.Sleep(2 * time.Second)
+			_, err := RunGuestControlStream(GuestExec{
+				VM: VM, Credentials: cred, Exe: "/bin/touch", Args: []string{"touch", path}, Timeout: time.Minute,
+			}, io.Discard, io.Discard)
+			assert.NoError(t, err)
+		}()
+	}
+
+	require.NoError(t, m.WaitGuestFile(path, cred, time.Minute))
+}
+
+func TestWaitGuestFileTimeout(t *testing.T) {
+	Setup(t)
+	defer Teardown()
+	defer func(interval time.Duration) { waitPollInterval = interval }(waitPollInterval)
+	waitPollInterval = time.Millisecond
+
+	m := &Machine{Name: "go-virtualbox"}
+	cred := GuestCredentials{Username: "vagrant"}
+	path := "/var/lib/cloud/instance/boot-finished"
+	if ManageMock != nil {
+		ManageMock.EXPECT().runOutErr(gomock.Any()).
+			Return("", "VBoxManage: error: VERR_FILE_NOT_FOUND", errors.New("exit status 1")).AnyTimes()
+	} else {
+		m = testMachineWithGuestAdditions(t, "")
+		cred = testGuestCredentials(t)
+		path = guestTempDir(t, cred) + "/boot-finished"
+	}
+
+	err := m.WaitGuestFile(path, cred, 20*time.Millisecond)
+	require.ErrorIs(t, err, ErrGuestFileTimeout)
+	require.Contains(t, err.Error(), ErrGuestPathNotFound.Error())
+}